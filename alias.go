@@ -0,0 +1,128 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// AliasOptions holds esbuild build options equivalent to the simple top-level imports of an import map
+type AliasOptions struct {
+	// Alias maps specifiers to the local files they resolve to
+	Alias map[string]string
+	// External lists the specifiers which resolve to remote URLs, with path prefixes as "prefix/*"
+	External []string
+	// Skipped lists the specifiers which can't be expressed with build options, like scoped
+	// mappings, local path prefixes ending with "/", URL-like keys and specifiers whose
+	// subpaths esbuild would handle differently than the import map
+	Skipped []string
+}
+
+// ToAliasOptions converts the top-level bare specifiers of an import map into esbuild Alias entries
+// for local targets and externals for remote ones. This gives import map driven builds without
+// intercepting every path with the plugin, at the cost of supporting only exact specifiers.
+//
+// Unlike import map keys, esbuild aliases and externals also match the subpaths of a package, so
+// "react" applies to "react/jsx-runtime" too. Specifiers are skipped when the map has a subpath
+// of them that esbuild would rewrite with theirs, but subpaths missing from the map, which a
+// browser fails to resolve, are still rewritten to the target of their package.
+func ToAliasOptions(m importmap.IImportMap) (AliasOptions, error) {
+	options := AliasOptions{
+		Alias: make(map[string]string),
+	}
+
+	imports := m.GetImports()
+	specifiers := make([]string, 0, len(imports))
+	for specifier := range imports {
+		specifiers = append(specifiers, specifier)
+	}
+	// decide the subpaths before the specifiers they are nested in
+	sort.Slice(specifiers, func(i, j int) bool {
+		return len(specifiers[i]) > len(specifiers[j])
+	})
+
+	aliased := make(map[string]bool)
+	skipped := make(map[string]bool)
+	for _, specifier := range specifiers {
+		kind, _ := importmap.ParseSpecifier(specifier, nil)
+		if kind != importmap.SpecifierBare || strings.HasSuffix(specifier, "*") {
+			skipped[specifier] = true
+			continue
+		}
+
+		resolved, err := m.Resolve(specifier)
+		if err != nil {
+			return AliasOptions{}, err
+		}
+		u, err := url.Parse(resolved)
+		if err != nil {
+			return AliasOptions{}, err
+		}
+
+		prefix := strings.HasSuffix(specifier, "/")
+		switch {
+		case u.Scheme == "file" && !prefix:
+			// aliases apply before externals, so only longer aliases take precedence
+			if overMatches(specifier, specifiers, func(subpath string) bool { return !aliased[subpath] }) {
+				skipped[specifier] = true
+				continue
+			}
+			path, err := importmap.FilePath(u)
+			if err != nil {
+				return AliasOptions{}, err
+			}
+			options.Alias[specifier] = path
+			aliased[specifier] = true
+		case u.Scheme == "http" || u.Scheme == "https":
+			if overMatches(specifier, specifiers, func(subpath string) bool { return skipped[subpath] }) {
+				skipped[specifier] = true
+				continue
+			}
+			if prefix {
+				options.External = append(options.External, specifier+"*")
+			} else {
+				options.External = append(options.External, specifier)
+			}
+		default:
+			skipped[specifier] = true
+		}
+	}
+
+	for specifier := range skipped {
+		options.Skipped = append(options.Skipped, specifier)
+	}
+	for _, scope := range m.GetScopes() {
+		for specifier := range scope {
+			options.Skipped = append(options.Skipped, specifier)
+		}
+	}
+
+	sort.Strings(options.External)
+	sort.Strings(options.Skipped)
+	return options, nil
+}
+
+// overMatches reports whether esbuild would wrongly apply the entry of specifier to one of the
+// other specifiers nested in it, conflicts telling which subpath entries it would override.
+func overMatches(specifier string, specifiers []string, conflicts func(subpath string) bool) bool {
+	prefix := strings.TrimSuffix(specifier, "/") + "/"
+	for _, subpath := range specifiers {
+		if subpath != specifier && strings.HasPrefix(subpath, prefix) && conflicts(subpath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply adds the aliases and externals to esbuild build options
+func (o AliasOptions) Apply(buildOptions *api.BuildOptions) {
+	if len(o.Alias) > 0 && buildOptions.Alias == nil {
+		buildOptions.Alias = make(map[string]string, len(o.Alias))
+	}
+	for specifier, target := range o.Alias {
+		buildOptions.Alias[specifier] = target
+	}
+	buildOptions.External = append(buildOptions.External, o.External...)
+}
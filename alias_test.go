@@ -0,0 +1,112 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToAliasOptions(t *testing.T) {
+	m, err := importmap.New(importmap.WithMap(importmap.Data{
+		Imports: importmap.Imports{
+			"test-module": "./testModule.js",
+			"react":       "https://esm.sh/react@18",
+			"@/":          "./",
+		},
+		Scopes: importmap.Scopes{
+			"/vendor/": {"react": "https://esm.sh/react@17"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := ToAliasOptions(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, _ := os.Getwd()
+	if options.Alias["test-module"] != cwd+"/testModule.js" {
+		t.Errorf("unexpected alias %v", options.Alias)
+	}
+	if !reflect.DeepEqual(options.External, []string{"react"}) {
+		t.Errorf("unexpected externals %v", options.External)
+	}
+	if !reflect.DeepEqual(options.Skipped, []string{"@/", "react"}) {
+		t.Errorf("unexpected skipped specifiers %v", options.Skipped)
+	}
+
+	buildOptions := api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins:     []api.Plugin{getFileTreePlugin(t, "import {define} from 'test-module'; import React from 'react'; console.log(define, React);")},
+	}
+	options.Apply(&buildOptions)
+
+	result := api.Build(buildOptions)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `"test"`) || !strings.Contains(output, `from "react"`) {
+		t.Errorf("unexpected output:\n%s", output)
+	}
+}
+
+func TestToAliasOptionsSubpaths(t *testing.T) {
+	m, err := importmap.New(importmap.WithMap(importmap.Data{
+		Imports: importmap.Imports{
+			"react":             "./testModule.js",
+			"react/jsx-runtime": "https://esm.sh/react@18/jsx-runtime",
+			"preact":            "https://esm.sh/preact@10",
+			"preact/hooks":      "./testModule.js",
+			"lit/":              "https://esm.sh/lit@3/",
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := ToAliasOptions(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, _ := os.Getwd()
+	if !reflect.DeepEqual(options.Alias, map[string]string{"preact/hooks": cwd + "/testModule.js"}) {
+		t.Errorf("unexpected aliases %v", options.Alias)
+	}
+	if !reflect.DeepEqual(options.External, []string{"lit/*", "preact", "react/jsx-runtime"}) {
+		t.Errorf("unexpected externals %v", options.External)
+	}
+	if !reflect.DeepEqual(options.Skipped, []string{"react"}) {
+		t.Errorf("expected the alias of react not to shadow react/jsx-runtime, got %v", options.Skipped)
+	}
+
+	buildOptions := api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{getFileTreePlugin(t, "import {define} from 'preact/hooks'; import {h} from 'preact'; "+
+			"import {jsx} from 'react/jsx-runtime'; import {html} from 'lit/html.js'; console.log(define, h, jsx, html);")},
+	}
+	options.Apply(&buildOptions)
+
+	result := api.Build(buildOptions)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	for _, expected := range []string{`"test"`, `from "preact"`, `from "react/jsx-runtime"`, `from "lit/html.js"`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %s in the output:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// parseBaseURL parses Config.BaseURL, nil when unset.
+func parseBaseURL(config *Config) (*url.URL, error) {
+	if config.BaseURL == "" {
+		return nil, nil
+	}
+	baseUrl, err := url.Parse(config.BaseURL)
+	if err != nil || baseUrl.Scheme == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected an absolute URL like https://app.example.com/", config.BaseURL)
+	}
+	return baseUrl, nil
+}
+
+// mapOptions returns the options of the import maps built from the configuration: the base URL,
+// the root directory when the base URL isn't served over HTTP, the environment and the conditions.
+func mapOptions(config *Config) ([]importmap.Option, error) {
+	opts := []importmap.Option{
+		importmap.WithEnvironment(config.Environment),
+		importmap.WithConditions(config.Conditions...),
+	}
+	baseUrl, err := parseBaseURL(config)
+	if err != nil {
+		return nil, err
+	}
+	if baseUrl != nil {
+		opts = append(opts, importmap.WithMapUrl(baseUrl))
+	}
+	if config.RootDir != "" && !isHTTPUrl(baseUrl) {
+		rootDir, err := filepath.Abs(config.RootDir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, importmap.WithRootUrl(importmap.DirURL(rootDir)))
+	}
+	return opts, nil
+}
+
+func isHTTPUrl(u *url.URL) bool {
+	return u != nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// rootFile maps a resolved URL to the file it is served from under Config.RootDir: root-relative
+// paths, and URLs on the origin of an HTTP base URL. Other URLs are returned as is.
+func (p *plugin) rootFile(resolved string) string {
+	if p.config.RootDir == "" {
+		return resolved
+	}
+	rootDir, err := filepath.Abs(p.config.RootDir)
+	if err != nil {
+		return resolved
+	}
+
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return resolved
+	}
+	if strings.HasPrefix(resolved, "/") && !strings.HasPrefix(resolved, "//") {
+		return withQuery(importmap.FileURL(filepath.Join(rootDir, filepath.FromSlash(u.Path))), u).String()
+	}
+	baseUrl, _ := parseBaseURL(p.config)
+	if !isHTTPUrl(baseUrl) || u.Scheme != baseUrl.Scheme || u.Host != baseUrl.Host {
+		return resolved
+	}
+	return withQuery(importmap.FileURL(filepath.Join(rootDir, filepath.FromSlash(u.Path))), u).String()
+}
+
+// WithBaseURL sets the URL the import maps given as data or files are resolved against, the
+// current working directory by default. With an HTTP URL like "https://app.example.com/", "/"
+// prefixed targets resolve to the origin of the page like they do in the browser. Maps loaded
+// from a URL are resolved against their own URL.
+func WithBaseURL(baseUrl string) Option {
+	return func(config *Config) {
+		config.BaseURL = baseUrl
+	}
+}
+
+// WithRootDir sets the directory the root of the site is served from, e.g. "./public". "/"
+// prefixed targets, and targets on the origin of an HTTP base URL, are loaded from the files
+// under it instead of the file system root or the network.
+func WithRootDir(dir string) Option {
+	return func(config *Config) {
+		config.RootDir = dir
+	}
+}
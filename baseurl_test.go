@@ -0,0 +1,185 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRootDir(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"js/app.js": "export const app = 'served app';",
+		"lib.js":    "export const lib = 'served lib';",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPluginWithBaseURLAndRootDir(t *testing.T) {
+	dir := writeRootDir(t)
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"app": "/js/app.js",
+			"lib": "./lib.js",
+		}}),
+		WithBaseURL("https://app.example.com/"),
+		WithRootDir(dir),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {app} from 'app'; import {lib} from 'lib'; console.log(app, lib);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "served app") || !strings.Contains(output, "served lib") {
+		t.Errorf("expected the targets on the origin of the base URL to be read from the root directory, got:\n%s", output)
+	}
+}
+
+func TestPluginWithRootDir(t *testing.T) {
+	dir := writeRootDir(t)
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"app": "/js/app.js"}}),
+		WithRootDir(dir),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {app} from 'app'; console.log(app);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "served app") {
+		t.Errorf("expected the root-relative target to be read from the root directory, got:\n%s", output)
+	}
+}
+
+func TestPluginWithInvalidBaseURL(t *testing.T) {
+	_, err := NewPlugin(WithMap(importmap.Data{Imports: importmap.Imports{"a": "./a.js"}}), WithBaseURL("./relative/"))
+	if err == nil {
+		t.Error("expected a relative base URL to be rejected")
+	}
+}
+
+func TestPluginWithEnvironment(t *testing.T) {
+	data := importmap.Data{
+		Imports: importmap.Imports{"pkg": "https://cdn.invalid/pkg.development.js"},
+		Environments: map[string]importmap.Data{
+			"production": {Imports: importmap.Imports{"pkg": "https://cdn.invalid/pkg.production.js"}},
+		},
+	}
+	fetcher := fixtureFetcher{
+		"https://cdn.invalid/pkg.development.js": "export const pkg = 'development';",
+		"https://cdn.invalid/pkg.production.js":  "export const pkg = 'production';",
+	}
+
+	for _, environment := range []string{"", "production"} {
+		plugin, err := NewPlugin(WithMap(data), WithFetcher(fetcher), WithEnvironment(environment))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		expected := "development"
+		if environment != "" {
+			expected = environment
+		}
+		if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, expected) {
+			t.Errorf("expected the %s build of pkg, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestPluginWithConditions(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{
+			Imports: importmap.Imports{"shim": "https://cdn.invalid/shim.browser.js"},
+			Conditions: map[string]importmap.Data{
+				"deno": {Imports: importmap.Imports{"shim": "https://cdn.invalid/shim.deno.js"}},
+			},
+		}),
+		WithFetcher(fixtureFetcher{"https://cdn.invalid/shim.deno.js": "export const shim = 'deno';"}),
+		WithConditions("deno", "browser"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {shim} from 'shim'; console.log(shim);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "deno") {
+		t.Errorf("expected the deno target of shim, got:\n%s", output)
+	}
+}
+
+func TestPluginWithConditionsOfLoadedMaps(t *testing.T) {
+	const mapJSON = `{
+		"imports": {"shim": "https://cdn.invalid/shim.browser.js"},
+		"conditions": {"deno": {"imports": {"shim": "https://cdn.invalid/shim.deno.js"}}}
+	}`
+	htmlPath := filepath.Join(t.TempDir(), "index.html")
+	if err := os.WriteFile(htmlPath, []byte(`<script type="importmap">`+mapJSON+`</script>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fetcher := fixtureFetcher{
+		"https://maps.invalid/importmap.json": mapJSON,
+		"https://cdn.invalid/shim.deno.js":    "export const shim = 'deno';",
+	}
+
+	for _, source := range []Option{WithImportMapURL("https://maps.invalid/importmap.json"), WithImportMapHTML(htmlPath)} {
+		plugin, err := NewPlugin(source, WithFetcher(fetcher), WithConditions("deno", "browser"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := buildWithPlugin(t, "import {shim} from 'shim'; console.log(shim);", plugin)
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "deno") {
+			t.Errorf("expected the deno target of shim, got:\n%s", output)
+		}
+	}
+}
+
+func TestPluginQueryAndFragment(t *testing.T) {
+	dir := writeRootDir(t)
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"app":   "/js/app.js?v=2#main",
+			"react": "https://esm.sh/react?dev",
+		}}),
+		WithRootDir(dir),
+		WithFetcher(fixtureFetcher{"https://esm.sh/react?dev": "export const react = 'development';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {app} from 'app'; import {react} from 'react'; console.log(app, react);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "served app") || !strings.Contains(output, "development") {
+		t.Errorf("expected the targets to be loaded with their query, got:\n%s", output)
+	}
+}
@@ -0,0 +1,122 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cachedModule is the file stored in the cache directory for a downloaded module
+type cachedModule struct {
+	URL      string      `json:"url"`
+	FinalURL string      `json:"finalUrl"`
+	Header   http.Header `json:"header"`
+	// Integrity is the hash of Body, so corrupted entries are downloaded again
+	Integrity string `json:"integrity"`
+	// Body is base64 in the file, so binary modules like wasm and images are stored intact
+	Body []byte `json:"body"`
+}
+
+// bypassCacheKey marks contexts of downloads which must reach upstream, like vendor checks
+type bypassCacheKey struct{}
+
+// WithCacheDir stores downloaded modules in dir, so later builds, including the ones of other
+// processes, don't download them again. A leading "~" stands for the home directory.
+// Remove the directory to download modules with mutable URLs again.
+func WithCacheDir(dir string) Option {
+	return func(config *Config) {
+		config.CacheDir = dir
+	}
+}
+
+// expandHome replaces a leading "~" of path with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// cacheFile returns the file of the cache directory holding the module downloaded from rawUrl.
+func (p *plugin) cacheFile(rawUrl string) string {
+	sum := sha256.Sum256([]byte(rawUrl))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(p.cacheDir, key[:2], key+".json")
+}
+
+// readCache returns the module cached for rawUrl, if any and intact.
+func (p *plugin) readCache(ctx context.Context, rawUrl string) (*fetchResult, bool) {
+	if p.cacheDir == "" || ctx.Value(bypassCacheKey{}) != nil {
+		return nil, false
+	}
+	contents, err := os.ReadFile(p.cacheFile(rawUrl))
+	if err != nil {
+		return nil, false
+	}
+	var module cachedModule
+	if err = json.Unmarshal(contents, &module); err != nil || module.URL != rawUrl {
+		return nil, false
+	}
+	if importmap.VerifyIntegrity(module.Body, module.Integrity) != nil {
+		return nil, false
+	}
+	return &fetchResult{
+		contents:  string(module.Body),
+		body:      string(module.Body),
+		header:    module.Header,
+		finalUrl:  module.FinalURL,
+		fromCache: true,
+	}, true
+}
+
+// writeCache stores a downloaded module in the cache directory. The file is written next to its
+// final location and renamed, so concurrent builds never read partial entries.
+func (p *plugin) writeCache(rawUrl string, result *fetchResult) error {
+	if p.cacheDir == "" {
+		return nil
+	}
+	integrity, err := importmap.ComputeIntegrity([]byte(result.body), "")
+	if err != nil {
+		return err
+	}
+	contents, err := json.Marshal(cachedModule{
+		URL:       rawUrl,
+		FinalURL:  result.finalUrl,
+		Header:    result.header,
+		Integrity: integrity,
+		Body:      []byte(result.body),
+	})
+	if err != nil {
+		return err
+	}
+
+	target := p.cacheFile(rawUrl)
+	if err = os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err = tmp.Write(contents); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,85 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchWithCacheDir(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Header().Set("Content-Type", "text/javascript")
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		p := newTestPlugin(t, WithCacheDir(dir))
+		result, err := p.fetch(context.Background(), server.URL+"/mod.js")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.contents != "export default 1;" || result.header.Get("Content-Type") != "text/javascript" {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("expected the second plugin to use the cache, got %d downloads", downloads)
+	}
+
+	p := newTestPlugin(t, WithCacheDir(dir))
+	if err := os.WriteFile(p.cacheFile(server.URL+"/mod.js"), []byte(`{"body": "tampered"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := p.fetch(context.Background(), server.URL+"/mod.js"); err != nil || result.contents != "export default 1;" {
+		t.Errorf("expected a corrupted entry to be downloaded again, got %+v, %v", result, err)
+	}
+	if _, err := p.fetch(context.WithValue(context.Background(), bypassCacheKey{}, true), server.URL+"/mod.js"); err != nil || downloads != 3 {
+		t.Errorf("expected the cache to be bypassed, got %d downloads, %v", downloads, err)
+	}
+}
+
+func TestFetchBinaryWithCacheDir(t *testing.T) {
+	body := []byte{0x00, 0x61, 0x73, 0x6d, 0xff, 0xfe, 0x80, 0xc3}
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Header().Set("Content-Type", "application/wasm")
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		p := newTestPlugin(t, WithCacheDir(dir))
+		result, err := p.fetch(context.Background(), server.URL+"/mod.wasm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.body != string(body) {
+			t.Errorf("expected the bytes to be kept, got %x", result.body)
+		}
+	}
+	if downloads != 1 {
+		t.Errorf("expected the binary module to be served from the cache, got %d downloads", downloads)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	if dir, _ := expandHome("~/.cache/importmap"); dir != filepath.Join(home, ".cache", "importmap") {
+		t.Errorf("unexpected directory %s", dir)
+	}
+	if dir, _ := expandHome("cache"); dir != "cache" {
+		t.Errorf("unexpected directory %s", dir)
+	}
+}
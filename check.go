@@ -0,0 +1,196 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exactVersion matches versions pinned to a single release, e.g. "18.2.0" or "1.0.0-rc.1"
+var exactVersion = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// VendorChange reports a vendored module whose upstream content differs from the vendored copy
+type VendorChange struct {
+	// URL is the upstream URL of the module
+	URL string
+	// VendoredFile is the path of the stale copy inside the vendor directory
+	VendoredFile string
+}
+
+func (c VendorChange) String() string {
+	return fmt.Sprintf("%s changed upstream, %s needs a refresh", c.URL, c.VendoredFile)
+}
+
+// isMutableUrl reports whether the content behind a URL can change, i.e. whether it doesn't pin
+// an exact package version such as "react@18.2.0".
+func isMutableUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return true
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		at := strings.LastIndex(segment, "@")
+		if at > 0 && exactVersion.MatchString(segment[at+1:]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check compares the upstream content of the vendored modules with mutable tags (e.g. "@latest"
+// or "@18") against the vendored copies, and returns the ones needing a refresh. It takes the
+// options the plugin was created with, the vendor directory being required.
+func Check(ctx context.Context, opts ...Option) ([]VendorChange, error) {
+	config := &Config{}
+	if err := applyOptions(config, opts); err != nil {
+		return nil, err
+	}
+	if config.VendorDir == "" {
+		return nil, errors.New("no vendor directory was provided")
+	}
+
+	importMap, err := importmap.New()
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPlugin(config, importMap)
+	if err != nil {
+		return nil, err
+	}
+	return p.check(ctx)
+}
+
+func (p *plugin) check(ctx context.Context) ([]VendorChange, error) {
+	if p.config.Offline {
+		return nil, errors.New("upstream can't be checked in offline mode")
+	}
+	contents, err := os.ReadFile(filepath.Join(p.config.VendorDir, VendorMapFile))
+	if err != nil {
+		return nil, err
+	}
+	data := importmap.Data{}
+	if err = json.Unmarshal(contents, &data); err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for key := range data.Imports {
+		if (strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://")) && isMutableUrl(key) {
+			urls = append(urls, key)
+		}
+	}
+	sort.Strings(urls)
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		changes []VendorChange
+		errs    []error
+	)
+	for _, rawUrl := range urls {
+		wg.Add(1)
+		go func(rawUrl string, target string) {
+			defer wg.Done()
+			change, err := p.checkVendored(ctx, rawUrl, target)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			} else if change != nil {
+				changes = append(changes, *change)
+			}
+		}(rawUrl, data.Imports[rawUrl])
+	}
+	wg.Wait()
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].URL < changes[j].URL
+	})
+	return changes, errors.Join(errs...)
+}
+
+// checkVendored compares the upstream content of rawUrl with its vendored copy, target being
+// the vendored URL recorded in the vendor map.
+func (p *plugin) checkVendored(ctx context.Context, rawUrl string, target string) (*VendorChange, error) {
+	result, err := p.fetch(context.WithValue(ctx, bypassCacheKey{}, true), rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	// the vendored copies went through the OnAfterFetch hook, applied by fetch, and interop
+	result.contents = p.interopContents(result, p.remoteLoader(rawUrl, result))
+
+	if p.config.HashVendorFiles {
+		// the file name carries the hash of the content, so a new name means new content
+		name := vendorFileName(rawUrl, result.contents, true)
+		if strings.HasSuffix(target, "/"+name) {
+			return nil, nil
+		}
+		return &VendorChange{URL: rawUrl, VendoredFile: path.Base(target)}, nil
+	}
+
+	name := vendorFileName(rawUrl, "", false)
+	vendored, err := os.ReadFile(filepath.Join(p.config.VendorDir, filepath.FromSlash(name)))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil && string(vendored) == result.contents {
+		return nil, nil
+	}
+	return &VendorChange{URL: rawUrl, VendoredFile: name}, nil
+}
+
+// WithVendorCheckInterval polls upstream for changes of the vendored modules with mutable tags
+// every interval while the build context is alive, e.g. in watch mode, calling onChange with
+// the modules needing a refresh, or with the error when a check fails.
+func WithVendorCheckInterval(interval time.Duration, onChange func([]VendorChange, error)) Option {
+	return func(config *Config) {
+		config.VendorCheckInterval = interval
+		config.OnVendorChange = onChange
+	}
+}
+
+// startVendorCheck polls for upstream changes until the build context is disposed.
+func (p *plugin) startVendorCheck() {
+	ctx := p.ctx
+	go func() {
+		ticker := time.NewTicker(p.config.VendorCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.pollVendorCheck(ctx); err != nil && ctx.Err() == nil && p.config.OnVendorChange != nil {
+					p.config.OnVendorChange(nil, err)
+				}
+			}
+		}
+	}()
+}
+
+// pollVendorCheck checks for upstream changes once, returning the panics of the check or of the
+// callback as an error so they don't stop the polling or crash the process.
+func (p *plugin) pollVendorCheck(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("checking the vendored modules panicked: %v", r)
+		}
+	}()
+	changes, err := p.check(ctx)
+	if err == nil && len(changes) > 0 && p.config.OnVendorChange != nil {
+		p.config.OnVendorChange(changes, nil)
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIsMutableUrl(t *testing.T) {
+	cases := map[string]bool{
+		"https://esm.sh/react@18.2.0":          false,
+		"https://esm.sh/react@18.2.0/index.js": false,
+		"https://esm.sh/@scope/pkg@1.0.0-rc.1": false,
+		"https://esm.sh/react@18":              true,
+		"https://esm.sh/react@latest/index.js": true,
+		"https://example.com/lib.js":           true,
+	}
+	for rawUrl, expected := range cases {
+		if actual := isMutableUrl(rawUrl); actual != expected {
+			t.Errorf("isMutableUrl(%q) = %v, expected %v", rawUrl, actual, expected)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var version atomic.Value
+	version.Store("1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		_, _ = w.Write([]byte("export const v = '" + version.Load().(string) + "';"))
+	}))
+	defer server.Close()
+
+	for _, hashed := range []bool{false, true} {
+		dir := t.TempDir()
+		opts := []Option{
+			WithMap(importmap.Data{Imports: importmap.Imports{
+				"latest": server.URL + "/latest@latest/index.js",
+				"pinned": server.URL + "/pinned@1.0.0/index.js",
+			}}),
+			WithVendorDir(dir),
+		}
+		if hashed {
+			opts = append(opts, WithHashedVendorFiles())
+		}
+
+		version.Store("1")
+		plugin, err := NewPlugin(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := buildWithPlugin(t, "import {v as a} from 'latest'; import {v as b} from 'pinned'; console.log(a, b);", plugin)
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+
+		changes, err := Check(context.Background(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 0 {
+			t.Errorf("expected no changes, got %v", changes)
+		}
+
+		version.Store("2")
+		changes, err = Check(context.Background(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 1 || changes[0].URL != server.URL+"/latest@latest/index.js" {
+			t.Errorf("expected the mutable module to need a refresh (hashed: %v), got %v", hashed, changes)
+		}
+	}
+
+	if _, err := Check(context.Background()); err == nil {
+		t.Error("expected an error without a vendor directory")
+	}
+}
+
+func TestPollVendorCheckPanic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	vendorMap := `{"imports": {"` + server.URL + `/pkg@latest/index.js": "./pkg.js"}}`
+	if err := os.WriteFile(filepath.Join(dir, VendorMapFile), []byte(vendorMap), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestPlugin(t, WithVendorDir(dir), WithVendorCheckInterval(time.Hour, func([]VendorChange, error) {
+		panic("boom")
+	}))
+
+	if err := p.pollVendorCheck(context.Background()); err == nil {
+		t.Error("expected the panic of the callback to be returned as an error")
+	}
+
+	// failed checks are returned too, to be passed to the callback
+	p = newTestPlugin(t, WithVendorDir(t.TempDir()))
+	if err := p.pollVendorCheck(context.Background()); err == nil {
+		t.Error("expected an error without a vendor map")
+	}
+}
+
+func TestCheckTransformedModules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		_, _ = w.Write([]byte(umdModule))
+	}))
+	defer server.Close()
+
+	for _, hashed := range []bool{false, true} {
+		opts := []Option{
+			WithMap(importmap.Data{Imports: importmap.Imports{"cjs": server.URL + "/cjs@latest/index.js"}}),
+			WithVendorDir(t.TempDir()),
+			WithCommonJSInterop(),
+			WithHooks(Hooks{
+				OnAfterFetch: func(rawUrl string, contents string) (string, error) {
+					return contents + "\n// seen", nil
+				},
+			}),
+		}
+		if hashed {
+			opts = append(opts, WithHashedVendorFiles())
+		}
+
+		plugin, err := NewPlugin(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := buildWithPlugin(t, "import cjs from 'cjs'; console.log(cjs);", plugin)
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+
+		// the vendored copies are compared with upstream once transformed the same way
+		changes, err := Check(context.Background(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 0 {
+			t.Errorf("expected no changes (hashed: %v), got %v", hashed, changes)
+		}
+	}
+}
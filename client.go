@@ -0,0 +1,105 @@
+package esbuild_plugin_importmap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// idleConnTimeout is how long idle connections are kept for the next downloads, long enough for
+// the modules discovered by the next round of imports and the rebuilds of watch mode
+const idleConnTimeout = 2 * time.Minute
+
+// newHTTPClient creates the client shared by the remote downloads of a plugin according to the
+// TLS settings of config. Its transport speaks HTTP/2 where available and keeps as many idle
+// connections per origin as downloads may run in parallel, so the modules of a CDN are fetched
+// over a few warm connections instead of a TLS handshake each.
+func newHTTPClient(config *Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	maxFetchesPerHost := config.MaxFetchesPerHost
+	if maxFetchesPerHost <= 0 {
+		maxFetchesPerHost = defaultMaxFetchesPerHost
+	}
+
+	// the default transport honors HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConnsPerHost = maxFetchesPerHost
+	transport.IdleConnTimeout = idleConnTimeout
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if config.Proxy != "" {
+		proxyUrl, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", config.Proxy, err)
+		}
+		if proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: expected a URL like http://proxy:3128", config.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect(config)}, nil
+}
+
+// WithProxy sends remote downloads through the proxy at proxyUrl, e.g. "http://proxy.corp:3128",
+// instead of the one set with the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+func WithProxy(proxyUrl string) Option {
+	return func(config *Config) {
+		config.Proxy = proxyUrl
+	}
+}
+
+// newTLSConfig returns nil when config doesn't customize TLS.
+func newTLSConfig(config *Config) (*tls.Config, error) {
+	if config.RootCAs == nil && len(config.CABundlePaths) == 0 &&
+		len(config.ClientCertificates) == 0 && len(config.ClientCertificateFiles) == 0 {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:      config.RootCAs,
+		Certificates: append([]tls.Certificate(nil), config.ClientCertificates...),
+	}
+
+	if len(config.CABundlePaths) > 0 {
+		if tlsConfig.RootCAs == nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			tlsConfig.RootCAs = pool
+		} else {
+			tlsConfig.RootCAs = tlsConfig.RootCAs.Clone()
+		}
+
+		for _, path := range config.CABundlePaths {
+			pem, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", path)
+			}
+		}
+	}
+
+	for _, files := range config.ClientCertificateFiles {
+		cert, err := tls.LoadX509KeyPair(files[0], files[1])
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+
+	return tlsConfig, nil
+}
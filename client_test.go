@@ -0,0 +1,97 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchWithCustomCAAndClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = newTestPlugin(t).fetch(context.Background(), server.URL+"/mod.js"); err == nil {
+		t.Error("expected the untrusted certificate to be rejected")
+	}
+
+	if _, err = newTestPlugin(t, WithCABundle(bundle)).fetch(context.Background(), server.URL+"/mod.js"); err == nil {
+		t.Error("expected the request without a client certificate to be rejected")
+	}
+
+	p := newTestPlugin(t, WithCABundle(bundle), WithClientCertificate(server.TLS.Certificates[0]))
+	result, err := p.fetch(context.Background(), server.URL+"/mod.js")
+	if err != nil || result.contents != "export default 1;" {
+		t.Errorf("unexpected result %v, %v", result, err)
+	}
+}
+
+func TestFetchWithProxy(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("// proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	p := newTestPlugin(t, WithProxy(proxy.URL))
+	result, err := p.fetch(context.Background(), "http://esm.invalid/mod.js")
+	if err != nil || result.contents != "// proxied http://esm.invalid/mod.js" {
+		t.Errorf("expected the download to go through the proxy, got %v, %v", result, err)
+	}
+
+	if _, err = newPlugin(&Config{Proxy: "proxy.corp"}, nil); err == nil {
+		t.Error("expected an error for a proxy URL without scheme")
+	}
+}
+
+func TestFetchReusesConnections(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	p := newTestPlugin(t)
+	for round := 0; round < 3; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < defaultMaxFetchesPerHost; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if _, err := p.fetch(context.Background(), fmt.Sprintf("%s/%d/%d.js", server.URL, round, i)); err != nil {
+					t.Error(err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	if n := connections.Load(); n > defaultMaxFetchesPerHost {
+		t.Errorf("expected at most %d connections, got %d", defaultMaxFetchesPerHost, n)
+	}
+}
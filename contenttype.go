@@ -0,0 +1,76 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"mime"
+	"strings"
+)
+
+// contentTypePreviewLength is how many bytes of a rejected response are quoted in the error.
+const contentTypePreviewLength = 64
+
+// scriptMediaTypes are the media types accepted for the JS, JSX, TS and TSX loaders
+var scriptMediaTypes = map[string]bool{
+	"":                         true,
+	"application/javascript":   true,
+	"application/x-javascript": true,
+	"application/ecmascript":   true,
+	"text/javascript":          true,
+	"text/ecmascript":          true,
+	"text/jsx":                 true,
+	"application/typescript":   true,
+	"application/x-typescript": true,
+	"text/typescript":          true,
+	"text/plain":               true,
+	"application/octet-stream": true,
+}
+
+// loaderNames are the names of the loaders as used by esbuild's command line
+var loaderNames = map[api.Loader]string{
+	api.LoaderJS:      "js",
+	api.LoaderJSX:     "jsx",
+	api.LoaderTS:      "ts",
+	api.LoaderTSX:     "tsx",
+	api.LoaderJSON:    "json",
+	api.LoaderCSS:     "css",
+	api.LoaderFile:    "file",
+	api.LoaderBinary:  "binary",
+	api.LoaderDataURL: "dataurl",
+	api.LoaderText:    "text",
+	api.LoaderBase64:  "base64",
+}
+
+func loaderName(loader api.Loader) string {
+	if name, ok := loaderNames[loader]; ok {
+		return name
+	}
+	return fmt.Sprintf("loader(%d)", loader)
+}
+
+// checkContentType returns an error when the Content-Type of a downloaded module doesn't fit the loader.
+func checkContentType(rawUrl string, result *fetchResult, loader api.Loader) error {
+	contentType := result.header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	ok := true
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		ok = false
+	case loader == api.LoaderJS || loader == api.LoaderJSX || loader == api.LoaderTS || loader == api.LoaderTSX:
+		ok = scriptMediaTypes[mediaType]
+	}
+	if ok {
+		return nil
+	}
+
+	preview := result.contents
+	if len(preview) > contentTypePreviewLength {
+		preview = preview[:contentTypePreviewLength] + "..."
+	}
+	return fmt.Errorf("unexpected Content-Type %q for %s loaded with the %s loader, the response starts with %q",
+		contentType, rawUrl, loaderName(loader), preview)
+}
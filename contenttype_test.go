@@ -0,0 +1,33 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckContentType(t *testing.T) {
+	result := func(contentType string, contents string) *fetchResult {
+		return &fetchResult{contents: contents, header: http.Header{"Content-Type": []string{contentType}}}
+	}
+
+	if err := checkContentType("https://esm.sh/react", result("application/javascript; charset=utf-8", "export {}"), api.LoaderJS); err != nil {
+		t.Error(err)
+	}
+	if err := checkContentType("https://esm.sh/x.ts", result("application/typescript", "export {}"), api.LoaderTS); err != nil {
+		t.Error(err)
+	}
+
+	err := checkContentType("https://cdn.example/react", result("text/html", "<!DOCTYPE html><html><head><title>Sign in to the network</title></head></html>"), api.LoaderJS)
+	if err == nil {
+		t.Fatal("expected html responses to be rejected")
+	}
+	if !strings.Contains(err.Error(), "<!DOCTYPE html>") {
+		t.Errorf("expected the error to quote the body, got %v", err)
+	}
+
+	if err = checkContentType("https://cdn.example/logo", result("image/png", "\x89PNG"), api.LoaderJS); err == nil {
+		t.Error("expected images loaded as js to be rejected")
+	}
+}
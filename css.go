@@ -0,0 +1,14 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"strings"
+)
+
+// isRemoteAsset reports whether a resolution is a url() reference of a stylesheet to a remote
+// asset. Stylesheets are bundled with their @import rules, while the images and fonts they
+// reference are left to the browser.
+func isRemoteAsset(kind api.ResolveKind, resolvedPath string) bool {
+	return kind == api.ResolveCSSURLToken &&
+		(strings.HasPrefix(resolvedPath, "http://") || strings.HasPrefix(resolvedPath, "https://"))
+}
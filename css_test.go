@@ -0,0 +1,62 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// cssFetcher serves stylesheets with their media type
+type cssFetcher map[string]string
+
+func (f cssFetcher) Fetch(_ context.Context, rawUrl string) ([]byte, http.Header, string, error) {
+	contents, ok := f[rawUrl]
+	if !ok {
+		return nil, nil, "", &HTTPError{URL: rawUrl, StatusCode: http.StatusNotFound}
+	}
+	return []byte(contents), http.Header{"Content-Type": []string{"text/css"}}, rawUrl, nil
+}
+
+func TestPluginBundlesRemoteCSS(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"theme":  "https://mirror.invalid/theme@1.0.0/index",
+			"fonts/": "https://fonts.invalid/",
+		}}),
+		WithFetcher(cssFetcher{
+			"https://mirror.invalid/theme@1.0.0/index":    `@import "./base.css"; @import "fonts/inter.css"; body { background: url(./bg.png); }`,
+			"https://mirror.invalid/theme@1.0.0/base.css": `html { color: red; }`,
+			"https://fonts.invalid/inter.css":             `@font-face { font-family: Inter; src: url("/inter.woff2"); }`,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		LogLevel:    api.LogLevelSilent,
+		Write:       false,
+		Outdir:      t.TempDir(),
+		EntryPoints: []string{"theme"},
+		Plugins:     []api.Plugin{plugin},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	output := string(result.OutputFiles[0].Contents)
+	for _, expected := range []string{
+		"color: red",
+		"font-family: Inter",
+		"url(https://fonts.invalid/inter.woff2)",
+		"url(https://mirror.invalid/theme@1.0.0/bg.png)",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %s in the output:\n%s", expected, output)
+		}
+	}
+}
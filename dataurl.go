@@ -0,0 +1,73 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/evanw/esbuild/pkg/api"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// isDataURL reports whether rawUrl is a data: URL, like the tiny modules inlined in import maps.
+func isDataURL(rawUrl string) bool {
+	return len(rawUrl) > 5 && strings.EqualFold(rawUrl[:5], "data:")
+}
+
+// decodeDataURL returns the contents and the media type of a data: URL, which defaults to
+// text/plain like in browsers.
+func decodeDataURL(rawUrl string) ([]byte, string, error) {
+	header, data, ok := strings.Cut(rawUrl[len("data:"):], ",")
+	if !ok {
+		return nil, "", errors.New("invalid data URL: missing comma")
+	}
+
+	isBase64 := false
+	if params, found := strings.CutSuffix(header, ";base64"); found {
+		header, isBase64 = params, true
+	}
+	mediaType := "text/plain"
+	if header != "" {
+		parsed, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return nil, "", errors.New("invalid data URL: " + err.Error())
+		}
+		mediaType = parsed
+	}
+
+	decoded, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, "", errors.New("invalid data URL: " + err.Error())
+	}
+	if !isBase64 {
+		return []byte(decoded), mediaType, nil
+	}
+	contents, err := base64.StdEncoding.DecodeString(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			return -1
+		}
+		return r
+	}, decoded))
+	if err != nil {
+		return nil, "", errors.New("invalid data URL: " + err.Error())
+	}
+	return contents, mediaType, nil
+}
+
+// onLoadDataURL decodes a module mapped to a data: URL, loaded according to its media type.
+func (p *plugin) onLoadDataURL(args api.OnLoadArgs) (api.OnLoadResult, error) {
+	contents, mediaType, err := decodeDataURL(args.Path)
+	if err != nil {
+		return api.OnLoadResult{}, err
+	}
+
+	source := string(contents)
+	loader, ok := mediaTypeLoaders[mediaType]
+	if !ok {
+		loader = sniffLoader(source)
+	}
+	return api.OnLoadResult{
+		Contents: &source,
+		Loader:   loader,
+	}, nil
+}
@@ -0,0 +1,46 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestDecodeDataURL(t *testing.T) {
+	for _, tt := range []struct {
+		url       string
+		contents  string
+		mediaType string
+	}{
+		{"data:text/javascript,export%20default%201%3B", "export default 1;", "text/javascript"},
+		{"data:application/json;base64,eyJh IjoxfQ==", `{"a":1}`, "application/json"},
+		{"data:,hello", "hello", "text/plain"},
+	} {
+		contents, mediaType, err := decodeDataURL(tt.url)
+		if err != nil || string(contents) != tt.contents || mediaType != tt.mediaType {
+			t.Errorf("%s: unexpected result %q, %s, %v", tt.url, contents, mediaType, err)
+		}
+	}
+	if _, _, err := decodeDataURL("data:text/javascript;base64"); err == nil {
+		t.Error("expected an error without data")
+	}
+}
+
+func TestPluginLoadsDataURLs(t *testing.T) {
+	plugin, err := NewPlugin(WithMap(importmap.Data{Imports: importmap.Imports{
+		"shim":   "data:text/javascript,export const shim = 'inlined';",
+		"config": "data:application/json;base64,eyJhbnN3ZXIiOjQyfQ==",
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {shim} from 'shim'; import config from 'config'; console.log(shim, config.answer);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `"inlined"`) || !strings.Contains(output, "42") {
+		t.Errorf("expected the data URLs to be bundled, got:\n%s", output)
+	}
+}
@@ -0,0 +1,60 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// traceResolutions adds the trace of the import map resolution of every import the plugin
+// handles to the esbuild log, as a warning whose notes are the steps of the resolution and the
+// outcome of the plugin.
+func (p *plugin) traceResolutions(callback func(api.OnResolveArgs) (api.OnResolveResult, error)) func(api.OnResolveArgs) (api.OnResolveResult, error) {
+	return func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+		result, err := callback(args)
+		if err != nil || (result.Path == "" && len(result.Errors) == 0) {
+			return result, err
+		}
+
+		importMap := p.currentMap()
+		if p.hasEntryPointMaps() {
+			entryPoint := p.entryPoints.get(args.Importer)
+			if args.Kind == api.ResolveEntryPoint {
+				entryPoint = args.Path
+			}
+			importMap = p.mapFor(entryPoint)
+		}
+		parentUrl, parentErr := importerUrl(args)
+		if parentErr != nil {
+			return result, err
+		}
+
+		_, steps, _ := importMap.ResolveTrace(args.Path, parentUrl)
+		notes := make([]api.Note, 0, len(steps)+1)
+		for _, step := range steps {
+			notes = append(notes, api.Note{Text: step.String()})
+		}
+		outcome := fmt.Sprintf("plugin: %s in the %q namespace", result.Path, result.Namespace)
+		switch {
+		case len(result.Errors) > 0:
+			outcome = "plugin: failed with " + result.Errors[0].Text
+		case result.External:
+			outcome = fmt.Sprintf("plugin: %s left external", result.Path)
+		}
+		notes = append(notes, api.Note{Text: outcome})
+
+		result.Warnings = append(result.Warnings, api.Message{
+			Text:  fmt.Sprintf("[importmap] resolution of %q from %s", args.Path, parentUrl),
+			Notes: notes,
+		})
+		return result, err
+	}
+}
+
+// WithDebug logs how the import map resolved each import, with the scopes considered, the entry
+// matched and the final URL, to troubleshoot imports resolving somewhere unexpected. The traces
+// are warnings of the build, so the esbuild log shows them.
+func WithDebug() Option {
+	return func(config *Config) {
+		config.Debug = true
+	}
+}
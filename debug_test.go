@@ -0,0 +1,39 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginWithDebug(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"pkg": "https://cdn.invalid/pkg.js"}}),
+		WithFetcher(fixtureFetcher{"https://cdn.invalid/pkg.js": "export const pkg = 1;"}),
+		WithDebug(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	for _, warning := range result.Warnings {
+		if !strings.Contains(warning.Text, `resolution of "pkg"`) {
+			continue
+		}
+		var notes []string
+		for _, note := range warning.Notes {
+			notes = append(notes, note.Text)
+		}
+		trace := strings.Join(notes, "\n")
+		if !strings.Contains(trace, `match: imports map "pkg" -> "https://cdn.invalid/pkg.js"`) ||
+			!strings.Contains(trace, "result: resolved to https://cdn.invalid/pkg.js") {
+			t.Errorf("expected the match and final URL in the trace, got:\n%s", trace)
+		}
+		return
+	}
+	t.Errorf("expected the trace of pkg in the warnings, got %v", result.Warnings)
+}
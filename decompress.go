@@ -0,0 +1,53 @@
+package esbuild_plugin_importmap
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncoding are the content codings downloads ask for
+const acceptEncoding = "gzip, br, deflate"
+
+// decodeBody wraps body with the decoders of the content codings of the response, applied in
+// the reverse order of the Content-Encoding header.
+func decodeBody(body io.Reader, header http.Header) (io.Reader, error) {
+	codings := strings.Split(header.Get("Content-Encoding"), ",")
+	for idx := len(codings) - 1; idx >= 0; idx-- {
+		switch coding := strings.ToLower(strings.TrimSpace(codings[idx])); coding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			reader, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, err
+			}
+			body = reader
+		case "br":
+			body = brotli.NewReader(body)
+		case "deflate":
+			reader, err := zlib.NewReader(body)
+			if err != nil {
+				return nil, err
+			}
+			body = reader
+		default:
+			return nil, fmt.Errorf("unsupported Content-Encoding %q", coding)
+		}
+	}
+	return body, nil
+}
+
+// decodedHeader returns a copy of header describing the decoded body.
+func decodedHeader(header http.Header) http.Header {
+	if header.Get("Content-Encoding") == "" {
+		return header
+	}
+	header = header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	return header
+}
@@ -0,0 +1,54 @@
+package esbuild_plugin_importmap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"github.com/andybalholm/brotli"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPFetcherDecompresses(t *testing.T) {
+	const module = "export const pkg = 'compressed';"
+	var gzipped, brotlied bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, _ = gw.Write([]byte(module))
+	_ = gw.Close()
+	bw := brotli.NewWriter(&brotlied)
+	_, _ = bw.Write([]byte(module))
+	_ = bw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != acceptEncoding {
+			t.Errorf("unexpected Accept-Encoding %q", r.Header.Get("Accept-Encoding"))
+		}
+		switch r.URL.Path {
+		case "/gzip.js":
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(gzipped.Bytes())
+		case "/br.js":
+			w.Header().Set("Content-Encoding", "br")
+			_, _ = w.Write(brotlied.Bytes())
+		default:
+			w.Header().Set("Content-Encoding", "zstd")
+			_, _ = w.Write([]byte("?"))
+		}
+	}))
+	defer server.Close()
+
+	fetcher := &HTTPFetcher{}
+	for _, name := range []string{"/gzip.js", "/br.js"} {
+		body, header, _, err := fetcher.Fetch(context.Background(), server.URL+name)
+		if err != nil || string(body) != module {
+			t.Errorf("%s: unexpected body %q, %v", name, body, err)
+		}
+		if header.Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected the Content-Encoding to be removed", name)
+		}
+	}
+	if _, _, _, err := fetcher.Fetch(context.Background(), server.URL+"/zstd.js"); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+}
@@ -0,0 +1,115 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"sync"
+)
+
+// entryPointResolve marks the resolutions the plugin asks esbuild for while tracking entry points,
+// which the plugin leaves to esbuild and the other plugins.
+type entryPointResolve struct{}
+
+// entryPointTracker records which entry point first reached each module
+type entryPointTracker struct {
+	mu      sync.Mutex
+	modules map[string]string
+}
+
+func (t *entryPointTracker) get(path string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.modules[path]
+}
+
+// reset forgets the modules reached during the previous build.
+func (t *entryPointTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.modules = make(map[string]string)
+}
+
+func (t *entryPointTracker) set(path string, entryPoint string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.modules[path]; !ok {
+		t.modules[path] = entryPoint
+	}
+}
+
+// WithEntryPointMap overlays the import map with overlay for the modules imported by entryPoint,
+// directly or through other modules, so pages of a multi-page build can pin different versions.
+// entryPoint must be given as in the EntryPoints of the build options. A module shared by several
+// entry points is resolved with the map of the first entry point reaching it. Entry points and relative
+// imports resolved by plugins placed before this one can't be tracked.
+func WithEntryPointMap(entryPoint string, overlay importmap.Data) Option {
+	return func(config *Config) {
+		if config.EntryPointMaps == nil {
+			config.EntryPointMaps = make(map[string]importmap.Data)
+		}
+		config.EntryPointMaps[entryPoint] = overlay
+	}
+}
+
+// newEntryPointMaps merges the entry point overlays of config onto importMap.
+func newEntryPointMaps(config *Config, importMap importmap.IImportMap) (map[string]importmap.IImportMap, error) {
+	opts, err := mapOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	maps := make(map[string]importmap.IImportMap, len(config.EntryPointMaps))
+	for entryPoint, data := range config.EntryPointMaps {
+		overlay, err := importmap.New(append([]importmap.Option{importmap.WithMap(data)}, opts...)...)
+		if err != nil {
+			return nil, err
+		}
+		if maps[entryPoint], err = importmap.Merge(importMap, overlay, importmap.MergeScopes); err != nil {
+			return nil, err
+		}
+	}
+	return maps, nil
+}
+
+// entryPoint returns the entry point whose chain of importers leads to the module being resolved.
+// Imports the plugin leaves to esbuild are resolved once more to learn the paths of their modules.
+func (p *plugin) entryPoint(args api.OnResolveArgs) string {
+	entryPoint := p.entryPoints.get(args.Importer)
+	if args.Kind == api.ResolveEntryPoint {
+		entryPoint = args.Path
+	}
+	if entryPoint == "" {
+		return ""
+	}
+
+	kind, _ := importmap.ParseSpecifier(args.Path, nil)
+	if args.Kind == api.ResolveEntryPoint || (kind == importmap.SpecifierRelative && args.Namespace != namespace) {
+		result := p.build.Resolve(args.Path, api.ResolveOptions{
+			Importer:   args.Importer,
+			Namespace:  args.Namespace,
+			ResolveDir: args.ResolveDir,
+			Kind:       args.Kind,
+			PluginData: entryPointResolve{},
+		})
+		if len(result.Errors) == 0 {
+			p.entryPoints.set(result.Path, entryPoint)
+		}
+	}
+	return entryPoint
+}
+
+// mapFor returns the import map used to resolve imports reached from entryPoint.
+func (p *plugin) mapFor(entryPoint string) importmap.IImportMap {
+	p.mapsMu.RLock()
+	defer p.mapsMu.RUnlock()
+	if m, ok := p.entryPointMaps[entryPoint]; ok {
+		return m
+	}
+	return p.importMap
+}
+
+// hasEntryPointMaps reports whether some entry points have their own import map.
+func (p *plugin) hasEntryPointMaps() bool {
+	p.mapsMu.RLock()
+	defer p.mapsMu.RUnlock()
+	return len(p.entryPointMaps) > 0
+}
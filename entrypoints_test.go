@@ -0,0 +1,90 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestPluginEntryPointMaps(t *testing.T) {
+	files := map[string]string{
+		"a.js":        "import {widget} from 'widget'; import './a-helper.js'; console.log(widget);",
+		"a-helper.js": "import {lib} from 'lib'; console.log(lib);",
+		"b.js":        "import {widget} from 'widget'; console.log(widget);",
+	}
+	pages := api.Plugin{
+		Name: "pages",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: `^\./`}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				return api.OnResolveResult{Path: path.Clean(args.Path), Namespace: "pages"}, nil
+			})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: "pages"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				contents := files[args.Path]
+				return api.OnLoadResult{Contents: &contents, Loader: api.LoaderJS}, nil
+			})
+		},
+	}
+
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"widget": "https://mirror.invalid/widget@1.js",
+			"lib":    "https://mirror.invalid/lib@1.js",
+		}}),
+		WithEntryPointMap("./a.js", importmap.Data{Imports: importmap.Imports{
+			"widget": "https://mirror.invalid/widget@2.js",
+			"lib":    "https://mirror.invalid/lib@2.js",
+		}}),
+		WithFetcher(fixtureFetcher{
+			"https://mirror.invalid/widget@1.js": "export const widget = 'widget-v1';",
+			"https://mirror.invalid/widget@2.js": "export const widget = 'widget-v2';",
+			"https://mirror.invalid/lib@1.js":    "export const lib = 'lib-v1';",
+			"https://mirror.invalid/lib@2.js":    "export const lib = 'lib-v2';",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		Outdir:      "out",
+		EntryPoints: []string{"./a.js", "./b.js"},
+		Plugins:     []api.Plugin{plugin, pages},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	outputs := map[string]string{}
+	for _, file := range result.OutputFiles {
+		outputs[path.Base(file.Path)] = string(file.Contents)
+	}
+	if a := outputs["a.js"]; !strings.Contains(a, "widget-v2") || !strings.Contains(a, "lib-v2") {
+		t.Errorf("expected page a to use its overlay, got:\n%s", a)
+	}
+	if b := outputs["b.js"]; !strings.Contains(b, "widget-v1") {
+		t.Errorf("expected page b to use the base map, got:\n%s", b)
+	}
+}
+
+func TestEntryPointsReset(t *testing.T) {
+	p := newTestPlugin(t)
+	m, _ := importmap.New()
+	if err := p.replaceImportMap(m); err != nil {
+		t.Fatal(err)
+	}
+	p.entryPoints.set("shared.js", "./a.js")
+
+	// a module reached from another entry point in the next build gets its map
+	if _, err := p.onStart(); err != nil {
+		t.Fatal(err)
+	}
+	p.entryPoints.set("shared.js", "./b.js")
+	if entryPoint := p.entryPoints.get("shared.js"); entryPoint != "./b.js" {
+		t.Errorf("expected the entry points of the previous build to be forgotten, got %s", entryPoint)
+	}
+}
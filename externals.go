@@ -0,0 +1,117 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// externalsTracker records the mapped specifiers left external during a build
+type externalsTracker struct {
+	mu          sync.Mutex
+	resolutions map[mappingKey][]importmap.Resolution
+}
+
+func (e *externalsTracker) record(resolution importmap.Resolution) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := mappingKey{resolution.Scope, resolution.Key}
+	for _, recorded := range e.resolutions[key] {
+		if recorded.URL == resolution.URL {
+			return
+		}
+	}
+	e.resolutions[key] = append(e.resolutions[key], resolution)
+}
+
+func (e *externalsTracker) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resolutions = make(map[mappingKey][]importmap.Resolution)
+}
+
+// tracksExternals reports whether a map of the specifiers left external is written after builds
+func (p *plugin) tracksExternals() bool {
+	return p.config.ExternalsMapPath != "" || p.config.ResidualMap || p.config.InjectHTMLPath != ""
+}
+
+// matchesExternal reports whether specifier matches one of the external patterns of esbuild,
+// which may contain a single "*" wildcard.
+func matchesExternal(patterns []string, specifier string) bool {
+	for _, pattern := range patterns {
+		if prefix, suffix, ok := strings.Cut(pattern, "*"); ok {
+			if len(specifier) >= len(prefix)+len(suffix) &&
+				strings.HasPrefix(specifier, prefix) && strings.HasSuffix(specifier, suffix) {
+				return true
+			}
+		} else if pattern == specifier {
+			return true
+		}
+	}
+	return false
+}
+
+// externalsMap returns an import map with the entries of the map used by the specifiers left
+// external, in their scopes, and the integrity of the URLs they resolved to.
+func (p *plugin) externalsMap() importmap.Data {
+	p.externals.mu.Lock()
+	defer p.externals.mu.Unlock()
+
+	data := importmap.Data{
+		Imports:   make(importmap.Imports),
+		Scopes:    make(importmap.Scopes),
+		Integrity: make(importmap.Integrity),
+	}
+	importMap := p.currentMap()
+	imports := importMap.GetImports()
+	scopes := importMap.GetScopes()
+	for key, resolutions := range p.externals.resolutions {
+		if key.scope == "" {
+			data.Imports[key.key] = imports[key.key]
+		} else {
+			if data.Scopes[key.scope] == nil {
+				data.Scopes[key.scope] = make(importmap.Scope)
+			}
+			data.Scopes[key.scope][key.key] = scopes[key.scope][key.key]
+		}
+		for _, resolution := range resolutions {
+			if integrity, err := importMap.GetIntegrityValue(resolution.URL, ""); err == nil {
+				data.Integrity[resolution.URL] = integrity
+			}
+		}
+	}
+	return data
+}
+
+// writeExternalsMap writes the import map of the external specifiers to Config.ExternalsMapPath.
+func (p *plugin) writeExternalsMap() error {
+	contents, err := importmap.Format(p.externalsMap(), p.config.MapFormat...)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p.config.ExternalsMapPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p.config.ExternalsMapPath, contents, 0o644)
+}
+
+// WithExternal leaves the mapped specifiers matching patterns external, like the External build
+// option does, e.g. "react" when the page's own import map provides it at runtime. Patterns may
+// contain a single "*" wildcard.
+func WithExternal(patterns []string) Option {
+	return func(config *Config) {
+		config.External = append(config.External, patterns...)
+	}
+}
+
+// WithExternalsMap writes an import map for the browser to path after each build, holding only
+// the mapped specifiers marked external with WithExternal or in the build options, with their
+// scopes and integrity.
+// The bundle covers the other specifiers, so the map inlined in the served HTML stays small.
+func WithExternalsMap(path string) Option {
+	return func(config *Config) {
+		config.ExternalsMapPath = path
+	}
+}
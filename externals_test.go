@@ -0,0 +1,108 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const reactIntegrity = "sha384-OLBgp1GsljhM2TJ+sbHjaiH9txEUvgdDTAzHv2P24donTt6/529l+9Ua0vFImLlb"
+
+func TestMatchesExternal(t *testing.T) {
+	patterns := []string{"react", "@scope/*", "*.css"}
+	cases := map[string]bool{
+		"react":         true,
+		"react-dom":     false,
+		"@scope/pkg":    true,
+		"@other/pkg":    false,
+		"theme.css":     true,
+		"theme.css.map": false,
+	}
+	for specifier, expected := range cases {
+		if actual := matchesExternal(patterns, specifier); actual != expected {
+			t.Errorf("matchesExternal(%q) = %v, expected %v", specifier, actual, expected)
+		}
+	}
+}
+
+func TestPluginExternalsMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "externals.json")
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{
+			Imports: importmap.Imports{
+				"react":   "https://esm.invalid/react@18.2.0",
+				"bundled": "https://esm.invalid/bundled.js",
+				"unused":  "https://esm.invalid/unused.js",
+			},
+			Integrity: importmap.Integrity{
+				"https://esm.invalid/react@18.2.0": reactIntegrity,
+			},
+		}),
+		WithFetcher(fixtureFetcher{"https://esm.invalid/bundled.js": "export const bundled = 1;"}),
+		WithExternalsMap(path),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		External:    []string{"react", "unused"},
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, "import React from 'react'; import {bundled} from 'bundled'; console.log(React, bundled);"),
+			plugin,
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, `from "react"`) {
+		t.Errorf("expected react to be left external, got:\n%s", output)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := importmap.Data{}
+	if err = json.Unmarshal(contents, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Imports) != 1 || data.Imports["react"] != "https://esm.invalid/react@18.2.0" {
+		t.Errorf("expected only the external specifier in the map, got %s", contents)
+	}
+	if data.Integrity["https://esm.invalid/react@18.2.0"] != reactIntegrity {
+		t.Errorf("expected the integrity of the external specifier, got %s", contents)
+	}
+}
+
+func TestPluginWithExternal(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"react":     "https://esm.invalid/react@18.2.0",
+			"react-dom": "https://esm.invalid/react-dom@18.2.0",
+			"bundled":   "https://esm.invalid/bundled.js",
+		}}),
+		WithFetcher(fixtureFetcher{"https://esm.invalid/bundled.js": "export const bundled = 1;"}),
+		WithExternal([]string{"react", "react-*"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import React from 'react'; import ReactDOM from 'react-dom'; import {bundled} from 'bundled'; console.log(React, ReactDOM, bundled);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `from "react"`) || !strings.Contains(output, `from "react-dom"`) {
+		t.Errorf("expected react and react-dom to be left external, got:\n%s", output)
+	}
+}
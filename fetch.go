@@ -0,0 +1,272 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultMaxFetchesPerHost mirrors the per-origin connection limit browsers use.
+	defaultMaxFetchesPerHost = 6
+
+	// fetchesPerProc is how many downloads are kept in flight per available core.
+	// Fetching is dominated by network latency, so a few requests per core keep the
+	// cores busy without tripping CDN throttling.
+	fetchesPerProc = 4
+
+	// maxDefaultConcurrentFetches caps the auto-tuned total on machines with many cores.
+	maxDefaultConcurrentFetches = 64
+
+	// maxRetryAfter is the longest Retry-After delay the plugin is willing to wait for.
+	maxRetryAfter = 30 * time.Second
+)
+
+// HTTPError is returned when a remote module can't be downloaded because of the response status
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	// RetryAfter is the delay requested by the server with the Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	var reason string
+	switch {
+	case e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone:
+		reason = "the module does not exist"
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		reason = "access to the module was denied"
+	case e.StatusCode == http.StatusTooManyRequests:
+		reason = "the server is rate limiting requests"
+	case e.StatusCode >= 500:
+		reason = "the server failed to respond"
+	default:
+		reason = "unexpected response"
+	}
+	return fmt.Sprintf("failed to download %s: %s (%d %s)", e.URL, reason, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retryable reports whether the request may succeed if it is sent again later
+func (e *HTTPError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// defaultMaxConcurrentFetches returns the auto-tuned total download parallelism.
+func defaultMaxConcurrentFetches() int {
+	n := runtime.GOMAXPROCS(0) * fetchesPerProc
+	if n < defaultMaxFetchesPerHost {
+		n = defaultMaxFetchesPerHost
+	}
+	if n > maxDefaultConcurrentFetches {
+		n = maxDefaultConcurrentFetches
+	}
+	return n
+}
+
+// fetchResult is a downloaded remote module
+type fetchResult struct {
+	contents string
+	header   http.Header
+	// body is the module as served, before the OnAfterFetch hook
+	body string
+	// finalUrl is the URL the module was served from after redirects
+	finalUrl string
+	// fromCache is set for modules served without a download
+	fromCache bool
+	// vendored is set for modules read from the vendor directory, whose contents already went
+	// through the OnAfterFetch hook and interop, body included
+	vendored bool
+}
+
+// fetch downloads the given url while respecting the configured parallelism, unless it is in the
+// cache directory or was already downloaded during the build.
+func (p *plugin) fetch(ctx context.Context, rawUrl string) (*fetchResult, error) {
+	if hook := p.config.Hooks.OnBeforeFetch; hook != nil {
+		var err error
+		if rawUrl, err = hook(ctx, rawUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if hook := p.config.Hooks.OnFetchStart; hook != nil {
+		hook(rawUrl)
+	}
+
+	start := time.Now()
+	var result *fetchResult
+	if ctx.Value(bypassCacheKey{}) != nil {
+		result, err = p.fetchUncoalesced(ctx, u)
+	} else {
+		result, err = p.downloads.do(rawUrl, func() (*fetchResult, error) {
+			return p.fetchUncoalesced(ctx, u)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.config.OnStats != nil {
+		p.stats.fetched(result, time.Since(start))
+	}
+	if hook := p.config.Hooks.OnFetchComplete; hook != nil {
+		hook(rawUrl, len(result.body), result.fromCache)
+	}
+
+	if hook := p.config.Hooks.OnAfterFetch; hook != nil && !result.vendored {
+		if result.contents, err = hook(rawUrl, result.contents); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// fetchUncoalesced returns the module cached for u or downloads it.
+func (p *plugin) fetchUncoalesced(ctx context.Context, u *url.URL) (*fetchResult, error) {
+	rawUrl := u.String()
+	if result, ok := p.readCache(ctx, rawUrl); ok {
+		return result, nil
+	}
+	if p.config.Offline {
+		if result, ok := p.readVendored(rawUrl); ok {
+			return result, nil
+		}
+		p.offline.record(rawUrl)
+		return nil, &OfflineError{URL: rawUrl}
+	}
+	result, err := p.download(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.writeCache(rawUrl, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// download fetches u from upstream, retrying failed requests according to the configuration.
+func (p *plugin) download(ctx context.Context, u *url.URL) (*fetchResult, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := p.fetchOnce(ctx, u)
+		if err == nil || ctx.Err() != nil {
+			return result, err
+		}
+
+		delay, ok := p.retryDelay(err, attempt)
+		if !ok {
+			return nil, err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *plugin) fetchOnce(ctx context.Context, u *url.URL) (*fetchResult, error) {
+	release, err := p.limiter.acquire(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	attemptCtx, cancel := p.fetchTimeout(ctx)
+	defer cancel()
+	body, header, finalUrl, err := p.fetcher.Fetch(attemptCtx, u.String())
+
+	statusCode := http.StatusOK
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		statusCode = httpErr.StatusCode
+	}
+	if isRateLimited(statusCode, header) {
+		p.limiter.throttle(u.Host)
+	} else if err == nil {
+		p.limiter.succeed(u.Host)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	if finalUrl == "" {
+		finalUrl = u.String()
+	}
+
+	return &fetchResult{
+		contents: string(body),
+		body:     string(body),
+		header:   header,
+		finalUrl: finalUrl,
+	}, nil
+}
+
+// rateLimitRemainingHeaders are the headers CDNs use to announce the requests left in the current window
+var rateLimitRemainingHeaders = []string{
+	"X-RateLimit-Remaining",
+	"RateLimit-Remaining",
+	"X-Rate-Limit-Remaining",
+}
+
+// isRateLimited reports whether the response was throttled or exhausted the rate limit of the server.
+func isRateLimited(statusCode int, header http.Header) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	for _, name := range rateLimitRemainingHeaders {
+		if value := header.Get(name); value != "" {
+			if remaining, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && remaining <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// throttlingWarnings reports the origins which rate limited downloads since the last call.
+func (p *plugin) throttlingWarnings() []api.Message {
+	var warnings []api.Message
+	for _, throttled := range p.limiter.takeThrottled() {
+		warnings = append(warnings, api.Message{
+			Text: fmt.Sprintf("you are being throttled by %s: %d requests were rate limited, parallel downloads from it were reduced to %d",
+				throttled.host, throttled.requests, throttled.limit),
+		})
+	}
+	return warnings
+}
+
+// parseRetryAfter parses a Retry-After header value, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
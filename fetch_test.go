@@ -0,0 +1,150 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPlugin(t *testing.T, opts ...Option) *plugin {
+	t.Helper()
+
+	config := &Config{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	p, err := newPlugin(config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestFetchStatusErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.js":
+			_, _ = w.Write([]byte("export default 1;"))
+		case "/gone.js":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("<html>not found</html>"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	p := newTestPlugin(t)
+
+	result, err := p.fetch(context.Background(), server.URL+"/ok.js")
+	if err != nil || result.contents != "export default 1;" {
+		t.Errorf("unexpected result %v, %v", result, err)
+	}
+
+	var httpErr *HTTPError
+	if _, err = p.fetch(context.Background(), server.URL+"/gone.js"); !errors.As(err, &httpErr) {
+		t.Fatalf("expected an *HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusNotFound || httpErr.Retryable() {
+		t.Errorf("expected a permanent 404 error, got %v", httpErr)
+	}
+
+	if _, err = p.fetch(context.Background(), server.URL+"/flaky.js"); !errors.As(err, &httpErr) || !httpErr.Retryable() {
+		t.Errorf("expected a retryable error, got %v", err)
+	}
+}
+
+func TestFetchHonorsRetryAfter(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	result, err := newTestPlugin(t).fetch(context.Background(), server.URL+"/mod.js")
+	if err != nil || result.contents != "export default 1;" {
+		t.Fatalf("unexpected result %v, %v", result, err)
+	}
+	if time.Since(start) < time.Second {
+		t.Error("expected the retry to wait for the Retry-After delay")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("120", now); d != 2*time.Minute {
+		t.Errorf("expected 2m, got %s", d)
+	}
+	if d := parseRetryAfter("Mon, 01 Jan 2024 00:00:30 GMT", now); d != 30*time.Second {
+		t.Errorf("expected 30s, got %s", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Errorf("expected 0, got %s", d)
+	}
+}
+
+func TestFetchReportsThrottling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	p := newTestPlugin(t)
+	if _, err := p.fetch(context.Background(), server.URL+"/mod.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := p.throttlingWarnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Text, "you are being throttled by") {
+		t.Errorf("expected a throttling warning, got %v", warnings)
+	}
+}
+
+func TestFetchProgressHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var events []string
+	hooks := Hooks{
+		OnFetchStart: func(rawUrl string) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, "start "+strings.TrimPrefix(rawUrl, server.URL))
+		},
+		OnFetchComplete: func(rawUrl string, bytes int, fromCache bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, fmt.Sprintf("complete %s %d %v", strings.TrimPrefix(rawUrl, server.URL), bytes, fromCache))
+		},
+	}
+
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		p := newTestPlugin(t, WithHooks(hooks), WithCacheDir(dir))
+		if _, err := p.fetch(context.Background(), server.URL+"/mod.js"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{"start /mod.js", "complete /mod.js 17 false", "start /mod.js", "complete /mod.js 17 true"}
+	if strings.Join(events, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
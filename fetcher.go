@@ -0,0 +1,90 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Fetcher downloads remote modules, e.g. from a mirror, a gateway or recorded fixtures
+type Fetcher interface {
+	// Fetch returns the body and headers of the resource at rawUrl along with the URL it was
+	// finally served from. Failed downloads should be reported with *HTTPError.
+	Fetch(ctx context.Context, rawUrl string) (body []byte, header http.Header, finalUrl string, err error)
+}
+
+// HTTPFetcher is the default Fetcher, downloading modules with an http.Client.
+// Responses are revalidated with conditional requests when it has a Cache.
+type HTTPFetcher struct {
+	Client *http.Client
+	Cache  *HTTPCache
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawUrl string) ([]byte, http.Header, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// asking for the encodings explicitly disables the transparent gzip decoding of the transport
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	var cached *httpCacheEntry
+	if f.Cache != nil {
+		var ok bool
+		if cached, ok = f.Cache.get(rawUrl); ok {
+			cached.setConditionalHeaders(req)
+		}
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cached.body, cached.header, cached.finalUrl, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, resp.Header, "", &HTTPError{
+			URL:        rawUrl,
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	decoded, err := decodeBody(resp.Body, resp.Header)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to download %s: %w", rawUrl, err)
+	}
+	body, err := io.ReadAll(decoded)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	header := decodedHeader(resp.Header)
+
+	finalUrl := resp.Request.URL.String()
+	if f.Cache != nil {
+		f.Cache.put(rawUrl, &httpCacheEntry{body: body, header: header, finalUrl: finalUrl})
+	}
+
+	return body, header, finalUrl, nil
+}
+
+// WithFetcher replaces the HTTP client used to download remote modules
+func WithFetcher(fetcher Fetcher) Option {
+	return func(config *Config) {
+		config.Fetcher = fetcher
+	}
+}
@@ -0,0 +1,59 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fixtureFetcher serves recorded modules
+type fixtureFetcher map[string]string
+
+func (f fixtureFetcher) Fetch(ctx context.Context, rawUrl string) ([]byte, http.Header, string, error) {
+	contents, ok := f[rawUrl]
+	if !ok {
+		return nil, nil, "", &HTTPError{URL: rawUrl, StatusCode: http.StatusNotFound}
+	}
+	return []byte(contents), http.Header{"Content-Type": []string{"text/javascript"}}, rawUrl, nil
+}
+
+func TestPluginWithFetcher(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"pkg": "https://mirror.invalid/pkg.js"}}),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/pkg.js": "export const pkg = 'fixture';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), `"fixture"`) {
+		t.Errorf("expected the module to come from the fetcher, got:\n%s", result.OutputFiles[0].Contents)
+	}
+}
+
+func TestHTTPFetcherFinalUrl(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest.js", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/v2.js", http.StatusFound)
+	})
+	mux.HandleFunc("/v2.js", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("export {};"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	body, _, finalUrl, err := (&HTTPFetcher{}).Fetch(context.Background(), server.URL+"/latest.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "export {};" || finalUrl != server.URL+"/v2.js" {
+		t.Errorf("unexpected result %q from %s", body, finalUrl)
+	}
+}
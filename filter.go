@@ -0,0 +1,60 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// WithResolveFilter sets the esbuild filter, a Go regular expression, of the paths the plugin
+// resolves, ".*" by default, so esbuild doesn't call the plugin for the imports meant for other
+// resolver plugins. The imports of the modules the plugin loads are always resolved by it.
+func WithResolveFilter(filter string) Option {
+	return func(config *Config) {
+		config.ResolveFilter = filter
+	}
+}
+
+// WithIncludedSpecifiers makes the plugin resolve only the specifiers matching patterns, leaving
+// the other ones to other plugins and esbuild. Patterns are specifiers or contain a "*" wildcard
+// like the external patterns of esbuild, e.g. "react" and "@lit/*". Like WithResolveFilter it
+// doesn't apply to the imports of the modules the plugin loads.
+func WithIncludedSpecifiers(patterns ...string) Option {
+	return func(config *Config) {
+		config.IncludedSpecifiers = append(config.IncludedSpecifiers, patterns...)
+	}
+}
+
+// WithExcludedSpecifiers leaves the specifiers matching patterns to other plugins and esbuild,
+// even when they are included, see WithIncludedSpecifiers for the patterns.
+func WithExcludedSpecifiers(patterns ...string) Option {
+	return func(config *Config) {
+		config.ExcludedSpecifiers = append(config.ExcludedSpecifiers, patterns...)
+	}
+}
+
+// resolveFilter returns the esbuild filter of the imports the plugin resolves.
+func (p *plugin) resolveFilter() string {
+	if p.config.ResolveFilter == "" {
+		return ".*"
+	}
+	return p.config.ResolveFilter
+}
+
+// filterSpecifiers leaves the specifiers which aren't included, or are excluded, to other
+// plugins and esbuild, unless they are imported by the modules of the plugin's namespaces.
+func (p *plugin) filterSpecifiers(callback func(api.OnResolveArgs) (api.OnResolveResult, error)) func(api.OnResolveArgs) (api.OnResolveResult, error) {
+	return func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+		if args.Namespace != namespace && args.Namespace != schemeNamespace && !p.intercepts(args.Path) {
+			return api.OnResolveResult{}, nil
+		}
+		return callback(args)
+	}
+}
+
+// intercepts tells whether the plugin resolves specifier according to the included and excluded
+// specifiers.
+func (p *plugin) intercepts(specifier string) bool {
+	if len(p.config.IncludedSpecifiers) > 0 && !matchesExternal(p.config.IncludedSpecifiers, specifier) {
+		return false
+	}
+	return !matchesExternal(p.config.ExcludedSpecifiers, specifier)
+}
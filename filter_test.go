@@ -0,0 +1,71 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginResolveFilter(t *testing.T) {
+	data := importmap.Data{Imports: importmap.Imports{
+		"react":  "https://cdn.invalid/react.js",
+		"lodash": "https://cdn.invalid/lodash.js",
+		"app":    "https://cdn.invalid/app/index.js",
+	}}
+	fetcher := fixtureFetcher{
+		"https://cdn.invalid/react.js":     "export default 'mapped react';",
+		"https://cdn.invalid/lodash.js":    "export default 'mapped lodash';",
+		"https://cdn.invalid/app/index.js": "export {default} from './dep.js';",
+		"https://cdn.invalid/app/dep.js":   "export default 'mapped app';",
+	}
+	// another resolver plugin handles what the import map plugin leaves to it
+	other := api.Plugin{
+		Name: "other",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: "^[a-z]+$"}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				return api.OnResolveResult{Path: args.Path, Namespace: "other"}, nil
+			})
+			build.OnLoad(api.OnLoadOptions{Filter: ".*", Namespace: "other"}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
+				contents := "export default 'other " + args.Path + "';"
+				return api.OnLoadResult{Contents: &contents}, nil
+			})
+		},
+	}
+	build := func(opts ...Option) string {
+		t.Helper()
+		plugin, err := NewPlugin(append([]Option{WithMap(data), WithFetcher(fetcher)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := api.Build(api.BuildOptions{
+			Bundle:   true,
+			Format:   api.FormatESModule,
+			LogLevel: api.LogLevelSilent,
+			Stdin:    &api.StdinOptions{Contents: "import react from 'react'; import lodash from 'lodash'; import app from 'app'; console.log(react, lodash, app);"},
+			Plugins:  []api.Plugin{plugin, other},
+		})
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		return string(result.OutputFiles[0].Contents)
+	}
+
+	output := build(WithResolveFilter("^(react|app)$"))
+	for _, expected := range []string{"mapped react", "other lodash", "mapped app"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q with the resolve filter, got:\n%s", expected, output)
+		}
+	}
+
+	output = build(WithIncludedSpecifiers("react", "a*"), WithExcludedSpecifiers("app"))
+	for _, expected := range []string{"mapped react", "other lodash", "other app"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q with the included and excluded specifiers, got:\n%s", expected, output)
+		}
+	}
+
+	if _, err := NewPlugin(WithMap(data), WithResolveFilter("(")); err == nil {
+		t.Error("expected an invalid filter to be rejected")
+	}
+}
@@ -2,6 +2,9 @@ module github.com/pushrbx/esbuild-plugin-importmap
 
 go 1.22.5
 
-require github.com/evanw/esbuild v0.23.0
+require (
+	github.com/andybalholm/brotli v1.2.5
+	github.com/evanw/esbuild v0.23.0
+)
 
 require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
@@ -0,0 +1,51 @@
+package esbuild_plugin_importmap
+
+import (
+	"net/http"
+	"sync"
+)
+
+// HTTPCache keeps downloaded modules along with their ETag and Last-Modified validators, so they
+// are revalidated with conditional requests instead of being downloaded again
+type HTTPCache struct {
+	mu      sync.Mutex
+	entries map[string]*httpCacheEntry
+}
+
+type httpCacheEntry struct {
+	body     []byte
+	header   http.Header
+	finalUrl string
+}
+
+// NewHTTPCache creates an empty HTTPCache
+func NewHTTPCache() *HTTPCache {
+	return &HTTPCache{entries: make(map[string]*httpCacheEntry)}
+}
+
+func (c *HTTPCache) get(rawUrl string) (*httpCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[rawUrl]
+	return entry, ok
+}
+
+// put stores a response, unless it has no validator to revalidate it with.
+func (c *HTTPCache) put(rawUrl string, entry *httpCacheEntry) {
+	if entry.header.Get("ETag") == "" && entry.header.Get("Last-Modified") == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[rawUrl] = entry
+}
+
+// setConditionalHeaders adds the validators of the cached response to req.
+func (e *httpCacheEntry) setConditionalHeaders(req *http.Request) {
+	if etag := e.header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.header.Get("Last-Modified"); lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+}
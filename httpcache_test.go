@@ -0,0 +1,42 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPFetcherRevalidates(t *testing.T) {
+	var downloads, revalidations int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	fetcher := &HTTPFetcher{Cache: NewHTTPCache()}
+	for i := 0; i < 3; i++ {
+		body, header, _, err := fetcher.Fetch(context.Background(), server.URL+"/mod.js")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != "export default 1;" || header.Get("ETag") != `"v1"` {
+			t.Errorf("unexpected response %q, %v", body, header)
+		}
+	}
+	if downloads != 1 || revalidations != 2 {
+		t.Errorf("expected 1 download and 2 revalidations, got %d and %d", downloads, revalidations)
+	}
+
+	body, _, _, err := (&HTTPFetcher{}).Fetch(context.Background(), server.URL+"/mod.js")
+	if err != nil || string(body) != "export default 1;" || downloads != 2 {
+		t.Errorf("expected a download without a cache, got %q, %v", body, err)
+	}
+}
@@ -0,0 +1,62 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"path/filepath"
+)
+
+// withQuery sets the query and fragment of u to the ones of from, which file system paths can't
+// hold, and returns u.
+func withQuery(u *url.URL, from *url.URL) *url.URL {
+	u.RawQuery, u.ForceQuery = from.RawQuery, from.ForceQuery
+	u.Fragment, u.RawFragment = from.Fragment, from.RawFragment
+	return u
+}
+
+// filePath returns the file system path of a file URL without its query and fragment, or rawPath
+// itself when it isn't a file URL.
+func filePath(rawPath string) string {
+	if u, err := url.Parse(rawPath); err == nil && u.Scheme == "file" {
+		if path, err := importmap.FilePath(u); err == nil {
+			return path
+		}
+	}
+	return rawPath
+}
+
+// importerUrl returns the URL the imports of a module are resolved against, so the scopes of the
+// map apply to local files as they do to remote modules:
+//   - the URL a downloaded module was served from, after redirects;
+//   - the importer itself for the modules of the plugin's namespaces;
+//   - the file URL of files, or of the resolve directory for entry points and stdin, which have
+//     no importer.
+func importerUrl(args api.OnResolveArgs) (*url.URL, error) {
+	if provenance := provenanceOf(args.PluginData); provenance != nil && provenance.BaseURL != "" {
+		return url.Parse(provenance.BaseURL)
+	}
+
+	switch {
+	case args.Namespace == namespace || args.Namespace == schemeNamespace:
+		return url.Parse(args.Importer)
+	case (args.Namespace == "file" || args.Namespace == "") && filepath.IsAbs(args.Importer):
+		return importmap.FileURL(args.Importer), nil
+	case args.Importer == "" || args.Importer == "<stdin>":
+		return resolveDirUrl(args), nil
+	}
+
+	// importers of other plugins' namespaces are used as is when they are URLs
+	if u, err := url.Parse(args.Importer); err == nil {
+		return u, nil
+	}
+	return resolveDirUrl(args), nil
+}
+
+// resolveDirUrl returns the URL of the resolve directory of args, empty without one.
+func resolveDirUrl(args api.OnResolveArgs) *url.URL {
+	if args.ResolveDir == "" {
+		return &url.URL{}
+	}
+	return importmap.DirURL(args.ResolveDir)
+}
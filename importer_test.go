@@ -0,0 +1,84 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImporterUrl(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		args     api.OnResolveArgs
+		expected string
+	}{
+		{api.OnResolveArgs{Importer: filepath.Join(dir, "src", "index.js"), Namespace: "file"}, importmap.FileURL(filepath.Join(dir, "src", "index.js")).String()},
+		{api.OnResolveArgs{ResolveDir: filepath.Join(dir, "src"), Kind: api.ResolveEntryPoint}, importmap.FileURL(filepath.Join(dir, "src")).String() + "/"},
+		{api.OnResolveArgs{Importer: "https://esm.invalid/a.js", Namespace: namespace}, "https://esm.invalid/a.js"},
+		{api.OnResolveArgs{Importer: "https://esm.invalid/a.js", Namespace: namespace, PluginData: &Provenance{BaseURL: "https://esm.invalid/b.js"}}, "https://esm.invalid/b.js"},
+	}
+	for _, c := range cases {
+		u, err := importerUrl(c.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.String() != c.expected {
+			t.Errorf("expected %s for %+v, got %s", c.expected, c.args, u)
+		}
+	}
+}
+
+func TestPluginScopesForLocalFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"src/app/index.js": "import {dep} from 'dep'; console.log(dep);",
+		"scoped.js":        "export const dep = 'scoped';",
+		"global.js":        "export const dep = 'global';",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := importmap.FileURL(dir).String()
+	plugin, err := NewPlugin(WithMap(importmap.Data{
+		Imports: importmap.Imports{"dep": root + "/global.js"},
+		Scopes:  importmap.Scopes{root + "/src/app/": {"dep": root + "/scoped.js"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(options api.BuildOptions) string {
+		options.Bundle = true
+		options.Format = api.FormatESModule
+		options.LogLevel = api.LogLevelSilent
+		options.Plugins = []api.Plugin{plugin}
+		result := api.Build(options)
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		return string(result.OutputFiles[0].Contents)
+	}
+
+	output := build(api.BuildOptions{EntryPoints: []string{filepath.Join(dir, "src", "app", "index.js")}})
+	if !strings.Contains(output, `"scoped"`) {
+		t.Errorf("expected the scope of the importer's directory to apply, got:\n%s", output)
+	}
+
+	output = build(api.BuildOptions{Stdin: &api.StdinOptions{
+		Contents:   "import {dep} from 'dep'; console.log(dep);",
+		ResolveDir: filepath.Join(dir, "src", "app"),
+	}})
+	if !strings.Contains(output, `"scoped"`) {
+		t.Errorf("expected the scope of the resolve directory to apply to stdin, got:\n%s", output)
+	}
+}
@@ -0,0 +1,41 @@
+package importmap
+
+// CanonicalForm implements the IImportMap interface
+func (i *importMap) CanonicalForm() Data {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	data := Data{
+		Imports:   i.canonicalSpecifierMap(i.imports),
+		Scopes:    make(Scopes, len(i.scopes)),
+		Integrity: make(Integrity, len(i.integrity)),
+	}
+
+	for scopeKey, scope := range i.scopes {
+		data.Scopes[i.canonicalUrl(scopeKey)] = i.canonicalSpecifierMap(scope)
+	}
+	for target, integrity := range i.integrity {
+		data.Integrity[i.canonicalUrl(target)] = integrity
+	}
+
+	return data
+}
+
+func (i *importMap) canonicalSpecifierMap(specifierMap map[string]string) map[string]string {
+	result := make(map[string]string, len(specifierMap))
+	for key, target := range specifierMap {
+		if !isPlain(key) {
+			key = i.canonicalUrl(key)
+		}
+		result[key] = i.canonicalUrl(target)
+	}
+	return result
+}
+
+// canonicalUrl resolves u against the map URL, leaving it as is when it can't be resolved.
+func (i *importMap) canonicalUrl(u string) string {
+	resolved, err := resolve(u, i.mapUrl, i.rootUrl)
+	if err != nil {
+		return u
+	}
+	return resolved
+}
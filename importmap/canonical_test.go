@@ -0,0 +1,83 @@
+package importmap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func newRoundTripMap(t *testing.T, mapUrl *url.URL) IImportMap {
+	t.Helper()
+
+	m, err := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"react":                     "https://esm.sh/react@18",
+			"app/":                      "./app/",
+			"/lib/old.js":               "/lib/new.js",
+			"https://cdn.com/legacy.js": "../legacy.js",
+		},
+		Scopes: Scopes{
+			"./vendor/":        {"react": "/react-17.js"},
+			"https://cdn.com/": {"dep": "https://cdn.com/dep@2.js"},
+		},
+		Integrity: Integrity{
+			"/react-17.js":          "sha384-abc",
+			"https://esm.sh/x.js":   "sha384-def",
+			"./app/entry.js":        "sha384-ghi",
+			"https://cdn.com/d.js?": "sha384-jkl",
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	for _, rawUrl := range []string{"https://site.com/maps/", "file:///srv/app/"} {
+		mapUrl, _ := url.Parse(rawUrl)
+		m := newRoundTripMap(t, mapUrl)
+
+		serialized, err := Serialize(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Parse(serialized, WithMapUrl(mapUrl))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(m.CanonicalForm(), loaded.CanonicalForm()) {
+			t.Errorf("%s: round trip changed the map\nbefore: %v\nafter:  %v", rawUrl, m.CanonicalForm(), loaded.CanonicalForm())
+		}
+
+		reserialized, err := Serialize(loaded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(serialized) != string(reserialized) {
+			t.Errorf("%s: serialization is not stable\n%s\n%s", rawUrl, serialized, reserialized)
+		}
+	}
+}
+
+func TestRebaseRoundTrip(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/maps/")
+	otherUrl, _ := url.Parse("https://site.com/other/nested/")
+	m := newRoundTripMap(t, mapUrl)
+	expected := m.CanonicalForm()
+
+	if err := m.Rebase(otherUrl, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rebase(mapUrl, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(expected, m.CanonicalForm()) {
+		t.Errorf("rebase changed the map\nbefore: %v\nafter:  %v", expected, m.CanonicalForm())
+	}
+	if m.GetIntegrity()["/react-17.js"] != "sha384-abc" {
+		t.Errorf("integrity values must not be rewritten, got %v", m.GetIntegrity())
+	}
+}
@@ -0,0 +1,77 @@
+package importmap
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentUse resolves while other goroutines modify the map and its shallow clone, run it
+// with -race.
+func TestConcurrentUse(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, err := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{"react": "/react.js", "lib/": "/lib/"},
+		Scopes:  Scopes{"/legacy/": {"react": "/react-17.js"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := m.ShallowClone()
+	parent, _ := url.Parse("https://site.com/legacy/app.js")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := m.ResolveWithParent("react", parent); err != nil {
+					t.Error(err)
+					return
+				}
+				_, _ = m.Resolve("lib/a.js")
+				_, _, _ = clone.ResolveTrace("https://site.com/lib/b.js", parent)
+				_ = m.CanonicalForm()
+				_, _ = m.GetIntegrityValue("/react.js", "")
+				_ = m.Clone()
+				// the sections are iterated while the writers modify the map
+				for range m.GetImports() {
+				}
+				for _, scope := range clone.GetScopes() {
+					for range scope {
+					}
+				}
+				for range m.GetIntegrity() {
+				}
+			}
+		}()
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("pkg-%d-%d", n, j)
+				clone.Set(key, "/"+key+".js")
+				m.SetWithParent(key, "/legacy/"+key+".js", "/legacy/")
+				_ = m.SetIntegrityValue("/"+key+".js", emptySha384)
+				if j%10 == 0 {
+					other, _ := New(WithMapUrl(mapUrl), WithMap(Data{Imports: Imports{key + "-extra": "/extra.js"}}))
+					if _, err := m.Extend(other, false); err != nil {
+						t.Error(err)
+						return
+					}
+					if err := clone.Rebase(mapUrl, nil); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	// a copy taken once the writers are done holds all their entries
+	if scope := m.GetScopes()["/legacy/"]; len(scope) != 1+8*100 {
+		t.Errorf("expected every entry set concurrently, got %d entries", len(scope))
+	}
+}
@@ -0,0 +1,27 @@
+package importmap
+
+// ForConditions returns the map of a runtime matching conditions, like "deno" and "browser": d
+// with the overrides of d.Conditions for each of them, the first conditions taking precedence,
+// and without the conditions.
+func (d Data) ForConditions(conditions ...string) Data {
+	if d.Conditions == nil {
+		return d
+	}
+	overrides := make([]Data, 0, len(conditions))
+	for i := len(conditions) - 1; i >= 0; i-- {
+		if data, ok := d.Conditions[conditions[i]]; ok {
+			overrides = append(overrides, data)
+		}
+	}
+	result := d.override(overrides...)
+	result.Environments = d.Environments
+	return result
+}
+
+// WithConditions selects the overrides of Data.Conditions applied to the map, the first
+// conditions taking precedence, e.g. "node" when bundling for Node.js.
+func WithConditions(conditions ...string) Option {
+	return func(options *Options) {
+		options.Conditions = conditions
+	}
+}
@@ -0,0 +1,41 @@
+package importmap
+
+import (
+	"testing"
+)
+
+func TestWithConditions(t *testing.T) {
+	contents := []byte(`{
+		"imports": {"fs": "/shims/fs.js", "fetch": "/shims/fetch.js"},
+		"conditions": {
+			"node": {"imports": {"fs": "node:fs", "fetch": "node:undici"}},
+			"deno": {"imports": {"fs": "jsr:@std/fs"}}
+		},
+		"environments": {
+			"production": {"imports": {"fetch": "/shims/fetch.min.js"}}
+		}
+	}`)
+
+	cases := []struct {
+		conditions  []string
+		environment string
+		expected    Imports
+	}{
+		{nil, "", Imports{"fs": "/shims/fs.js", "fetch": "/shims/fetch.js"}},
+		{[]string{"node"}, "", Imports{"fs": "node:fs", "fetch": "node:undici"}},
+		{[]string{"deno", "node"}, "", Imports{"fs": "jsr:@std/fs", "fetch": "node:undici"}},
+		{[]string{"browser"}, "production", Imports{"fs": "/shims/fs.js", "fetch": "/shims/fetch.min.js"}},
+		{[]string{"node"}, "production", Imports{"fs": "node:fs", "fetch": "/shims/fetch.min.js"}},
+	}
+	for _, c := range cases {
+		m, err := Parse(contents, WithConditions(c.conditions...), WithEnvironment(c.environment))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for specifier, expected := range c.expected {
+			if target := m.GetImports()[specifier]; target != expected {
+				t.Errorf("expected %s for %s with %v in %q, got %s", expected, specifier, c.conditions, c.environment, target)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package importmap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Conflict is an entry mapped to different targets, or integrity values, by two import maps
+type Conflict struct {
+	// Scope is the key of the scope holding the entry, empty for top-level imports and integrity
+	Scope string
+	Key   string
+	// Integrity is set for conflicting integrity values of the target Key
+	Integrity bool
+	Existing  string
+	Incoming  string
+}
+
+func (c Conflict) String() string {
+	switch {
+	case c.Integrity:
+		return fmt.Sprintf("integrity of %q: %q != %q", c.Key, c.Existing, c.Incoming)
+	case c.Scope != "":
+		return fmt.Sprintf("%q in scope %q: %q != %q", c.Key, c.Scope, c.Existing, c.Incoming)
+	}
+	return fmt.Sprintf("%q: %q != %q", c.Key, c.Existing, c.Incoming)
+}
+
+// ConflictError is returned by ExtendStrict when the maps disagree on some entries
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		conflicts = append(conflicts, c.String())
+	}
+	return "conflicting import map entries: " + strings.Join(conflicts, "; ")
+}
+
+// ExtendStrict is an implementation of the IImportMap interface.
+func (i *importMap) ExtendStrict(importMap IImportMap) (IImportMap, error) {
+	if conflicts := conflicts(Diff(i, importMap)); len(conflicts) > 0 {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+	return i.Extend(importMap, false)
+}
+
+// conflicts returns the changed entries of delta, sorted by scope and key.
+func conflicts(delta Delta) []Conflict {
+	var result []Conflict
+	for key, change := range delta.Imports.Changed {
+		result = append(result, Conflict{Key: key, Existing: change.From, Incoming: change.To})
+	}
+	for scopeKey, scope := range delta.Scopes {
+		for key, change := range scope.Changed {
+			result = append(result, Conflict{Scope: scopeKey, Key: key, Existing: change.From, Incoming: change.To})
+		}
+	}
+	for key, change := range delta.Integrity.Changed {
+		result = append(result, Conflict{Key: key, Integrity: true, Existing: change.From, Incoming: change.To})
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Integrity != result[b].Integrity {
+			return !result[a].Integrity
+		}
+		if result[a].Scope != result[b].Scope {
+			return result[a].Scope < result[b].Scope
+		}
+		return result[a].Key < result[b].Key
+	})
+	return result
+}
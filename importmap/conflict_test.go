@@ -0,0 +1,46 @@
+package importmap
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestExtendStrict(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	team, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{"react": "https://esm.sh/react@18", "shared": "/shared.js"},
+		Scopes:  Scopes{"/admin/": {"ui": "/ui@1.js"}},
+	}))
+
+	compatible, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{"shared": "./shared.js", "lodash": "https://esm.sh/lodash@4"},
+	}))
+	extended, err := team.Clone().ExtendStrict(compatible)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUrlsEquals(extended, "lodash", "https://site.com/index.js", "https://esm.sh/lodash@4", t)
+
+	conflicting, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{"react": "https://esm.sh/react@17"},
+		Scopes:  Scopes{"/admin/": {"ui": "/ui@2.js"}},
+	}))
+	_, err = team.ExtendStrict(conflicting)
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+	expected := []Conflict{
+		{Key: "react", Existing: "https://esm.sh/react@18", Incoming: "https://esm.sh/react@17"},
+		{Scope: "https://site.com/admin/", Key: "ui", Existing: "https://site.com/ui@1.js", Incoming: "https://site.com/ui@2.js"},
+	}
+	if len(conflictErr.Conflicts) != len(expected) || conflictErr.Conflicts[0] != expected[0] || conflictErr.Conflicts[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, conflictErr.Conflicts)
+	}
+	assertUrlsEquals(team, "react", "https://site.com/index.js", "https://esm.sh/react@18", t)
+
+	if _, err = team.Freeze().ExtendStrict(compatible); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package importmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// denoConfig holds the import map fields of a deno.json file
+type denoConfig struct {
+	Imports   Imports `json:"imports"`
+	Scopes    Scopes  `json:"scopes"`
+	ImportMap string  `json:"importMap"`
+}
+
+// LoadFromDenoConfig loads the import map of a deno.json or deno.jsonc file: its imports and
+// scopes keys, or the file its importMap key points at. Relative targets are resolved against
+// the file, as Deno does, unless a map URL is given in opts.
+func LoadFromDenoConfig(path string, opts ...Option) (IImportMap, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	fileUrl := FileURL(absPath)
+
+	m, err := ParseDenoConfig(contents, append([]Option{WithMapUrl(fileUrl)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseDenoConfig parses the import map of the contents of a deno.json or deno.jsonc file.
+// Comments and trailing commas are allowed. A config whose importMap key points at a separate
+// file is loaded from that file when it is a local path, relative to the map URL.
+func ParseDenoConfig(contents []byte, opts ...Option) (IImportMap, error) {
+	contents, err := decodeText(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	config := denoConfig{}
+	if err = json.Unmarshal(stripJSONC(contents), &config); err != nil {
+		return nil, err
+	}
+
+	if config.ImportMap != "" && config.Imports == nil && config.Scopes == nil {
+		options := &Options{}
+		for _, opt := range opts {
+			opt(options)
+		}
+		mapUrl := options.MapUrl
+		if mapUrl == nil {
+			if mapUrl, err = defaultMapUrl(); err != nil {
+				return nil, err
+			}
+		}
+		ref, err := url.Parse(config.ImportMap)
+		if err != nil {
+			return nil, err
+		}
+		target := mapUrl.ResolveReference(ref)
+		if target.Scheme != "file" {
+			return nil, fmt.Errorf("the import map %s isn't a local file", target)
+		}
+		mapPath, err := FilePath(target)
+		if err != nil {
+			return nil, err
+		}
+		mapContents, err := os.ReadFile(mapPath)
+		if err != nil {
+			return nil, err
+		}
+		return Parse(mapContents, append(opts, WithMapUrl(target))...)
+	}
+
+	return New(append(opts, WithMap(Data{Imports: config.Imports, Scopes: config.Scopes}))...)
+}
+
+// stripJSONC removes the comments and trailing commas of JSON with comments, leaving strings
+// untouched.
+func stripJSONC(contents []byte) []byte {
+	result := make([]byte, 0, len(contents))
+	for i := 0; i < len(contents); i++ {
+		c := contents[i]
+		switch {
+		case c == '"':
+			start := i
+			for i++; i < len(contents) && contents[i] != '"'; i++ {
+				if contents[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(contents) {
+				i = len(contents) - 1
+			}
+			result = append(result, contents[start:i+1]...)
+		case c == '/' && i+1 < len(contents) && contents[i+1] == '/':
+			for i < len(contents) && contents[i] != '\n' {
+				i++
+			}
+			if i < len(contents) {
+				result = append(result, '\n')
+			}
+		case c == '/' && i+1 < len(contents) && contents[i+1] == '*':
+			i += 2
+			for i+1 < len(contents) && !(contents[i] == '*' && contents[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == ']' || c == '}':
+			// drop the comma preceding the closing bracket, if any
+			j := len(result) - 1
+			for j >= 0 && isJSONSpace(result[j]) {
+				j--
+			}
+			if j >= 0 && result[j] == ',' {
+				result = append(result[:j], result[j+1:]...)
+			}
+			result = append(result, c)
+		default:
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,69 @@
+package importmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFromDenoConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deno.jsonc")
+	contents := `{
+		// the tasks are ignored
+		"tasks": {"dev": "deno run --watch main.ts"},
+		"imports": {
+			"react": "https://esm.sh/react@18.2.0", /* pinned */
+			"@/": "./src/",
+		},
+		"scopes": {
+			"./legacy/": {"react": "https://esm.sh/react@17.0.2"},
+		},
+		"description": "not // a comment",
+	}`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadFromDenoConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := m.Resolve("@/app.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "file://"+filepath.ToSlash(dir)+"/src/app.ts" {
+		t.Errorf("expected the target to be relative to the config file, got %s", resolved)
+	}
+	if m.GetScopes()["./legacy/"]["react"] != "https://esm.sh/react@17.0.2" {
+		t.Errorf("expected the scopes of the config, got %v", m.GetScopes())
+	}
+}
+
+func TestLoadFromDenoConfigImportMapKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "maps", "import_map.json"), []byte(`{"imports": {"lib": "./lib.js"}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "deno.json")
+	if err = os.WriteFile(path, []byte(`{"importMap": "./maps/import_map.json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadFromDenoConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := m.Resolve("lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "file://"+filepath.ToSlash(dir)+"/maps/lib.js" {
+		t.Errorf("expected the target to be relative to the import map file, got %s", resolved)
+	}
+}
@@ -0,0 +1,71 @@
+package importmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnostic describes an import map entry that was ignored or is otherwise problematic
+type Diagnostic struct {
+	// Scope is the scope key of the entry, empty for top-level imports
+	Scope string
+	// Key is the specifier key of the entry
+	Key string
+	// Message describes the problem
+	Message string
+	// WholeScope is set when the problem concerns the scope itself rather than one of its entries
+	WholeScope bool
+}
+
+// String formats the diagnostic the way browsers report import map warnings
+func (d Diagnostic) String() string {
+	if d.WholeScope {
+		return fmt.Sprintf("ignored import map scope %q: %s", d.Scope, d.Message)
+	}
+	if d.Scope == "" {
+		return fmt.Sprintf("ignored import map entry %q: %s", d.Key, d.Message)
+	}
+	return fmt.Sprintf("ignored import map entry %q in scope %q: %s", d.Key, d.Scope, d.Message)
+}
+
+// InvalidMapError is returned when a map with invalid entries is loaded in ValidationError mode,
+// and by the resolutions of a map Set or SetWithParent left invalid entries out of in that mode
+type InvalidMapError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *InvalidMapError) Error() string {
+	messages := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		messages = append(messages, d.String())
+	}
+	return "invalid import map: " + strings.Join(messages, "; ")
+}
+
+// checkEntry returns why the key -> target mapping cannot be used, or an empty string if it can.
+func checkEntry(key string, target string) string {
+	if key == "" {
+		return "specifier keys must not be empty"
+	}
+	if strings.Contains(key, `\`) {
+		return "specifier keys must not contain backslashes"
+	}
+	if strings.HasSuffix(key, "/") && !strings.HasSuffix(target, "/") {
+		return fmt.Sprintf("the target %q must end with \"/\" because its key does", target)
+	}
+	return ""
+}
+
+// filterSpecifierMap returns a copy of specifierMap without the entries that cannot be matched,
+// recording a diagnostic for each dropped entry.
+func (i *importMap) filterSpecifierMap(scope string, specifierMap map[string]string) map[string]string {
+	result := make(map[string]string, len(specifierMap))
+	for key, target := range specifierMap {
+		if msg := checkEntry(key, target); msg != "" {
+			i.diagnostics = append(i.diagnostics, Diagnostic{Scope: scope, Key: key, Message: msg})
+			continue
+		}
+		result[key] = target
+	}
+	return result
+}
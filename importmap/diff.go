@@ -0,0 +1,110 @@
+package importmap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change is an entry whose value differs between two import maps
+type Change struct {
+	From string
+	To   string
+}
+
+// SpecifierMapDelta holds the differences between two specifier maps, or two integrity maps
+type SpecifierMapDelta struct {
+	Added   map[string]string
+	Removed map[string]string
+	Changed map[string]Change
+}
+
+// Empty reports whether the maps are the same
+func (d SpecifierMapDelta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Delta holds the differences between two import maps
+type Delta struct {
+	Imports SpecifierMapDelta
+	// Scopes holds the scopes with differences, the entries of added and removed scopes being
+	// reported as added and removed
+	Scopes    map[string]SpecifierMapDelta
+	Integrity SpecifierMapDelta
+}
+
+// Empty reports whether the import maps are the same
+func (d Delta) Empty() bool {
+	return d.Imports.Empty() && len(d.Scopes) == 0 && d.Integrity.Empty()
+}
+
+// String lists the differences one per line, "+" marking added entries, "-" removed entries
+// and "~" changed entries
+func (d Delta) String() string {
+	var b strings.Builder
+	write := func(section string, delta SpecifierMapDelta) {
+		for _, key := range sortedKeys(delta.Added) {
+			fmt.Fprintf(&b, "+ %s%q: %q\n", section, key, delta.Added[key])
+		}
+		for _, key := range sortedKeys(delta.Removed) {
+			fmt.Fprintf(&b, "- %s%q: %q\n", section, key, delta.Removed[key])
+		}
+		for _, key := range sortedKeys(delta.Changed) {
+			fmt.Fprintf(&b, "~ %s%q: %q -> %q\n", section, key, delta.Changed[key].From, delta.Changed[key].To)
+		}
+	}
+
+	write("imports ", d.Imports)
+	for _, scopeKey := range sortedKeys(d.Scopes) {
+		write(fmt.Sprintf("scopes %q ", scopeKey), d.Scopes[scopeKey])
+	}
+	write("integrity ", d.Integrity)
+	return b.String()
+}
+
+// Diff returns the differences from a to b. The maps are compared in their canonical form,
+// so entries written relative in one map and absolute in the other are the same.
+func Diff(a IImportMap, b IImportMap) Delta {
+	canonicalA, canonicalB := a.CanonicalForm(), b.CanonicalForm()
+
+	delta := Delta{
+		Imports:   diffSpecifierMaps(canonicalA.Imports, canonicalB.Imports),
+		Scopes:    make(map[string]SpecifierMapDelta),
+		Integrity: diffSpecifierMaps(canonicalA.Integrity, canonicalB.Integrity),
+	}
+	for scopeKey, scope := range canonicalA.Scopes {
+		if scopeDelta := diffSpecifierMaps(scope, canonicalB.Scopes[scopeKey]); !scopeDelta.Empty() {
+			delta.Scopes[scopeKey] = scopeDelta
+		}
+	}
+	for scopeKey, scope := range canonicalB.Scopes {
+		if _, ok := canonicalA.Scopes[scopeKey]; !ok {
+			if scopeDelta := diffSpecifierMaps(nil, scope); !scopeDelta.Empty() {
+				delta.Scopes[scopeKey] = scopeDelta
+			}
+		}
+	}
+	return delta
+}
+
+func diffSpecifierMaps[M ~map[string]string](a M, b M) SpecifierMapDelta {
+	delta := SpecifierMapDelta{
+		Added:   make(map[string]string),
+		Removed: make(map[string]string),
+		Changed: make(map[string]Change),
+	}
+	for key, from := range a {
+		to, ok := b[key]
+		switch {
+		case !ok:
+			delta.Removed[key] = from
+		case to != from:
+			delta.Changed[key] = Change{From: from, To: to}
+		}
+	}
+	for key, to := range b {
+		if _, ok := a[key]; !ok {
+			delta.Added[key] = to
+		}
+	}
+	return delta
+}
@@ -0,0 +1,48 @@
+package importmap
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	a, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"same":    "./same.js",
+			"removed": "/removed.js",
+			"changed": "https://esm.sh/changed@1",
+		},
+		Scopes: Scopes{
+			"/old/": {"a": "/a.js"},
+			"/x/":   {"a": "/a.js"},
+		},
+		Integrity: Integrity{"https://esm.sh/changed@1": emptySha384},
+	}))
+	b, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"same":    "https://site.com/same.js",
+			"added":   "/added.js",
+			"changed": "https://esm.sh/changed@2",
+		},
+		Scopes: Scopes{
+			"/new/": {"a": "/a.js"},
+			"/x/":   {"a": "/a.js"},
+		},
+	}))
+
+	if !Diff(a, a).Empty() {
+		t.Error("expected no differences between a map and itself")
+	}
+
+	expected := `+ imports "added": "https://site.com/added.js"
+- imports "removed": "https://site.com/removed.js"
+~ imports "changed": "https://esm.sh/changed@1" -> "https://esm.sh/changed@2"
++ scopes "https://site.com/new/" "a": "https://site.com/a.js"
+- scopes "https://site.com/old/" "a": "https://site.com/a.js"
+- integrity "https://esm.sh/changed@1": "` + emptySha384 + `"
+`
+	if actual := Diff(a, b).String(); actual != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,50 @@
+package importmap
+
+// ForEnvironment returns the map of an environment: d with the imports, scopes and integrity of
+// d.Environments[environment] overriding its own, and without the environments. Environments
+// without overrides get the base map.
+func (d Data) ForEnvironment(environment string) Data {
+	if d.Environments == nil {
+		return d
+	}
+	result := d.override(d.Environments[environment])
+	result.Conditions = d.Conditions
+	return result
+}
+
+// override returns a copy of the imports, scopes and integrity of d, each of overrides replacing
+// the entries of the previous ones.
+func (d Data) override(overrides ...Data) Data {
+	result := Data{
+		Imports:   make(Imports, len(d.Imports)),
+		Scopes:    make(Scopes, len(d.Scopes)),
+		Integrity: make(Integrity, len(d.Integrity)),
+	}
+	for _, data := range append([]Data{d}, overrides...) {
+		for specifier, target := range data.Imports {
+			result.Imports[specifier] = target
+		}
+		for scopeKey, scope := range data.Scopes {
+			merged, ok := result.Scopes[scopeKey]
+			if !ok {
+				merged = make(map[string]string, len(scope))
+				result.Scopes[scopeKey] = merged
+			}
+			for specifier, target := range scope {
+				merged[specifier] = target
+			}
+		}
+		for target, integrity := range data.Integrity {
+			result.Integrity[target] = integrity
+		}
+	}
+	return result
+}
+
+// WithEnvironment selects the overrides of Data.Environments applied to the map, e.g. mapping
+// react to its minified build in "production". Without it only the base map is used.
+func WithEnvironment(environment string) Option {
+	return func(options *Options) {
+		options.Environment = environment
+	}
+}
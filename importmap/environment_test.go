@@ -0,0 +1,55 @@
+package importmap
+
+import (
+	"testing"
+)
+
+func TestWithEnvironment(t *testing.T) {
+	m, err := Parse([]byte(`{
+		"imports": {
+			"react": "https://cdn.invalid/react.development.js",
+			"app": "/app.js"
+		},
+		"scopes": {
+			"/legacy/": {"react": "https://cdn.invalid/react-17.development.js", "dom": "/dom.js"}
+		},
+		"environments": {
+			"production": {
+				"imports": {"react": "https://cdn.invalid/react.production.min.js"},
+				"scopes": {"/legacy/": {"react": "https://cdn.invalid/react-17.production.min.js"}},
+				"integrity": {"https://cdn.invalid/react.production.min.js": "sha384-abc"}
+			}
+		}
+	}`), WithEnvironment("production"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"react": "https://cdn.invalid/react.production.min.js",
+		"app":   "/app.js",
+	}
+	for specifier, expected := range cases {
+		if target := m.GetImports()[specifier]; target != expected {
+			t.Errorf("expected %s for %s, got %s", expected, specifier, target)
+		}
+	}
+	scope := m.GetScopes()["/legacy/"]
+	if scope["react"] != "https://cdn.invalid/react-17.production.min.js" || scope["dom"] != "/dom.js" {
+		t.Errorf("expected the scope overrides merged into the scope, got %v", scope)
+	}
+	if m.GetIntegrity()["https://cdn.invalid/react.production.min.js"] != "sha384-abc" {
+		t.Errorf("expected the integrity of the environment, got %v", m.GetIntegrity())
+	}
+
+	data := Data{
+		Imports:      Imports{"react": "./react.development.js"},
+		Environments: map[string]Data{"production": {Imports: Imports{"react": "./react.min.js"}}},
+	}
+	if base := data.ForEnvironment("test"); base.Imports["react"] != "./react.development.js" || base.Environments != nil {
+		t.Errorf("expected the base map for an environment without overrides, got %+v", base)
+	}
+	if data.ForEnvironment("production").Imports["react"] != "./react.min.js" || data.Imports["react"] != "./react.development.js" {
+		t.Error("expected the overrides applied to a copy of the map")
+	}
+}
@@ -0,0 +1,81 @@
+package importmap
+
+import (
+	"fmt"
+	"net/url"
+	"runtime"
+	"strings"
+)
+
+// FileURL returns the file URL of an absolute file system path. On Windows drive paths like
+// C:\app\a.js become file:///C:/app/a.js and UNC paths like \\server\share\a.js become
+// file://server/share/a.js.
+func FileURL(path string) *url.URL {
+	return fileURL(path, runtime.GOOS == "windows")
+}
+
+// FilePath returns the file system path of a file URL, leaving out its query and fragment. On
+// Windows file:///C:/app/a.js becomes C:\app\a.js and file://server/share/a.js becomes
+// \\server\share\a.js; elsewhere file URLs with a host other than localhost are rejected.
+func FilePath(u *url.URL) (string, error) {
+	return filePath(u, runtime.GOOS == "windows")
+}
+
+// DirURL returns the file URL of a directory, with the trailing slash relative URLs need to
+// resolve inside of it.
+func DirURL(dir string) *url.URL {
+	u := FileURL(dir)
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u
+}
+
+// fileURL implements FileURL, with the path rules of Windows when windows is set.
+func fileURL(path string, windows bool) *url.URL {
+	if !windows {
+		return &url.URL{Scheme: "file", Path: path}
+	}
+
+	slashed := strings.ReplaceAll(path, `\`, "/")
+	if strings.HasPrefix(slashed, "//") {
+		host, rest, _ := strings.Cut(slashed[2:], "/")
+		return &url.URL{Scheme: "file", Host: host, Path: "/" + rest}
+	}
+	if !strings.HasPrefix(slashed, "/") {
+		// drive paths like C:/app get the leading slash of file:///C:/app
+		slashed = "/" + slashed
+	}
+	return &url.URL{Scheme: "file", Path: slashed}
+}
+
+// filePath implements FilePath, with the path rules of Windows when windows is set.
+func filePath(u *url.URL, windows bool) (string, error) {
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("%s isn't a file URL", u)
+	}
+	host := u.Host
+	if strings.EqualFold(host, "localhost") {
+		host = ""
+	}
+	if !windows {
+		if host != "" {
+			return "", fmt.Errorf("the file URL %s points to the host %s", u, host)
+		}
+		return u.Path, nil
+	}
+
+	path := u.Path
+	if host != "" {
+		return `\\` + host + strings.ReplaceAll(path, "/", `\`), nil
+	}
+	// the drive letter follows the leading slash, written C: or C| in legacy URLs
+	if len(path) >= 3 && path[0] == '/' && isDriveLetter(path[1]) && (path[2] == ':' || path[2] == '|') {
+		path = path[1:2] + ":" + path[3:]
+	}
+	return strings.ReplaceAll(path, "/", `\`), nil
+}
+
+func isDriveLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
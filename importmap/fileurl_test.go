@@ -0,0 +1,116 @@
+package importmap
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestFileURL(t *testing.T) {
+	cases := []struct {
+		path    string
+		windows bool
+		url     string
+	}{
+		{"/app/src/a.js", false, "file:///app/src/a.js"},
+		{"/app/my dir/a#1.js", false, "file:///app/my%20dir/a%231.js"},
+		{`C:\app\src\a.js`, true, "file:///C:/app/src/a.js"},
+		{`C:\`, true, "file:///C:/"},
+		{`d:\my dir\a.js`, true, "file:///d:/my%20dir/a.js"},
+		{`\\server\share\a.js`, true, "file://server/share/a.js"},
+	}
+	for _, c := range cases {
+		u := fileURL(c.path, c.windows)
+		if u.String() != c.url {
+			t.Errorf("expected %s for %s, got %s", c.url, c.path, u)
+		}
+
+		// the path survives the round trip through the serialized URL
+		parsed, err := url.Parse(u.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, err := filePath(parsed, c.windows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != c.path {
+			t.Errorf("expected %s back from %s, got %s", c.path, u, path)
+		}
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	cases := []struct {
+		url     string
+		windows bool
+		path    string
+	}{
+		{"file:///app/a.js?v=1#top", false, "/app/a.js"},
+		{"file://localhost/app/a.js", false, "/app/a.js"},
+		{"file:///C:/app/a.js", true, `C:\app\a.js`},
+		{"file:///C|/app/a.js", true, `C:\app\a.js`},
+		{"file://localhost/C:/app/a.js", true, `C:\app\a.js`},
+		{"file://server/share/a.js", true, `\\server\share\a.js`},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, err := filePath(u, c.windows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != c.path {
+			t.Errorf("expected %s for %s, got %s", c.path, c.url, path)
+		}
+	}
+
+	for _, rawUrl := range []string{"file://server/share/a.js", "https://example.com/a.js"} {
+		u, _ := url.Parse(rawUrl)
+		if _, err := filePath(u, false); err == nil {
+			t.Errorf("expected %s to be rejected", rawUrl)
+		}
+	}
+}
+
+func TestWindowsMapUrl(t *testing.T) {
+	// maps loaded from a Windows path resolve their targets and scopes next to the file
+	m, err := New(
+		WithMap(Data{
+			Imports: Imports{"app": "./src/app.js"},
+			Scopes:  Scopes{"./vendor/": {"app": "./vendor/app.js"}},
+		}),
+		WithMapUrl(fileURL(`C:\project\importmap.json`, true)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := m.Resolve("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "file:///C:/project/src/app.js" {
+		t.Errorf("expected the target next to the map, got %s", resolved)
+	}
+	resolved, err = m.ResolveWithParent("app", fileURL(`C:\project\vendor\lib.js`, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "file:///C:/project/vendor/app.js" {
+		t.Errorf("expected the scope to contain the Windows parent, got %s", resolved)
+	}
+}
+
+func TestDirURL(t *testing.T) {
+	for dir, expected := range map[string]string{
+		"/app/src":  "file:///app/src/",
+		"/app/src/": "file:///app/src/",
+		"/":         "file:///",
+	} {
+		if u := DirURL(dir); u.String() != expected {
+			t.Errorf("expected %s for %s, got %s", expected, dir, u)
+		}
+	}
+}
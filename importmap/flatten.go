@@ -0,0 +1,142 @@
+package importmap
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Flatten is an implementation of the IImportMap interface.
+//
+// Mappings are only hoisted to the grouped scope when they don't conflict with a mapping of the
+// grouped scope, of a scope between the two or with a top-level import of the same specifier, so
+// the scopes left in place are the ones which genuinely diverge.
+func (i *importMap) Flatten() IImportMap {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.indexes.reset()
+	scopeKeys := make([]string, 0, len(i.scopes))
+	for scopeKey := range i.scopes {
+		scopeKeys = append(scopeKeys, scopeKey)
+	}
+	sort.Strings(scopeKeys)
+
+	// local scopes are grouped under their common baseline, remote ones under their origin
+	localBase := ""
+	for _, scopeKey := range scopeKeys {
+		if scopeUrl := i.canonicalUrl(scopeKey); i.isLocalUrl(scopeUrl) {
+			localBase = commonBase(localBase, scopeUrl)
+		}
+	}
+
+	for _, scopeKey := range scopeKeys {
+		scopeUrl := i.canonicalUrl(scopeKey)
+		base := localBase
+		if !i.isLocalUrl(scopeUrl) {
+			base = originBase(scopeUrl)
+		}
+		if base == "" || normalizeUrlString(base) == normalizeUrlString(scopeUrl) {
+			continue
+		}
+
+		baseKey := i.scopeKeyFor(base, scopeKey)
+		scope := i.scopes[scopeKey]
+		names := make([]string, 0, len(scope))
+		for name := range scope {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			target := i.canonicalUrl(scope[name])
+			if existing, ok := i.scopes[baseKey][name]; ok && i.canonicalUrl(existing) != target {
+				continue
+			}
+			if existing, ok := i.imports[name]; ok && i.canonicalUrl(existing) != target {
+				continue
+			}
+			if i.divergesBetween(base, scopeUrl, name, target) {
+				continue
+			}
+			if i.scopes[baseKey] == nil {
+				i.scopes[baseKey] = make(Scope)
+			}
+			i.scopes[baseKey][name] = scope[name]
+			delete(scope, name)
+		}
+
+		if len(scope) == 0 {
+			delete(i.scopes, scopeKey)
+		}
+	}
+
+	return i
+}
+
+// divergesBetween reports whether a scope more specific than base and less specific than scopeUrl
+// maps name to another target than target, the mapping then not being hoistable past it.
+func (i *importMap) divergesBetween(base string, scopeUrl string, name string, target string) bool {
+	for scopeKey, scope := range i.scopes {
+		existing, ok := scope[name]
+		if !ok {
+			continue
+		}
+		between := i.canonicalUrl(scopeKey)
+		if !strings.HasSuffix(between, "/") || len(between) <= len(base) || len(between) >= len(scopeUrl) {
+			continue
+		}
+		if strings.HasPrefix(between, base) && strings.HasPrefix(scopeUrl, between) && i.canonicalUrl(existing) != target {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalUrl reports whether u is on the origin of the map URL.
+func (i *importMap) isLocalUrl(u string) bool {
+	if strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") {
+		return true
+	}
+	parsed, err := url.Parse(u)
+	return err == nil && sameOrigin(parsed, i.mapUrl)
+}
+
+// scopeKeyFor returns the key of the scope resolving to base, or base itself if there's none,
+// written root-relative like the key of the scope being grouped.
+func (i *importMap) scopeKeyFor(base string, like string) string {
+	for scopeKey := range i.scopes {
+		if normalizeUrlString(i.canonicalUrl(scopeKey)) == normalizeUrlString(base) {
+			return scopeKey
+		}
+	}
+	if strings.HasPrefix(like, "/") && !strings.HasPrefix(like, "//") {
+		if parsed, err := url.Parse(base); err == nil && (parsed.Host == "" || sameOrigin(parsed, i.mapUrl)) {
+			return parsed.Path
+		}
+	}
+	return base
+}
+
+// originBase returns the root of the origin of an absolute URL, e.g. "https://site.com/".
+func originBase(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/"
+}
+
+// commonBase returns the longest common prefix of a and b ending with "/", a being empty at first.
+func commonBase(a string, b string) string {
+	if slash := strings.LastIndex(b, "/"); slash >= 0 {
+		b = b[:slash+1]
+	}
+	if a == "" {
+		return b
+	}
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:strings.LastIndex(a[:n], "/")+1]
+}
@@ -0,0 +1,83 @@
+package importmap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	mapUrl, _ := url.Parse("https://app.com/")
+	m, err := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"react": "https://esm.sh/react@18",
+		},
+		Scopes: Scopes{
+			"https://site.com/x/": {
+				"dep":   "https://site.com/dep@1.js",
+				"react": "https://esm.sh/react@17",
+				"x":     "https://site.com/x.js",
+			},
+			"https://site.com/y/": {
+				"dep": "https://site.com/dep@1.js",
+				"y":   "https://site.com/y.js",
+			},
+			"https://site.com/z/": {
+				"dep": "https://site.com/dep@2.js",
+			},
+			"/pages/a/": {"local": "/a.js"},
+			"/pages/b/": {"local": "/a.js"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flattened := m.Flatten()
+	if flattened != m {
+		t.Fatal("expected Flatten to return the map for chaining")
+	}
+
+	expected := Scopes{
+		"https://site.com/": {
+			"dep": "https://site.com/dep@1.js",
+			"x":   "https://site.com/x.js",
+			"y":   "https://site.com/y.js",
+		},
+		"https://site.com/x/": {
+			"react": "https://esm.sh/react@17",
+		},
+		"https://site.com/z/": {
+			"dep": "https://site.com/dep@2.js",
+		},
+		"/pages/": {"local": "/a.js"},
+	}
+	if !reflect.DeepEqual(m.GetScopes(), expected) {
+		t.Errorf("expected %v, got %v", expected, m.GetScopes())
+	}
+
+	assertUrlsEquals(m, "dep", "https://site.com/y/index.js", "https://site.com/dep@1.js", t)
+	assertUrlsEquals(m, "dep", "https://site.com/z/index.js", "https://site.com/dep@2.js", t)
+	assertUrlsEquals(m, "react", "https://site.com/x/index.js", "https://esm.sh/react@17", t)
+	assertUrlsEquals(m, "react", "https://site.com/y/index.js", "https://esm.sh/react@18", t)
+}
+
+func TestFlattenNestedScopes(t *testing.T) {
+	mapUrl, _ := url.Parse("https://app.com/")
+	m, err := New(WithMapUrl(mapUrl), WithMap(Data{
+		Scopes: Scopes{
+			"/":     {"z": "/v.js"},
+			"/a/":   {"z": "/w.js"},
+			"/a/b/": {"z": "/v.js"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the mapping of /a/b/ isn't hoisted past the diverging one of /a/
+	m.Flatten()
+	assertUrlsEquals(m, "z", "https://app.com/a/b/c.js", "https://app.com/v.js", t)
+	assertUrlsEquals(m, "z", "https://app.com/a/c.js", "https://app.com/w.js", t)
+	assertUrlsEquals(m, "z", "https://app.com/c.js", "https://app.com/v.js", t)
+}
@@ -0,0 +1,192 @@
+package importmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// FormatOptions is the layout of the json written by Format
+type FormatOptions struct {
+	// Indent is the indentation of each level, ignored when Compact is set
+	Indent string
+	// Compact writes the json without any whitespace, e.g. for inlining into HTML
+	Compact bool
+	// Compare orders the keys, code point order when nil
+	Compare func(a, b string) int
+	// TrailingNewline ends the json with a newline, like most formatters do
+	TrailingNewline bool
+}
+
+type FormatOption func(options *FormatOptions)
+
+// WithIndent indents each level of the json with indent, e.g. "\t" or four spaces
+func WithIndent(indent string) FormatOption {
+	return func(options *FormatOptions) {
+		options.Indent = indent
+		options.Compact = false
+	}
+}
+
+// WithCompact writes the json on a single line without whitespace
+func WithCompact() FormatOption {
+	return func(options *FormatOptions) {
+		options.Compact = true
+	}
+}
+
+// WithSortedKeys orders the keys of the imports, scopes and integrity with compare instead of the
+// default code point order, e.g. to match the case-insensitive order of a formatter.
+func WithSortedKeys(compare func(a, b string) int) FormatOption {
+	return func(options *FormatOptions) {
+		options.Compare = compare
+	}
+}
+
+// WithTrailingNewline ends the json with a newline
+func WithTrailingNewline() FormatOption {
+	return func(options *FormatOptions) {
+		options.TrailingNewline = true
+	}
+}
+
+// Format returns data as json, indented with two spaces unless opts say otherwise. Empty
+// imports, scopes, integrity, environments and conditions are left out, and keys are always sorted so the output is stable.
+func Format(data Data, opts ...FormatOption) ([]byte, error) {
+	options := &FormatOptions{Indent: "  "}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return format(data, "", options)
+}
+
+// format writes data as json, prefixing the lines after the first with prefix
+func format(data Data, prefix string, options *FormatOptions) ([]byte, error) {
+	f := &formatter{options: options, prefix: prefix}
+	if err := f.data(data)(0); err != nil {
+		return nil, err
+	}
+	if options.TrailingNewline {
+		f.b.WriteByte('\n')
+	}
+	return f.b.Bytes(), nil
+}
+
+// data returns the function writing an import map
+func (f *formatter) data(data Data) func(depth int) error {
+	var members []member
+	if len(data.Imports) > 0 {
+		members = append(members, member{"imports", f.specifierMap(data.Imports)})
+	}
+	if len(data.Scopes) > 0 {
+		scopes := make(map[string]func(depth int) error, len(data.Scopes))
+		for scope, specifierMap := range data.Scopes {
+			scopes[scope] = f.specifierMap(specifierMap)
+		}
+		members = append(members, member{"scopes", f.object(f.sorted(scopes))})
+	}
+	if len(data.Integrity) > 0 {
+		members = append(members, member{"integrity", f.specifierMap(data.Integrity)})
+	}
+	for _, overrides := range []struct {
+		key  string
+		maps map[string]Data
+	}{{"environments", data.Environments}, {"conditions", data.Conditions}} {
+		if len(overrides.maps) == 0 {
+			continue
+		}
+		writers := make(map[string]func(depth int) error, len(overrides.maps))
+		for name, overrideData := range overrides.maps {
+			writers[name] = f.data(overrideData)
+		}
+		members = append(members, member{overrides.key, f.object(f.sorted(writers))})
+	}
+	return f.object(members)
+}
+
+// member is a key of an object with the function writing its value at a depth
+type member struct {
+	key   string
+	value func(depth int) error
+}
+
+type formatter struct {
+	options *FormatOptions
+	prefix  string
+	b       bytes.Buffer
+}
+
+// sorted returns the members of values in the order of the options
+func (f *formatter) sorted(values map[string]func(depth int) error) []member {
+	members := make([]member, 0, len(values))
+	for key, value := range values {
+		members = append(members, member{key, value})
+	}
+	compare := f.options.Compare
+	if compare == nil {
+		compare = strings.Compare
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if order := compare(members[i].key, members[j].key); order != 0 {
+			return order < 0
+		}
+		return members[i].key < members[j].key
+	})
+	return members
+}
+
+// specifierMap returns the function writing a map of strings
+func (f *formatter) specifierMap(values map[string]string) func(depth int) error {
+	writers := make(map[string]func(depth int) error, len(values))
+	for key, value := range values {
+		writers[key] = f.string(value)
+	}
+	return f.object(f.sorted(writers))
+}
+
+func (f *formatter) string(value string) func(depth int) error {
+	return func(int) error {
+		encoded, err := json.Marshal(value)
+		f.b.Write(encoded)
+		return err
+	}
+}
+
+// object returns the function writing members as an object
+func (f *formatter) object(members []member) func(depth int) error {
+	return func(depth int) error {
+		f.b.WriteByte('{')
+		for i, m := range members {
+			if i > 0 {
+				f.b.WriteByte(',')
+			}
+			f.newline(depth + 1)
+			if err := f.string(m.key)(depth + 1); err != nil {
+				return err
+			}
+			f.b.WriteByte(':')
+			if !f.options.Compact {
+				f.b.WriteByte(' ')
+			}
+			if err := m.value(depth + 1); err != nil {
+				return err
+			}
+		}
+		if len(members) > 0 {
+			f.newline(depth)
+		}
+		f.b.WriteByte('}')
+		return nil
+	}
+}
+
+// newline starts a line indented to depth, unless the json is compact
+func (f *formatter) newline(depth int) {
+	if f.options.Compact {
+		return
+	}
+	f.b.WriteByte('\n')
+	f.b.WriteString(f.prefix)
+	f.b.WriteString(strings.Repeat(f.options.Indent, depth))
+}
@@ -0,0 +1,77 @@
+package importmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	data := Data{
+		Imports: Imports{"b": "./b.js", "a": "./a.js", "C": "./c.js", "</script>": "./x.js"},
+		Scopes:  Scopes{"/legacy/": {"b": "./b-1.js"}},
+	}
+
+	formatted, err := Format(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{
+  "imports": {
+    "\u003c/script\u003e": "./x.js",
+    "C": "./c.js",
+    "a": "./a.js",
+    "b": "./b.js"
+  },
+  "scopes": {
+    "/legacy/": {
+      "b": "./b-1.js"
+    }
+  }
+}`
+	if string(formatted) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, formatted)
+	}
+
+	formatted, err = Format(data, WithIndent("\t"), WithSortedKeys(func(a, b string) int {
+		return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}), WithTrailingNewline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "{\n" +
+		"\t\"imports\": {\n" +
+		"\t\t\"\\u003c/script\\u003e\": \"./x.js\",\n" +
+		"\t\t\"a\": \"./a.js\",\n" +
+		"\t\t\"b\": \"./b.js\",\n" +
+		"\t\t\"C\": \"./c.js\"\n" +
+		"\t},\n" +
+		"\t\"scopes\": {\n" +
+		"\t\t\"/legacy/\": {\n" +
+		"\t\t\t\"b\": \"./b-1.js\"\n" +
+		"\t\t}\n" +
+		"\t}\n" +
+		"}\n"
+	if string(formatted) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, formatted)
+	}
+
+	formatted, err = Format(Data{Imports: Imports{"a": "./a.js"}, Scopes: Scopes{"/s/": {}}}, WithCompact())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(formatted) != `{"imports":{"a":"./a.js"},"scopes":{"/s/":{}}}` {
+		t.Errorf("unexpected compact json %s", formatted)
+	}
+}
+
+func TestInjectHTMLCompact(t *testing.T) {
+	doc := "<head>\n  <script type=\"importmap\">{}</script>\n</head>"
+	injected, err := InjectHTML([]byte(doc), Data{Imports: Imports{"a": "./a.js"}}, WithCompact())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<head>\n  <script type=\"importmap\">{\"imports\":{\"a\":\"./a.js\"}}</script>\n</head>"
+	if string(injected) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, injected)
+	}
+}
@@ -0,0 +1,110 @@
+package importmap
+
+import (
+	"errors"
+	"net/url"
+)
+
+// ErrFrozen is returned when trying to modify a frozen import map
+var ErrFrozen = errors.New("import map is frozen")
+
+// frozenImportMap is a read-only view of an import map. It owns a private copy of the data,
+// so it is safe to share across goroutines.
+type frozenImportMap struct {
+	*importMap
+}
+
+// Freeze implements the IImportMap interface
+func (i *importMap) Freeze() IImportMap {
+	return &frozenImportMap{importMap: deepCopy(i)}
+}
+
+// Thaw implements the IImportMap interface
+func (i *importMap) Thaw() IImportMap {
+	return deepCopy(i)
+}
+
+// Freeze returns the map itself, it is already frozen
+func (f *frozenImportMap) Freeze() IImportMap {
+	return f
+}
+
+// Clone returns the map itself, as it can't be modified there is no need for a copy
+func (f *frozenImportMap) Clone() IImportMap {
+	return f
+}
+
+// ShallowClone returns the map itself, as it can't be modified there is no need for a copy
+func (f *frozenImportMap) ShallowClone() IImportMap {
+	return f
+}
+
+// Rebase fails with ErrFrozen
+func (f *frozenImportMap) Rebase(_ *url.URL, _ *url.URL) error {
+	return ErrFrozen
+}
+
+// Extend fails with ErrFrozen
+func (f *frozenImportMap) Extend(_ IImportMap, _ bool) (IImportMap, error) {
+	return nil, ErrFrozen
+}
+
+// ExtendStrict fails with ErrFrozen
+func (f *frozenImportMap) ExtendStrict(_ IImportMap) (IImportMap, error) {
+	return nil, ErrFrozen
+}
+
+// SetIntegrityValue fails with ErrFrozen
+func (f *frozenImportMap) SetIntegrityValue(_ string, _ string) error {
+	return ErrFrozen
+}
+
+// AddIntegrityFromFiles fails with ErrFrozen
+func (f *frozenImportMap) AddIntegrityFromFiles(_ string, _ string) error {
+	return ErrFrozen
+}
+
+// Set leaves the map untouched, it can't report ErrFrozen because it returns the map for chaining
+func (f *frozenImportMap) Set(_ string, _ string) IImportMap {
+	return f
+}
+
+// SetWithParent leaves the map untouched, it can't report ErrFrozen because it returns the map for chaining
+func (f *frozenImportMap) SetWithParent(_ string, _ string, _ string) IImportMap {
+	return f
+}
+
+// Flatten leaves the map untouched
+func (f *frozenImportMap) Flatten() IImportMap {
+	return f
+}
+
+// CombineSubPaths leaves the map untouched
+func (f *frozenImportMap) CombineSubPaths() IImportMap {
+	return f
+}
+
+// Replace leaves the map untouched
+func (f *frozenImportMap) Replace(_ url.URL, _ url.URL) IImportMap {
+	return f
+}
+
+// GetImports returns a copy of the imports, so callers can't modify the frozen map
+func (f *frozenImportMap) GetImports() Imports {
+	return copyStringMap(f.imports)
+}
+
+// GetScopes returns a copy of the scopes, so callers can't modify the frozen map
+func (f *frozenImportMap) GetScopes() Scopes {
+	return copyScopes(f.scopes)
+}
+
+// GetIntegrity returns a copy of the integrity values, so callers can't modify the frozen map
+func (f *frozenImportMap) GetIntegrity() Integrity {
+	return copyStringMap(f.integrity)
+}
+
+// Diagnostics returns a copy of the diagnostics, so callers can't modify the frozen map
+func (f *frozenImportMap) Diagnostics() []Diagnostic {
+	return append([]Diagnostic(nil), f.diagnostics...)
+}
@@ -0,0 +1,46 @@
+package importmap
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(baseUrl), WithMap(Data{
+		Imports: Imports{"a": "/a.js"},
+	}))
+
+	frozen := m.Freeze()
+	m.Set("b", "/b.js")
+
+	if _, ok := frozen.GetImports()["b"]; ok {
+		t.Error("modifying the original map must not affect the frozen copy")
+	}
+
+	frozen.Set("c", "/c.js")
+	frozen.GetImports()["d"] = "/d.js"
+	if len(frozen.GetImports()) != 1 {
+		t.Errorf("expected the frozen map to be unchanged, got %v", frozen.GetImports())
+	}
+
+	if err := frozen.Rebase(baseUrl, nil); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen, got %v", err)
+	}
+	if _, err := frozen.Extend(m, false); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen, got %v", err)
+	}
+	if err := frozen.SetIntegrityValue("/a.js", "sha384-abc"); !errors.Is(err, ErrFrozen) {
+		t.Errorf("expected ErrFrozen, got %v", err)
+	}
+
+	assertUrlsEqualsU(frozen, "a", baseUrl, "https://site.com/a.js", t)
+
+	thawed := frozen.Thaw()
+	thawed.Set("e", "/e.js")
+	assertUrlsEqualsU(thawed, "e", baseUrl, "https://site.com/e.js", t)
+	if _, ok := frozen.GetImports()["e"]; ok {
+		t.Error("modifying the thawed map must not affect the frozen one")
+	}
+}
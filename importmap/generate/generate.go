@@ -0,0 +1,167 @@
+// Package generate builds import maps for npm packages, either with the JSPM Generator API, which
+// traces the dependencies of the packages and maps them to the URLs of a CDN, or from the packages
+// installed in node_modules, and pins the version ranges of CDN targets to exact versions.
+package generate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"io"
+	"net/http"
+)
+
+// DefaultAPIURL is the endpoint of the hosted JSPM Generator API
+const DefaultAPIURL = "https://api.jspm.io/generate"
+
+// Provider is the CDN the generated map points at
+type Provider = importmap.Provider
+
+const (
+	// ProviderJSPM maps to ga.jspm.io, the default
+	ProviderJSPM = importmap.ProviderJSPM
+	// ProviderESMSH maps to esm.sh
+	ProviderESMSH = importmap.ProviderESMSH
+	// ProviderJSDelivr maps to cdn.jsdelivr.net
+	ProviderJSDelivr = importmap.ProviderJSDelivr
+	// ProviderUnpkg maps to unpkg.com
+	ProviderUnpkg = importmap.ProviderUnpkg
+)
+
+// DefaultEnv are the conditions the packages are traced with when none are given
+var DefaultEnv = []string{"browser", "production", "module"}
+
+// Options is the configuration object of Generate
+type Options struct {
+	// Provider is the CDN the map points at, ProviderJSPM when empty
+	Provider Provider
+	// Env are the conditions the exports of the packages are resolved with, DefaultEnv when nil.
+	// It is the only option FromNodeModules uses.
+	Env []string
+	// InputMap is an existing map the generated mappings are added to
+	InputMap *importmap.Data
+	// APIURL is the endpoint of the generator, DefaultAPIURL when empty
+	APIURL string
+	// RegistryURL is the npm registry Pin looks the versions up in, DefaultRegistryURL when empty
+	RegistryURL string
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+}
+
+type Option func(options *Options)
+
+// WithProvider sets the CDN the generated map points at
+func WithProvider(provider Provider) Option {
+	return func(options *Options) {
+		options.Provider = provider
+	}
+}
+
+// WithEnv sets the conditions the exports of the packages are resolved with, e.g. "development"
+// instead of "production"
+func WithEnv(env ...string) Option {
+	return func(options *Options) {
+		options.Env = env
+	}
+}
+
+// WithInputMap adds the generated mappings to an existing map, keeping its other entries
+func WithInputMap(data importmap.Data) Option {
+	return func(options *Options) {
+		options.InputMap = &data
+	}
+}
+
+// WithAPIURL sets the endpoint of the generator, e.g. a self-hosted one
+func WithAPIURL(apiUrl string) Option {
+	return func(options *Options) {
+		options.APIURL = apiUrl
+	}
+}
+
+// WithRegistryURL sets the npm registry Pin looks the versions up in, e.g. a mirror
+func WithRegistryURL(registryUrl string) Option {
+	return func(options *Options) {
+		options.RegistryURL = registryUrl
+	}
+}
+
+// WithClient sets the HTTP client sending the requests
+func WithClient(client *http.Client) Option {
+	return func(options *Options) {
+		options.Client = client
+	}
+}
+
+// request is the body of a call to the generator
+type request struct {
+	Install  []string        `json:"install"`
+	Env      []string        `json:"env"`
+	Provider Provider        `json:"provider"`
+	InputMap *importmap.Data `json:"inputMap,omitempty"`
+}
+
+// response is the body returned by the generator
+type response struct {
+	Map   importmap.Data `json:"map"`
+	Error string         `json:"error"`
+}
+
+// Generate returns the import map of packages and their dependencies, traced by the JSPM
+// Generator. Packages are npm names with an optional version or range and subpath, e.g. "react",
+// "react-dom@18/client" or "lit@3.1.0".
+func Generate(ctx context.Context, packages []string, opts ...Option) (importmap.IImportMap, error) {
+	options := &Options{
+		Provider: ProviderJSPM,
+		Env:      DefaultEnv,
+		APIURL:   DefaultAPIURL,
+		Client:   http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if len(packages) == 0 {
+		return nil, errors.New("no packages to generate an import map for")
+	}
+
+	body, err := json.Marshal(request{
+		Install:  packages,
+		Env:      options.Env,
+		Provider: options.Provider,
+		InputMap: options.InputMap,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, options.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := options.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the import map: %w", err)
+	}
+	defer resp.Body.Close()
+	contents, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate the import map: %w", err)
+	}
+
+	result := response{}
+	decodeErr := json.Unmarshal(contents, &result)
+	if result.Error != "" {
+		return nil, fmt.Errorf("failed to generate the import map: %s", result.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to generate the import map: %s returned %s", options.APIURL, resp.Status)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to generate the import map: invalid response: %w", decodeErr)
+	}
+	return importmap.New(importmap.WithMap(result.Map))
+}
@@ -0,0 +1,66 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	var received request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(`{
+			"staticDeps": ["https://esm.sh/react@18.2.0"],
+			"dynamicDeps": [],
+			"map": {"imports": {"react": "https://esm.sh/react@18.2.0"}}
+		}`))
+	}))
+	defer server.Close()
+
+	m, err := Generate(context.Background(), []string{"react@18"},
+		WithAPIURL(server.URL),
+		WithProvider(ProviderESMSH),
+		WithEnv("browser", "development", "module"),
+		WithInputMap(importmap.Data{Imports: importmap.Imports{"app": "./app.js"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Provider != ProviderESMSH || len(received.Install) != 1 || received.Install[0] != "react@18" {
+		t.Errorf("unexpected request %+v", received)
+	}
+	if received.Env[1] != "development" || received.InputMap.Imports["app"] != "./app.js" {
+		t.Errorf("expected the environment and input map in the request, got %+v", received)
+	}
+	resolved, err := m.Resolve("react")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "https://esm.sh/react@18.2.0" {
+		t.Errorf("expected react to be mapped, got %s", resolved)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "Unable to resolve npm:not-a-package@latest"}`))
+	}))
+	defer server.Close()
+
+	_, err := Generate(context.Background(), []string{"not-a-package"}, WithAPIURL(server.URL))
+	if err == nil || err.Error() != "failed to generate the import map: Unable to resolve npm:not-a-package@latest" {
+		t.Errorf("expected the error of the generator, got %v", err)
+	}
+
+	if _, err = Generate(context.Background(), nil, WithAPIURL(server.URL)); err == nil {
+		t.Error("expected an error without packages")
+	}
+}
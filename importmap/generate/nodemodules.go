@@ -0,0 +1,323 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// packageJSON holds the fields of a package.json file used to map a package
+type packageJSON struct {
+	Name         string                     `json:"name"`
+	Main         string                     `json:"main"`
+	Module       string                     `json:"module"`
+	Exports      json.RawMessage            `json:"exports"`
+	Imports      map[string]json.RawMessage `json:"imports"`
+	Dependencies map[string]string          `json:"dependencies"`
+}
+
+// member is a member of a JSON object, kept in order since the order of conditions matters
+type member struct {
+	key   string
+	value json.RawMessage
+}
+
+// nodeModulesGenerator maps the installed packages, tracking the packages already mapped
+type nodeModulesGenerator struct {
+	conditions map[string]bool
+	data       importmap.Data
+	visited    map[string]bool
+}
+
+func newNodeModulesGenerator(options *Options) *nodeModulesGenerator {
+	g := &nodeModulesGenerator{
+		conditions: map[string]bool{"import": true, "default": true},
+		data: importmap.Data{
+			Imports: make(importmap.Imports),
+			Scopes:  make(importmap.Scopes),
+		},
+		visited: make(map[string]bool),
+	}
+	for _, condition := range options.Env {
+		g.conditions[condition] = true
+	}
+	return g
+}
+
+// FromNodeModules returns an import map of the dependencies of the package.json in projectDir
+// and their own dependencies, pointing at the files installed in node_modules. Packages are
+// located the way Node.js does, walking up the directories, and their entry points come from
+// their exports field, matched against the conditions of WithEnv plus "import" and "default",
+// or from their module and main fields.
+//
+// Dependencies installed at a different version below another package are mapped in a scope of
+// that package. Subpath patterns of exports are mapped when they are plain prefixes, like
+// "./features/*": "./src/features/*".
+func FromNodeModules(projectDir string, opts ...Option) (importmap.IImportMap, error) {
+	options := &Options{Env: DefaultEnv}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	projectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	project, err := readPackageJSON(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newNodeModulesGenerator(options)
+	if err = g.mapDependencies(projectDir, project, ""); err != nil {
+		return nil, err
+	}
+	return importmap.New(importmap.WithMap(g.data))
+}
+
+// PackageImports returns the subpath imports of the package.json in projectDir, such as
+// "#internal/*": "./src/internal/*.js", with their targets turned into file URLs. Conditional
+// targets are matched against the conditions of WithEnv plus "import" and "default". Targets
+// pointing at other packages are left out. The result is meant for importmap.AddSubpathImports.
+func PackageImports(projectDir string, opts ...Option) (importmap.Imports, error) {
+	options := &Options{Env: DefaultEnv}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	projectDir, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	project, err := readPackageJSON(projectDir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newNodeModulesGenerator(options)
+	imports := make(importmap.Imports, len(project.Imports))
+	for key, value := range project.Imports {
+		target, ok := g.matchConditions(value)
+		if !strings.HasPrefix(key, "#") || !ok || !strings.HasPrefix(target, "./") {
+			continue
+		}
+		// joined as a string, so the "*" of patterns isn't escaped
+		imports[key] = dirUrl(projectDir) + strings.TrimPrefix(target, "./")
+	}
+	return imports, nil
+}
+
+// mapDependencies maps the dependencies of the package in dir in the scope of the package, in
+// the top-level imports for the project itself, then maps the dependencies of each dependency.
+func (g *nodeModulesGenerator) mapDependencies(dir string, pkg *packageJSON, scopeKey string) error {
+	names := make([]string, 0, len(pkg.Dependencies))
+	for name := range pkg.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type installed struct {
+		dir string
+		pkg *packageJSON
+	}
+	var deps []installed
+	for _, name := range names {
+		depDir, err := findPackage(dir, name)
+		if err != nil {
+			return err
+		}
+		dep, err := readPackageJSON(depDir)
+		if err != nil {
+			return err
+		}
+		mappings, err := g.packageMappings(name, depDir, dep)
+		if err != nil {
+			return err
+		}
+
+		for key, target := range mappings {
+			if scopeKey == "" {
+				g.data.Imports[key] = target
+				continue
+			}
+			// a scope only needs the mappings differing from the top-level ones
+			if g.data.Imports[key] == target {
+				continue
+			}
+			if g.data.Scopes[scopeKey] == nil {
+				g.data.Scopes[scopeKey] = make(importmap.Scope)
+			}
+			g.data.Scopes[scopeKey][key] = target
+		}
+		if !g.visited[depDir] {
+			g.visited[depDir] = true
+			deps = append(deps, installed{depDir, dep})
+		}
+	}
+
+	for _, dep := range deps {
+		if err := g.mapDependencies(dep.dir, dep.pkg, dirUrl(dep.dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// packageMappings returns the mappings of the specifiers of package name installed in dir
+func (g *nodeModulesGenerator) packageMappings(name string, dir string, pkg *packageJSON) (map[string]string, error) {
+	mappings := make(map[string]string)
+	exports := bytes.TrimSpace(pkg.Exports)
+
+	if len(exports) == 0 || string(exports) == "null" {
+		entry := pkg.Main
+		if pkg.Module != "" && g.conditions["module"] {
+			entry = pkg.Module
+		}
+		if entry == "" {
+			entry = "index.js"
+		}
+		mappings[name] = fileUrl(filepath.Join(dir, filepath.FromSlash(entry)))
+		// without exports, every file of the package can be imported
+		mappings[name+"/"] = dirUrl(dir)
+		return mappings, nil
+	}
+
+	subpaths, err := exportsSubpaths(exports)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exports of %s: %w", name, err)
+	}
+	for _, subpath := range subpaths {
+		target, ok := g.matchConditions(subpath.value)
+		if !ok || !strings.HasPrefix(target, "./") {
+			continue
+		}
+		key := name + strings.TrimPrefix(subpath.key, ".")
+		if prefix, isPattern := strings.CutSuffix(subpath.key, "*"); isPattern {
+			targetPrefix, plain := strings.CutSuffix(target, "*")
+			if !plain || strings.Contains(targetPrefix, "*") {
+				continue
+			}
+			key, target = name+strings.TrimPrefix(prefix, "."), targetPrefix
+		}
+		resolved := fileUrl(filepath.Join(dir, filepath.FromSlash(target)))
+		if strings.HasSuffix(target, "/") {
+			resolved += "/"
+		}
+		mappings[key] = resolved
+	}
+	return mappings, nil
+}
+
+// exportsSubpaths returns the subpaths of an exports field, "." alone when the field is a
+// string, an array or an object of conditions.
+func exportsSubpaths(exports json.RawMessage) ([]member, error) {
+	members, isObject, err := objectMembers(exports)
+	if err != nil {
+		return nil, err
+	}
+	if !isObject || len(members) == 0 || !strings.HasPrefix(members[0].key, ".") {
+		return []member{{key: ".", value: exports}}, nil
+	}
+	return members, nil
+}
+
+// matchConditions returns the target of an exports value: the string itself, the first matching
+// entry of an array, or the first member of an object of conditions whose condition is set.
+func (g *nodeModulesGenerator) matchConditions(value json.RawMessage) (string, bool) {
+	var target string
+	if err := json.Unmarshal(value, &target); err == nil {
+		return target, true
+	}
+
+	var fallbacks []json.RawMessage
+	if err := json.Unmarshal(value, &fallbacks); err == nil {
+		for _, fallback := range fallbacks {
+			if target, ok := g.matchConditions(fallback); ok {
+				return target, true
+			}
+		}
+		return "", false
+	}
+
+	members, _, err := objectMembers(value)
+	if err != nil {
+		return "", false
+	}
+	for _, condition := range members {
+		if g.conditions[condition.key] {
+			if target, ok := g.matchConditions(condition.value); ok {
+				return target, true
+			}
+		}
+	}
+	return "", false
+}
+
+// objectMembers returns the members of a JSON object in order, and false for other values
+func objectMembers(raw json.RawMessage) ([]member, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, false, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, false, nil
+	}
+
+	var members []member
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return nil, true, err
+		}
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return nil, true, err
+		}
+		members = append(members, member{key: token.(string), value: value})
+	}
+	return members, true, nil
+}
+
+// findPackage returns the directory package name is installed in for the code of dir, looking
+// in the node_modules directories of dir and its parents.
+func findPackage(dir string, name string) (string, error) {
+	for current := dir; ; current = filepath.Dir(current) {
+		if filepath.Base(current) != "node_modules" {
+			candidate := filepath.Join(current, "node_modules", filepath.FromSlash(name))
+			if info, err := os.Stat(filepath.Join(candidate, "package.json")); err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+		if filepath.Dir(current) == current {
+			return "", fmt.Errorf("%s is not installed, run npm install in %s", name, dir)
+		}
+	}
+}
+
+func readPackageJSON(dir string) (*packageJSON, error) {
+	contents, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &packageJSON{}
+	if err = json.Unmarshal(contents, pkg); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(dir, "package.json"), err)
+	}
+	return pkg, nil
+}
+
+// fileUrl returns the file URL of an absolute path
+func fileUrl(p string) string {
+	return importmap.FileURL(filepath.Clean(p)).String()
+}
+
+// dirUrl returns the file URL of a directory, with a trailing slash
+func dirUrl(dir string) string {
+	return importmap.DirURL(filepath.Clean(dir)).String()
+}
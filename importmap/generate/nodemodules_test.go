@@ -0,0 +1,110 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFromNodeModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"package.json": `{"dependencies": {"a": "^1.0.0", "b": "^1.0.0", "@scope/c": "1.0.0"}}`,
+		"node_modules/a/package.json": `{
+			"name": "a",
+			"exports": {
+				".": {"node": "./node.js", "browser": {"require": "./browser.cjs", "import": "./browser.mjs"}, "default": "./index.js"},
+				"./utils": "./lib/utils.js",
+				"./features/*": "./src/features/*",
+				"./icons/*": "./icons/*.js",
+				"./internal": null
+			},
+			"dependencies": {"b": "^2.0.0"}
+		}`,
+		"node_modules/a/node_modules/b/package.json": `{"name": "b", "main": "b2.js"}`,
+		"node_modules/b/package.json":                `{"name": "b", "main": "main.js", "module": "esm.js"}`,
+		"node_modules/@scope/c/package.json":         `{"name": "@scope/c", "exports": "./c.mjs"}`,
+	})
+
+	m, err := FromNodeModules(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := dirUrl(dir)
+	expected := map[string]string{
+		"a":           root + "node_modules/a/browser.mjs",
+		"a/utils":     root + "node_modules/a/lib/utils.js",
+		"a/features/": root + "node_modules/a/src/features/",
+		"b":           root + "node_modules/b/esm.js",
+		"b/":          root + "node_modules/b/",
+		"@scope/c":    root + "node_modules/@scope/c/c.mjs",
+	}
+	imports := m.GetImports()
+	if len(imports) != len(expected) {
+		t.Errorf("expected %d imports, got %v", len(expected), imports)
+	}
+	for key, target := range expected {
+		if imports[key] != target {
+			t.Errorf("expected %s to map to %s, got %s", key, target, imports[key])
+		}
+	}
+
+	scope := m.GetScopes()[root+"node_modules/a/"]
+	if scope["b"] != root+"node_modules/a/node_modules/b/b2.js" {
+		t.Errorf("expected the nested version of b in the scope of a, got %v", m.GetScopes())
+	}
+	if len(m.GetScopes()) != 1 {
+		t.Errorf("expected a single scope, got %v", m.GetScopes())
+	}
+}
+
+func TestFromNodeModulesMissingPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"package.json": `{"dependencies": {"missing": "1.0.0"}}`})
+	if _, err := FromNodeModules(dir); err == nil {
+		t.Error("expected an error for a dependency which isn't installed")
+	}
+}
+
+func TestPackageImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"package.json": `{"imports": {
+			"#internal/*": "./src/internal/*.js",
+			"#config": {"node": "./config.node.js", "browser": "./config.browser.js"},
+			"#dep": "some-package",
+			"notsubpath": "./ignored.js"
+		}}`,
+	})
+
+	imports, err := PackageImports(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := dirUrl(dir)
+	expected := map[string]string{
+		"#internal/*": root + "src/internal/*.js",
+		"#config":     root + "config.browser.js",
+	}
+	if len(imports) != len(expected) {
+		t.Errorf("expected %d imports, got %v", len(expected), imports)
+	}
+	for key, target := range expected {
+		if imports[key] != target {
+			t.Errorf("expected %s to map to %s, got %s", key, target, imports[key])
+		}
+	}
+}
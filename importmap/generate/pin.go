@@ -0,0 +1,172 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultRegistryURL is the npm registry Pin looks the versions of the packages up in
+const DefaultRegistryURL = "https://registry.npmjs.org/"
+
+// providers are the CDNs whose targets Pin recognizes
+var providers = []Provider{ProviderJSPM, ProviderESMSH, ProviderJSDelivr, ProviderUnpkg}
+
+// packument is the part of the registry metadata of a package Pin uses
+type packument struct {
+	DistTags map[string]string          `json:"dist-tags"`
+	Versions map[string]json.RawMessage `json:"versions"`
+}
+
+// pinner resolves the versions of the packages, fetching the metadata of each package once
+type pinner struct {
+	ctx        context.Context
+	options    *Options
+	packuments map[string]*packument
+}
+
+// Pin returns a copy of m where the CDN targets with a version range, a dist-tag or no version at
+// all, like "https://esm.sh/react@^18" or "https://unpkg.com/lit", point at the exact version the
+// npm registry currently resolves them to, so the builds using the map are reproducible. Targets
+// already on an exact version and the other URLs are left untouched.
+func Pin(ctx context.Context, m importmap.IImportMap, opts ...Option) (importmap.IImportMap, error) {
+	options := &Options{
+		RegistryURL: DefaultRegistryURL,
+		Client:      http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	p := &pinner{ctx: ctx, options: options, packuments: make(map[string]*packument)}
+
+	data := importmap.Data{
+		Imports:   make(importmap.Imports, len(m.GetImports())),
+		Scopes:    make(importmap.Scopes, len(m.GetScopes())),
+		Integrity: make(importmap.Integrity, len(m.GetIntegrity())),
+	}
+	pinned := make(map[string]string)
+	for key, target := range m.GetImports() {
+		resolved, err := p.pinTarget(target)
+		if err != nil {
+			return nil, err
+		}
+		data.Imports[key], pinned[target] = resolved, resolved
+	}
+	for scopeKey, scope := range m.GetScopes() {
+		data.Scopes[scopeKey] = make(map[string]string, len(scope))
+		for key, target := range scope {
+			resolved, err := p.pinTarget(target)
+			if err != nil {
+				return nil, err
+			}
+			data.Scopes[scopeKey][key], pinned[target] = resolved, resolved
+		}
+	}
+	for target, integrity := range m.GetIntegrity() {
+		if resolved, ok := pinned[target]; ok {
+			target = resolved
+		}
+		data.Integrity[target] = integrity
+	}
+	return importmap.New(importmap.WithMap(data), importmap.WithMapUrl(m.GetMapUrl()))
+}
+
+// pinTarget returns the target with its package on an exact version
+func (p *pinner) pinTarget(target string) (string, error) {
+	for _, provider := range providers {
+		ref, ok := provider.ParsePackageURL(target)
+		if !ok {
+			continue
+		}
+		if _, exact := parseVersion(ref.Version); exact {
+			return target, nil
+		}
+		pinned, err := p.resolveVersion(ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to pin %s: %w", target, err)
+		}
+		return replaceVersion(target, ref, pinned)
+	}
+	return target, nil
+}
+
+// resolveVersion returns the greatest version of the package matching the version range or
+// dist-tag of ref, the latest one when it has no version
+func (p *pinner) resolveVersion(ref importmap.PackageRef) (string, error) {
+	metadata, err := p.packument(ref.Name)
+	if err != nil {
+		return "", err
+	}
+	spec := ref.Version
+	if spec == "" {
+		spec = "latest"
+	}
+	if tagged, ok := metadata.DistTags[spec]; ok {
+		return tagged, nil
+	}
+	versionRange, ok := parseRange(spec)
+	if !ok {
+		return "", fmt.Errorf("invalid version range %q", spec)
+	}
+	versions := make([]string, 0, len(metadata.Versions))
+	for v := range metadata.Versions {
+		versions = append(versions, v)
+	}
+	pinned, ok := versionRange.maxSatisfying(versions)
+	if !ok {
+		return "", fmt.Errorf("no version of %s matches %q", ref.Name, spec)
+	}
+	return pinned, nil
+}
+
+// packument fetches the registry metadata of a package
+func (p *pinner) packument(name string) (*packument, error) {
+	if metadata, ok := p.packuments[name]; ok {
+		return metadata, nil
+	}
+	registryUrl := strings.TrimSuffix(p.options.RegistryURL, "/") + "/" + strings.Replace(url.PathEscape(name), "%40", "@", 1)
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodGet, registryUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.npm.install-v1+json")
+
+	resp, err := p.options.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", registryUrl, resp.Status)
+	}
+	metadata := &packument{}
+	if err = json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata for %s: %w", name, err)
+	}
+	p.packuments[name] = metadata
+	return metadata, nil
+}
+
+// replaceVersion returns target with the version of the package replaced by version, keeping the
+// rest of the URL as is
+func replaceVersion(target string, ref importmap.PackageRef, version string) (string, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	old := ref.Name
+	if ref.Version != "" {
+		old += "@" + ref.Version
+	}
+	index := strings.Index(u.Path, old)
+	if index < 0 {
+		return "", fmt.Errorf("failed to pin %s: %s not found in the path", target, old)
+	}
+	u.Path = u.Path[:index] + ref.Name + "@" + version + u.Path[index+len(old):]
+	u.RawPath = ""
+	return u.String(), nil
+}
@@ -0,0 +1,82 @@
+package generate
+
+import (
+	"context"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPin(t *testing.T) {
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/react":
+			_, _ = w.Write([]byte(`{"dist-tags": {"latest": "18.3.1", "next": "19.0.0-rc.1"}, "versions": {"17.0.2": {}, "18.2.0": {}, "18.3.1": {}, "19.0.0-rc.1": {}}}`))
+		case "/@lit/reactive-element":
+			_, _ = w.Write([]byte(`{"dist-tags": {"latest": "2.0.4"}, "versions": {"1.6.3": {}, "2.0.4": {}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	m, err := importmap.New(importmap.WithMap(importmap.Data{
+		Imports: importmap.Imports{
+			"react":                  "https://esm.sh/react@^18?dev",
+			"react/":                 "https://esm.sh/react@%5E18/",
+			"react-next":             "https://cdn.jsdelivr.net/npm/react@next/+esm",
+			"@lit/reactive-element/": "https://ga.jspm.io/npm:@lit/reactive-element@1/",
+			"pinned":                 "https://unpkg.com/pinned@1.0.0?module",
+			"app":                    "./app.js",
+		},
+		Scopes: importmap.Scopes{
+			"/legacy/": {"react": "https://unpkg.com/react?module"},
+		},
+		Integrity: importmap.Integrity{"https://esm.sh/react@^18?dev": "sha384-abc"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := Pin(context.Background(), m, WithRegistryURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := importmap.Imports{
+		"react":                  "https://esm.sh/react@18.3.1?dev",
+		"react/":                 "https://esm.sh/react@18.3.1/",
+		"react-next":             "https://cdn.jsdelivr.net/npm/react@19.0.0-rc.1/+esm",
+		"@lit/reactive-element/": "https://ga.jspm.io/npm:@lit/reactive-element@1.6.3/",
+		"pinned":                 "https://unpkg.com/pinned@1.0.0?module",
+		"app":                    "./app.js",
+	}
+	for key, target := range expected {
+		if pinned.GetImports()[key] != target {
+			t.Errorf("expected %s for %s, got %s", target, key, pinned.GetImports()[key])
+		}
+	}
+	if scoped := pinned.GetScopes()["/legacy/"]["react"]; scoped != "https://unpkg.com/react@18.3.1?module" {
+		t.Errorf("expected the scoped target to be pinned to the latest version, got %s", scoped)
+	}
+	if pinned.GetIntegrity()["https://esm.sh/react@18.3.1?dev"] != "sha384-abc" {
+		t.Errorf("expected the integrity to follow the pinned target, got %v", pinned.GetIntegrity())
+	}
+	if requests["/react"] != 1 {
+		t.Errorf("expected the metadata of react to be fetched once, got %d requests", requests["/react"])
+	}
+	if m.GetImports()["react"] != "https://esm.sh/react@^18?dev" {
+		t.Error("expected the original map to be left untouched")
+	}
+
+	m, err = importmap.New(importmap.WithMap(importmap.Data{Imports: importmap.Imports{"react": "https://esm.sh/react@^20"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Pin(context.Background(), m, WithRegistryURL(server.URL)); err == nil {
+		t.Error("expected an error when no version matches")
+	}
+}
@@ -0,0 +1,260 @@
+package generate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// version is a semver version
+type version struct {
+	major, minor, patch int
+	pre                 []string
+}
+
+// parseVersion parses an exact version like "18.2.0" or "1.0.0-rc.1+build", a leading "v" or "="
+// being allowed
+func parseVersion(s string) (version, bool) {
+	numbers, pre, ok := parsePartial(s)
+	if !ok || len(numbers) != 3 {
+		return version{}, false
+	}
+	return version{numbers[0], numbers[1], numbers[2], pre}, true
+}
+
+// parsePartial parses a version which may miss its minor and patch numbers, like "18", "18.x" or
+// "1.2.*", returning the numbers given
+func parsePartial(s string) ([]int, []string, bool) {
+	s = strings.TrimLeft(strings.TrimSpace(s), "=v")
+	s, _, _ = strings.Cut(s, "+")
+	s, preRelease, hasPre := strings.Cut(s, "-")
+
+	var numbers []int
+	if s != "" {
+		for _, part := range strings.Split(s, ".") {
+			if part == "x" || part == "X" || part == "*" {
+				break
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return nil, nil, false
+			}
+			numbers = append(numbers, n)
+		}
+	}
+	if len(numbers) > 3 {
+		return nil, nil, false
+	}
+
+	var pre []string
+	if hasPre {
+		if len(numbers) != 3 || preRelease == "" {
+			return nil, nil, false
+		}
+		pre = strings.Split(preRelease, ".")
+	}
+	return numbers, pre, true
+}
+
+// compare returns -1, 0 or 1 as v is lower, equal or greater than other
+func (v version) compare(other version) int {
+	for _, d := range [][2]int{{v.major, other.major}, {v.minor, other.minor}, {v.patch, other.patch}} {
+		if d[0] != d[1] {
+			return compareInts(d[0], d[1])
+		}
+	}
+	switch {
+	case len(v.pre) == 0 && len(other.pre) == 0:
+		return 0
+	case len(v.pre) == 0:
+		return 1
+	case len(other.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.pre) && i < len(other.pre); i++ {
+		a, aErr := strconv.Atoi(v.pre[i])
+		b, bErr := strconv.Atoi(other.pre[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if a != b {
+				return compareInts(a, b)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case v.pre[i] != other.pre[i]:
+			return strings.Compare(v.pre[i], other.pre[i])
+		}
+	}
+	return compareInts(len(v.pre), len(other.pre))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparator is a bound of a range, like ">=18.0.0"
+type comparator struct {
+	op      string
+	version version
+}
+
+func (c comparator) matches(v version) bool {
+	cmp := v.compare(c.version)
+	switch c.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return cmp == 0
+}
+
+// versionRange is a set of comparator sets, one of which a version must match
+type versionRange [][]comparator
+
+// parseRange parses an npm version range like "^18", "~1.2.3", ">=1 <3", "1 - 2" or "16 || 18"
+func parseRange(s string) (versionRange, bool) {
+	var result versionRange
+	for _, alternative := range strings.Split(s, "||") {
+		fields := strings.Fields(alternative)
+		var set []comparator
+		for i := 0; i < len(fields); i++ {
+			if i+2 < len(fields) && fields[i+1] == "-" {
+				lower, ok := expand(">=", fields[i])
+				if !ok {
+					return nil, false
+				}
+				upper, ok := expand("<=", fields[i+2])
+				if !ok {
+					return nil, false
+				}
+				set = append(set, append(lower, upper...)...)
+				i += 2
+				continue
+			}
+			op, partial := splitOperator(fields[i])
+			if partial == "" && i+1 < len(fields) {
+				i++
+				partial = fields[i]
+			}
+			comparators, ok := expand(op, partial)
+			if !ok {
+				return nil, false
+			}
+			set = append(set, comparators...)
+		}
+		result = append(result, set)
+	}
+	return result, true
+}
+
+func splitOperator(field string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if rest, ok := strings.CutPrefix(field, op); ok {
+			return op, rest
+		}
+	}
+	return "", field
+}
+
+// expand turns an operator and a possibly partial version into comparators on exact versions
+func expand(op string, partial string) ([]comparator, bool) {
+	numbers, pre, ok := parsePartial(partial)
+	if !ok {
+		return nil, false
+	}
+	padded := append(numbers, 0, 0, 0)
+	lower := version{padded[0], padded[1], padded[2], pre}
+	// next is the first version above the ones the partial version stands for
+	next := func(index int) version {
+		switch index {
+		case 0:
+			return version{major: lower.major + 1, pre: []string{"0"}}
+		case 1:
+			return version{major: lower.major, minor: lower.minor + 1, pre: []string{"0"}}
+		}
+		return version{major: lower.major, minor: lower.minor, patch: lower.patch + 1, pre: []string{"0"}}
+	}
+
+	if len(numbers) == 0 {
+		if op == "<" || op == ">" {
+			return []comparator{{"<", version{pre: []string{"0"}}}}, true
+		}
+		return []comparator{{">=", version{}}}, true
+	}
+	switch op {
+	case "^":
+		index := 0
+		for index < len(numbers)-1 && numbers[index] == 0 {
+			index++
+		}
+		return []comparator{{">=", lower}, {"<", next(index)}}, true
+	case "~":
+		if len(numbers) == 1 {
+			return []comparator{{">=", lower}, {"<", next(0)}}, true
+		}
+		return []comparator{{">=", lower}, {"<", next(1)}}, true
+	case ">":
+		if len(numbers) < 3 {
+			return []comparator{{">=", next(len(numbers) - 1)}}, true
+		}
+		return []comparator{{">", lower}}, true
+	case "<=":
+		if len(numbers) < 3 {
+			return []comparator{{"<", next(len(numbers) - 1)}}, true
+		}
+		return []comparator{{"<=", lower}}, true
+	case ">=", "<":
+		return []comparator{{op, lower}}, true
+	}
+	if len(numbers) < 3 {
+		return []comparator{{">=", lower}, {"<", next(len(numbers) - 1)}}, true
+	}
+	return []comparator{{"=", lower}}, true
+}
+
+// matches reports whether v is in the range. Prereleases only match the comparator sets naming a
+// prerelease of the same major, minor and patch version, like npm does.
+func (r versionRange) matches(v version) bool {
+	for _, set := range r {
+		matches := true
+		allowed := len(v.pre) == 0
+		for _, c := range set {
+			matches = matches && c.matches(v)
+			if len(c.version.pre) > 0 && c.version.major == v.major && c.version.minor == v.minor && c.version.patch == v.patch {
+				allowed = true
+			}
+		}
+		if matches && allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSatisfying returns the greatest of versions in the range
+func (r versionRange) maxSatisfying(versions []string) (string, bool) {
+	var best string
+	var bestVersion version
+	for _, candidate := range versions {
+		v, ok := parseVersion(candidate)
+		if !ok || !r.matches(v) {
+			continue
+		}
+		if best == "" || v.compare(bestVersion) > 0 {
+			best, bestVersion = candidate, v
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,56 @@
+package generate
+
+import (
+	"testing"
+)
+
+func TestVersionRangeMaxSatisfying(t *testing.T) {
+	versions := []string{"0.1.0", "0.1.5", "1.0.0", "1.2.3", "1.2.9", "1.3.0", "2.0.0-rc.1", "2.0.0", "2.1.0", "3.0.0-beta.2"}
+	cases := map[string]string{
+		"^1":              "1.3.0",
+		"^1.2.3":          "1.3.0",
+		"~1.2.3":          "1.2.9",
+		"~1":              "1.3.0",
+		"1.2":             "1.2.9",
+		"1.x":             "1.3.0",
+		"^0.1.2":          "0.1.5",
+		">=1 <2":          "1.3.0",
+		">1.2":            "2.1.0",
+		"<=1.2":           "1.2.9",
+		"1.0.0 - 1.2":     "1.2.9",
+		"0.1 || 1.2.3":    "1.2.3",
+		"*":               "2.1.0",
+		"=2.0.0":          "2.0.0",
+		"^2.0.0-rc.0":     "2.1.0",
+		">= 3.0.0-beta.1": "3.0.0-beta.2",
+	}
+	for spec, expected := range cases {
+		versionRange, ok := parseRange(spec)
+		if !ok {
+			t.Errorf("failed to parse %q", spec)
+			continue
+		}
+		if best, _ := versionRange.maxSatisfying(versions); best != expected {
+			t.Errorf("expected %s for %q, got %s", expected, spec, best)
+		}
+	}
+
+	versionRange, _ := parseRange("^4")
+	if best, ok := versionRange.maxSatisfying(versions); ok {
+		t.Errorf("expected no version to match ^4, got %s", best)
+	}
+	if _, ok := parseRange("^a.b"); ok {
+		t.Error("expected an invalid range to be rejected")
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0"}
+	for i := 1; i < len(ordered); i++ {
+		lower, _ := parseVersion(ordered[i-1])
+		higher, _ := parseVersion(ordered[i])
+		if lower.compare(higher) != -1 || higher.compare(lower) != 1 {
+			t.Errorf("expected %s < %s", ordered[i-1], ordered[i])
+		}
+	}
+}
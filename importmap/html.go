@@ -0,0 +1,366 @@
+package importmap
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HTMLOptions is the configuration object for extracting import maps from HTML documents
+type HTMLOptions struct {
+	// BaseUrl is the URL of the document, used to resolve src attributes and relative targets.
+	// Defaults to the current working directory.
+	BaseUrl *url.URL
+	// Strict makes documents with more than one import map an error instead of merging them
+	Strict bool
+	// LoadSrc loads the import maps referenced by src attributes. Only file URLs are supported by default.
+	LoadSrc func(src *url.URL) ([]byte, error)
+	// MapOptions are the options of the import maps of the document, like WithEnvironment. The
+	// URL of each map overrides their map URL.
+	MapOptions []Option
+}
+
+type HTMLOption func(options *HTMLOptions)
+
+// WithHTMLBaseUrl sets the URL of the HTML document
+func WithHTMLBaseUrl(baseUrl *url.URL) HTMLOption {
+	return func(options *HTMLOptions) {
+		options.BaseUrl = baseUrl
+	}
+}
+
+// WithHTMLStrict makes documents with more than one import map an error
+func WithHTMLStrict() HTMLOption {
+	return func(options *HTMLOptions) {
+		options.Strict = true
+	}
+}
+
+// WithSrcLoader sets the function loading the import maps referenced by src attributes
+func WithSrcLoader(loadSrc func(src *url.URL) ([]byte, error)) HTMLOption {
+	return func(options *HTMLOptions) {
+		options.LoadSrc = loadSrc
+	}
+}
+
+// WithHTMLMapOptions sets the options the import maps of the document are created with
+func WithHTMLMapOptions(opts ...Option) HTMLOption {
+	return func(options *HTMLOptions) {
+		options.MapOptions = opts
+	}
+}
+
+// htmlTag is a tag found in an HTML document together with its raw text contents
+type htmlTag struct {
+	attributes map[string]string
+	contents   string
+	// start and end are the offsets of the element in the document, its closing tag included,
+	// and contentsStart the offset of its contents
+	start         int
+	end           int
+	contentsStart int
+}
+
+// LoadFromHTML loads the import maps of an HTML file like ParseHTML, the URL of the document
+// defaulting to the file URL of path.
+func LoadFromHTML(path string, opts ...HTMLOption) (IImportMap, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	fileUrl := FileURL(absPath)
+
+	return ParseHTML(contents, append([]HTMLOption{WithHTMLBaseUrl(fileUrl)}, opts...)...)
+}
+
+// ParseHTML extracts the <script type="importmap"> blocks of an HTML document and returns them as
+// a single IImportMap. A <base href> in the document changes the URL the maps are resolved against.
+//
+// Multiple import maps are merged the way browsers merge them: entries of earlier maps take
+// precedence and conflicting entries of later maps are left out with a Diagnostic. Each map's
+// relative URLs are resolved against its own URL, which is the src attribute for external maps.
+func ParseHTML(contents []byte, opts ...HTMLOption) (IImportMap, error) {
+	options := &HTMLOptions{
+		LoadSrc: loadFileSrc,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	contents, err := decodeText(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.BaseUrl == nil {
+		options.BaseUrl, err = defaultMapUrl()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the first <base href> changes the base URL of the document, as it does in browsers
+	for _, base := range extractTags(string(contents), "base") {
+		href, ok := base.attributes["href"]
+		if !ok {
+			continue
+		}
+		hrefUrl, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return nil, fmt.Errorf("invalid <base href>: %w", err)
+		}
+		options.BaseUrl = options.BaseUrl.ResolveReference(hrefUrl)
+		break
+	}
+
+	var blocks []htmlTag
+	for _, script := range extractTags(string(contents), "script") {
+		if strings.ToLower(strings.TrimSpace(script.attributes["type"])) == "importmap" {
+			blocks = append(blocks, script)
+		}
+	}
+
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no <script type=\"importmap\"> found")
+	}
+	if options.Strict && len(blocks) > 1 {
+		return nil, fmt.Errorf("found %d import maps, only one is allowed in strict mode", len(blocks))
+	}
+
+	merged := Data{
+		Imports:   make(Imports),
+		Scopes:    make(Scopes),
+		Integrity: make(Integrity),
+	}
+	var diagnostics []Diagnostic
+
+	for _, block := range blocks {
+		mapUrl := options.BaseUrl
+		source := []byte(stripCDATA(block.contents))
+
+		if src, ok := block.attributes["src"]; ok {
+			srcUrl, err := url.Parse(strings.TrimSpace(src))
+			if err != nil {
+				return nil, err
+			}
+			mapUrl = options.BaseUrl.ResolveReference(srcUrl)
+			source, err = options.LoadSrc(mapUrl)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load the import map %s: %w", mapUrl, err)
+			}
+		}
+
+		m, err := Parse(source, append(append([]Option(nil), options.MapOptions...), WithMapUrl(mapUrl))...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid import map: %w", err)
+		}
+		diagnostics = append(diagnostics, m.Diagnostics()...)
+		diagnostics = append(diagnostics, mergeFirstWins(merged, m.CanonicalForm())...)
+	}
+
+	// the environments and conditions were applied to each map, the merged one has none
+	m, err := New(append(append([]Option(nil), options.MapOptions...), WithMapUrl(options.BaseUrl), WithMap(merged))...)
+	if err != nil {
+		return nil, err
+	}
+	asImportMap(m).diagnostics = append(diagnostics, m.Diagnostics()...)
+	return m, nil
+}
+
+// mergeFirstWins adds the entries of data to merged unless they are already defined.
+// Entries which would have changed an existing mapping are reported.
+func mergeFirstWins(merged Data, data Data) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	mergeSpecifierMap := func(scope string, dst map[string]string, src map[string]string) {
+		for key, target := range src {
+			if existing, ok := dst[key]; ok {
+				if existing != target {
+					diagnostics = append(diagnostics, Diagnostic{
+						Scope:   scope,
+						Key:     key,
+						Message: fmt.Sprintf("already mapped to %q by a previous import map", existing),
+					})
+				}
+				continue
+			}
+			dst[key] = target
+		}
+	}
+
+	mergeSpecifierMap("", merged.Imports, data.Imports)
+	for scopeKey, scope := range data.Scopes {
+		if _, ok := merged.Scopes[scopeKey]; !ok {
+			merged.Scopes[scopeKey] = make(Scope)
+		}
+		mergeSpecifierMap(scopeKey, merged.Scopes[scopeKey], scope)
+	}
+	for target, integrity := range data.Integrity {
+		if _, ok := merged.Integrity[target]; !ok {
+			merged.Integrity[target] = integrity
+		}
+	}
+
+	return diagnostics
+}
+
+// loadFileSrc reads external import maps from the file system.
+func loadFileSrc(src *url.URL) ([]byte, error) {
+	if src.Scheme != "file" {
+		return nil, fmt.Errorf("loading import maps over %s is not supported, use WithSrcLoader", src.Scheme)
+	}
+	path, err := FilePath(src)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// stripCDATA removes the CDATA section markers some documents wrap inline scripts with.
+func stripCDATA(contents string) string {
+	trimmed := strings.TrimSpace(contents)
+	for _, prefix := range []string{"//<![CDATA[", "<![CDATA["} {
+		if strings.HasPrefix(trimmed, prefix) {
+			trimmed = strings.TrimPrefix(trimmed, prefix)
+			trimmed = strings.TrimSuffix(trimmed, "//]]>")
+			trimmed = strings.TrimSuffix(trimmed, "]]>")
+			return trimmed
+		}
+	}
+	return contents
+}
+
+// extractTags finds the tags with the given name in an HTML document, skipping comments.
+// The contents are only captured for raw text elements like script and style.
+func extractTags(doc string, name string) []htmlTag {
+	var tags []htmlTag
+	lower := asciiLower(doc)
+	open := "<" + name
+
+	for pos := 0; pos < len(doc); {
+		next := strings.Index(lower[pos:], open)
+		comment := strings.Index(lower[pos:], "<!--")
+		if next < 0 {
+			break
+		}
+		if comment >= 0 && comment < next {
+			end := strings.Index(lower[pos+comment+4:], "-->")
+			if end < 0 {
+				break
+			}
+			pos += comment + 4 + end + 3
+			continue
+		}
+
+		start := pos + next + len(open)
+		if start < len(doc) && !isTagNameEnd(doc[start]) {
+			pos = start
+			continue
+		}
+
+		attributes, end := parseAttributes(doc, start)
+		tag := htmlTag{attributes: attributes, start: pos + next, end: end, contentsStart: end}
+		pos = end
+
+		if name == "script" || name == "style" {
+			closing := strings.Index(lower[end:], "</"+name)
+			if closing < 0 {
+				tag.contents = doc[end:]
+				pos = len(doc)
+			} else {
+				tag.contents = doc[end : end+closing]
+				pos = end + closing
+			}
+			tag.end = pos
+			if gt := strings.IndexByte(doc[pos:], '>'); gt >= 0 {
+				tag.end = pos + gt + 1
+			}
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// asciiLower lowercases the ASCII letters of s only, so the positions found in the result are
+// the same in s, which strings.ToLower doesn't guarantee for the other characters.
+func asciiLower(s string) string {
+	b := []byte(s)
+	for n, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[n] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+func isTagNameEnd(c byte) bool {
+	return c == '>' || c == '/' || c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// parseAttributes parses the attributes of a tag starting at pos, returning them with lowercase
+// names and the position right after the closing ">".
+func parseAttributes(doc string, pos int) (map[string]string, int) {
+	attributes := make(map[string]string)
+
+	for pos < len(doc) {
+		for pos < len(doc) && (isTagNameEnd(doc[pos]) && doc[pos] != '>') {
+			pos++
+		}
+		if pos >= len(doc) {
+			break
+		}
+		if doc[pos] == '>' {
+			return attributes, pos + 1
+		}
+
+		nameStart := pos
+		for pos < len(doc) && doc[pos] != '=' && !isTagNameEnd(doc[pos]) {
+			pos++
+		}
+		attrName := strings.ToLower(doc[nameStart:pos])
+
+		for pos < len(doc) && (doc[pos] == ' ' || doc[pos] == '\t' || doc[pos] == '\n' || doc[pos] == '\r') {
+			pos++
+		}
+		if pos >= len(doc) || doc[pos] != '=' {
+			attributes[attrName] = ""
+			continue
+		}
+		pos++
+		for pos < len(doc) && (doc[pos] == ' ' || doc[pos] == '\t' || doc[pos] == '\n' || doc[pos] == '\r') {
+			pos++
+		}
+		if pos >= len(doc) {
+			break
+		}
+
+		var value string
+		if quote := doc[pos]; quote == '"' || quote == '\'' {
+			end := strings.IndexByte(doc[pos+1:], quote)
+			if end < 0 {
+				value = doc[pos+1:]
+				pos = len(doc)
+			} else {
+				value = doc[pos+1 : pos+1+end]
+				pos += end + 2
+			}
+		} else {
+			valueStart := pos
+			for pos < len(doc) && doc[pos] != '>' && !isTagNameEnd(doc[pos]) {
+				pos++
+			}
+			value = doc[valueStart:pos]
+		}
+		attributes[attrName] = value
+	}
+
+	return attributes, len(doc)
+}
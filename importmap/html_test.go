@@ -0,0 +1,129 @@
+package importmap
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseHTMLMultipleMaps(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "external.json"), []byte(`{"imports": {"external": "./external.js", "a": "./ignored.js"}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := "\ufeff<!DOCTYPE html><html><head>\n" +
+		`<!-- <script type="importmap">{"imports": {"commented": "/commented.js"}}</script> -->` + "\n" +
+		`<SCRIPT TYPE="importmap">{"imports": {"a": "/a.js"}, "scopes": {"/x/": {"a": "/x-a.js"}}}</SCRIPT>` + "\n" +
+		`<script type='importmap'>//<![CDATA[` + "\n" + `{"imports": {"a": "/other-a.js", "b": "/b.js"}}` + "\n" + `//]]></script>` + "\n" +
+		`<script type=importmap src="external.json"></script>` + "\n" +
+		`<script type="module">import "a";</script>` + "\n" +
+		`</head></html>`
+
+	baseUrl, _ := url.Parse("file://" + dir + "/index.html")
+	m, err := ParseHTML([]byte(doc), WithHTMLBaseUrl(baseUrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertUrlsEqualsU(m, "a", baseUrl, "/a.js", t)
+	assertUrlsEqualsU(m, "b", baseUrl, "/b.js", t)
+	assertUrlsEqualsU(m, "external", baseUrl, "file://"+dir+"/external.js", t)
+
+	if _, ok := m.GetImports()["commented"]; ok {
+		t.Error("import maps inside comments must be ignored")
+	}
+	if len(m.Diagnostics()) != 2 {
+		t.Errorf("expected the 2 conflicting entries to be reported, got %v", m.Diagnostics())
+	}
+
+	if _, err = ParseHTML([]byte(doc), WithHTMLBaseUrl(baseUrl), WithHTMLStrict()); err == nil {
+		t.Error("expected multiple import maps to fail in strict mode")
+	}
+}
+
+func TestParseHTMLWithoutImportMap(t *testing.T) {
+	if _, err := ParseHTML([]byte(`<html><script>console.log(1)</script></html>`)); err == nil {
+		t.Error("expected an error for documents without an import map")
+	}
+}
+
+func TestParseHTMLNonASCII(t *testing.T) {
+	// the lowercase of İ is longer than it, which must not shift the positions of the tags
+	doc := "<html><body><p>" + strings.Repeat("İ", 20) + "</p>\n" +
+		`<script type="importmap">{"imports": {"a": "/a.js"}}</script>` + "\n" +
+		`</body></html>`
+
+	baseUrl, _ := url.Parse("https://site.com/index.html")
+	m, err := ParseHTML([]byte(doc), WithHTMLBaseUrl(baseUrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUrlsEqualsU(m, "a", baseUrl, "https://site.com/a.js", t)
+}
+
+func TestParseHTMLMapOptions(t *testing.T) {
+	doc := `<script type="importmap">{
+		"imports": {"a": "/a.development.js"},
+		"environments": {"production": {"imports": {"a": "/a.production.js"}}}
+	}</script>`
+
+	baseUrl, _ := url.Parse("https://site.com/index.html")
+	m, err := ParseHTML([]byte(doc), WithHTMLBaseUrl(baseUrl), WithHTMLMapOptions(WithEnvironment("production")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUrlsEqualsU(m, "a", baseUrl, "https://site.com/a.production.js", t)
+}
+
+func TestLoadFromHTML(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "maps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(dir, "maps", "external.json"), []byte(`{"imports": {"external": "./external.js"}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := `<html><head><base href="/app/">` +
+		`<script type="importmap">{"imports": {"inline": "./lib/inline.js"}}</script>` +
+		`</head></html>`
+	path := filepath.Join(dir, "index.html")
+	if err = os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadFromHTML(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := m.Resolve("inline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "file:///app/lib/inline.js" {
+		t.Errorf("expected the <base href> to be honored, got %s", resolved)
+	}
+
+	doc = `<html><head><base href="maps/">` +
+		`<script type="importmap" src="external.json"></script>` +
+		`</head></html>`
+	if err = os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err = LoadFromHTML(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err = m.Resolve("external")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "maps", "external.js"))}).String()
+	if resolved != expected {
+		t.Errorf("expected %s, got %s", expected, resolved)
+	}
+}
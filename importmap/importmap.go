@@ -7,6 +7,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
 )
 
 type Scope map[string]string
@@ -33,6 +34,19 @@ type IImportMap interface {
 	// Returns the resolved URL string.
 	ResolveWithParent(specifier string, parentUrl *url.URL) (string, error)
 
+	// ResolveTrace performs a module resolution against the import map like ResolveDetailed,
+	// returning the decisions taken along the way as well: the parent and specifier, the scopes
+	// considered, the rebases of URL specifiers, the entry matched and the final URL.
+	ResolveTrace(specifier string, parentUrl *url.URL) (Resolution, []TraceStep, error)
+
+	// ResolveDetailed performs a module resolution against the import map like ResolveWithParent,
+	// also returning the entry of the map which was used.
+	ResolveDetailed(specifier string, parentUrl *url.URL) (Resolution, error)
+
+	// ResolveReverse returns the bare specifiers resolving to the given URL through the imports
+	// or any scope of the import map, sorted
+	ResolveReverse(rawUrl string) []string
+
 	// Rebase will rebase the entire import map to a new mapUrl and rootUrl
 	//
 	// Parameters:
@@ -41,6 +55,9 @@ type IImportMap interface {
 	// Returns IImportMap for chaining
 	Rebase(mapUrl *url.URL, rootUrl *url.URL) error
 
+	// GetMapUrl returns a copy of the URL the relative entries of the import map are resolved against
+	GetMapUrl() *url.URL
+
 	// Flatten groups the import map scopes to shared URLs to reduce duplicate mappings.
 	//
 	// For two given scopes, "https://site.com/x/" and "https://site.com/y/",
@@ -84,13 +101,23 @@ type IImportMap interface {
 	// Returns the integrity value, error if there was an error.
 	GetIntegrityValue(target string, integrity string) (string, error)
 
-	// SetIntegrityValue sets the integrity value of the specified target
+	// SetIntegrityValue sets the integrity value of the specified target.
+	// Malformed values are rejected with an *IntegrityError.
 	//
 	// Parameters:
 	//   - target: The target to set the integrity value for
 	//   - integrity: The integrity value to be set
 	SetIntegrityValue(target string, integrity string) error
 
+	// AddIntegrityFromFiles computes the integrity of the local files the targets of the map point
+	// at and adds it to the integrity section. Root-relative targets and targets on the origin of
+	// the map are looked up under rootDir, file URLs at their path. Missing files are skipped.
+	//
+	// Parameters:
+	//   - rootDir: The directory the root of the map's origin is served from
+	//   - algorithm: sha256, sha384 or sha512, sha384 when empty
+	AddIntegrityFromFiles(rootDir string, algorithm string) error
+
 	// Set will set a specific entry in the import map.
 	Set(name string, target string) IImportMap
 
@@ -100,21 +127,97 @@ type IImportMap interface {
 	// Extend will extend the import map with another import map
 	Extend(importMap IImportMap, overrideScopes bool) (IImportMap, error)
 
-	// Clone will clone the import map
+	// ExtendStrict extends the import map with another import map like Extend, merging scopes,
+	// unless the maps map a specifier or integrity target differently. The conflicts are then
+	// returned in a *ConflictError and the import map is left untouched.
+	ExtendStrict(importMap IImportMap) (IImportMap, error)
+
+	// Clone returns a deep copy of the import map, including its nested scopes and URLs,
+	// so modifying the copy leaves the original untouched
 	Clone() IImportMap
 
+	// ShallowClone returns a copy of the import map sharing its imports, scopes and integrity
+	// with the original, so modifying the entries of one modifies the other
+	ShallowClone() IImportMap
+
 	// GetScopes returns the scopes attribute of the import map
 	GetScopes() Scopes
 
 	// GetImports returns the imports attribute of the import map
 	GetImports() Imports
+
+	// Freeze returns a read-only copy of the import map which is safe to share across goroutines.
+	// Rebase, Extend and SetIntegrityValue of the frozen map fail with ErrFrozen, while the methods
+	// returning the map for chaining leave it untouched.
+	Freeze() IImportMap
+
+	// Thaw returns a mutable deep copy of the import map, frozen or not
+	Thaw() IImportMap
+
+	// Normalize returns a normalized copy of the import map, suitable for hashing and comparison:
+	// redundant "." and ".." segments of URLs are removed, their scheme and host lowercased and
+	// their percent-encoding made consistent, integrity values are normalized and scope entries
+	// identical to a top-level import are dropped. Unlike CanonicalForm, relative URLs stay relative.
+	Normalize() IImportMap
+
+	// CanonicalForm returns the contents of the import map with every URL-like key, scope key,
+	// integrity key and target resolved to an absolute URL. The canonical form doesn't depend on
+	// the relative or absolute representation the map was built with.
+	CanonicalForm() Data
+
+	// Diagnostics returns the problems found while loading or modifying the import map.
+	// Entries reported here were left out of the map.
+	Diagnostics() []Diagnostic
+
+	// Validate checks the import map against the rules of the import maps specification and returns
+	// the violations found, including the entries left out when loading the map: empty keys,
+	// targets which aren't URLs, keys ending with "/" mapped to targets which don't, invalid scope
+	// keys and invalid integrity entries.
+	Validate() []Diagnostic
+}
+
+// Resolution is the outcome of a module resolution
+type Resolution struct {
+	// URL is the resolved URL
+	URL string
+	// Scope is the key of the scope holding the entry used, empty for the top-level imports
+	Scope string
+	// Key is the key of the entry used, empty when the specifier is a URL without a mapping
+	Key string
+}
+
+// UnresolvedError is returned when a bare specifier has no mapping in the import map
+type UnresolvedError struct {
+	Specifier string
+	Parent    string
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("unable to resolve %s in %s", e.Specifier, e.Parent)
 }
 
+// ValidationMode controls how invalid import map entries are handled
+type ValidationMode int
+
+const (
+	// ValidationWarn leaves invalid entries out of the map and records a Diagnostic, like browsers do
+	ValidationWarn ValidationMode = iota
+	// ValidationError makes New fail with an *InvalidMapError when the map has invalid entries,
+	// resolution fail with one once Set or SetWithParent left an invalid entry out, and resolution
+	// fail when distinct scope keys resolve to the same URL
+	ValidationError
+)
+
 // Options is the configuration object for the import map service
 type Options struct {
-	Map     Data
-	MapUrl  *url.URL
-	RootUrl *url.URL
+	Map            Data
+	MapUrl         *url.URL
+	RootUrl        *url.URL
+	ValidationMode ValidationMode
+	// Environment selects the overrides of Data.Environments applied to the map
+	Environment string
+	// Conditions select the overrides of Data.Conditions applied to the map
+	Conditions []string
 }
 
 type Option func(options *Options)
@@ -124,38 +227,72 @@ type Data struct {
 	Imports   Imports   `json:"imports,omitempty"`
 	Scopes    Scopes    `json:"scopes,omitempty"`
 	Integrity Integrity `json:"integrity,omitempty"`
+	// Environments are overrides of the map for environments like "development" or "production",
+	// applied by New according to WithEnvironment. It isn't part of the import map standard and
+	// is left out of the maps given to browsers.
+	Environments map[string]Data `json:"environments,omitempty"`
+	// Conditions are overrides of the map for runtimes like "browser", "node" or "deno", like the
+	// conditions of package.json exports, applied by New according to WithConditions before the
+	// environment overrides. Like Environments, it isn't part of the import map standard.
+	Conditions map[string]Data `json:"conditions,omitempty"`
 }
 
 type importMap struct {
-	imports   Imports
-	scopes    Scopes
-	integrity Integrity
-	mapUrl    *url.URL
-	rootUrl   *url.URL
+	// mu guards the entries and URLs of the map, so it can be resolved while being modified.
+	// Shallow clones share it along with the entries.
+	mu *sync.RWMutex
+	// indexes speed up the lookups in large specifier maps, shared by shallow clones too
+	indexes *matchIndexes
+
+	imports     Imports
+	scopes      Scopes
+	integrity   Integrity
+	mapUrl      *url.URL
+	rootUrl     *url.URL
+	diagnostics []Diagnostic
+
+	validationMode ValidationMode
+	// subpathImports makes "#" specifiers resolve through the "#" keys of the imports, see
+	// AddSubpathImports
+	subpathImports bool
 }
 
 // New creates a new IImportMap instance
+//
+// The imports, scopes and integrity sections are always initialized, so the returned map can be
+// modified with Set, SetWithParent, SetIntegrityValue and Extend even when the provided Data
+// leaves some of them nil.
 func New(opts ...Option) (IImportMap, error) {
 	options := &Options{}
 
 	for _, opt := range opts {
 		opt(options)
 	}
+	options.Map = options.Map.ForConditions(options.Conditions...).ForEnvironment(options.Environment)
 
 	obj := &importMap{
-		imports:   options.Map.Imports,
-		scopes:    options.Map.Scopes,
-		integrity: options.Map.Integrity,
-		mapUrl:    options.MapUrl,
-		rootUrl:   options.RootUrl,
+		mu:             &sync.RWMutex{},
+		indexes:        &matchIndexes{},
+		integrity:      copyStringMap(options.Map.Integrity),
+		mapUrl:         options.MapUrl,
+		rootUrl:        options.RootUrl,
+		validationMode: options.ValidationMode,
+	}
+
+	obj.imports = obj.filterSpecifierMap("", options.Map.Imports)
+	obj.scopes = make(Scopes, len(options.Map.Scopes))
+	for scopeKey, scope := range options.Map.Scopes {
+		obj.scopes[scopeKey] = obj.filterSpecifierMap(scopeKey, scope)
+	}
+	obj.ensureMaps()
+
+	if options.ValidationMode == ValidationError && len(obj.diagnostics) > 0 {
+		return nil, &InvalidMapError{Diagnostics: obj.diagnostics}
 	}
 
 	if obj.mapUrl == nil {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return nil, err
-		}
-		obj.mapUrl, err = url.Parse(fmt.Sprintf("file://%s/", cwd))
+		var err error
+		obj.mapUrl, err = defaultMapUrl()
 		if err != nil {
 			return nil, err
 		}
@@ -164,10 +301,21 @@ func New(opts ...Option) (IImportMap, error) {
 	if obj.rootUrl == nil && (obj.mapUrl.Scheme == "http" || obj.mapUrl.Scheme == "https") {
 		obj.rootUrl = obj.mapUrl.ResolveReference(&url.URL{Path: "/"})
 	}
+	// an invalid scope key fails the resolutions rather than New
+	_, _ = obj.sortedScopes()
 
 	return obj, nil
 }
 
+// defaultMapUrl returns the URL of the current working directory, used when no map URL is provided.
+func defaultMapUrl() (*url.URL, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return DirURL(cwd), nil
+}
+
 func WithMap(importMap Data) Option {
 	return func(options *Options) {
 		options.Map = importMap
@@ -186,27 +334,72 @@ func WithRootUrl(rootUrl *url.URL) Option {
 	}
 }
 
+// WithValidationMode sets how invalid entries are handled. Set and SetWithParent can't fail, so they
+// always leave invalid entries out and record a Diagnostic, which makes the resolutions of the map
+// fail in ValidationError mode.
+func WithValidationMode(mode ValidationMode) Option {
+	return func(options *Options) {
+		options.ValidationMode = mode
+	}
+}
+
 func (i *importMap) Clone() IImportMap {
+	return deepCopy(i)
+}
+
+func (i *importMap) ShallowClone() IImportMap {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	return &importMap{
-		imports:   i.imports,
-		scopes:    i.scopes,
-		integrity: i.integrity,
-		mapUrl:    i.mapUrl,
-		rootUrl:   i.rootUrl,
+		mu:          i.mu,
+		indexes:     i.indexes,
+		imports:     i.imports,
+		scopes:      i.scopes,
+		integrity:   i.integrity,
+		mapUrl:      i.mapUrl,
+		rootUrl:     i.rootUrl,
+		diagnostics: i.diagnostics,
+
+		validationMode: i.validationMode,
+		subpathImports: i.subpathImports,
+	}
+}
+
+// ensureMaps initializes the sections which are nil.
+func (i *importMap) ensureMaps() {
+	if i.imports == nil {
+		i.imports = make(Imports)
+	}
+	if i.scopes == nil {
+		i.scopes = make(Scopes)
+	}
+	if i.integrity == nil {
+		i.integrity = make(Integrity)
 	}
 }
 
 func (i *importMap) Extend(importMap IImportMap, overrideScopes bool) (IImportMap, error) {
-	for k, v := range importMap.GetImports() {
+	// the entries are copied first, importMap may share the lock of i
+	other := deepCopy(importMap)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.indexes.reset()
+	i.ensureMaps()
+
+	for k, v := range other.imports {
 		i.imports[k] = v
 	}
 
 	if overrideScopes {
-		for k, v := range importMap.GetScopes() {
+		for k, v := range other.scopes {
+			if v == nil {
+				v = make(Scope)
+			}
 			i.scopes[k] = v
 		}
-	} else if importMap.GetScopes() != nil {
-		for scopeKey, scope := range importMap.GetScopes() {
+	} else {
+		for scopeKey, scope := range other.scopes {
 			if _, ok := i.scopes[scopeKey]; !ok {
 				i.scopes[scopeKey] = make(Scope)
 			}
@@ -217,10 +410,10 @@ func (i *importMap) Extend(importMap IImportMap, overrideScopes bool) (IImportMa
 		}
 	}
 
-	for k, v := range importMap.GetIntegrity() {
+	for k, v := range other.integrity {
 		i.integrity[k] = v
 	}
-	err := i.Rebase(i.mapUrl, nil)
+	err := i.rebase(i.mapUrl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -229,12 +422,28 @@ func (i *importMap) Extend(importMap IImportMap, overrideScopes bool) (IImportMa
 
 // Set implements the IImportMap interface
 func (i *importMap) Set(name string, target string) IImportMap {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.indexes.reset()
+	i.ensureMaps()
+	if msg := checkEntry(name, target); msg != "" {
+		i.diagnostics = append(i.diagnostics, Diagnostic{Key: name, Message: msg})
+		return i
+	}
 	i.imports[name] = target
 	return i
 }
 
 // SetWithParent implements the IImportMap interface
 func (i *importMap) SetWithParent(name string, target string, parent string) IImportMap {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.indexes.reset()
+	i.ensureMaps()
+	if msg := checkEntry(name, target); msg != "" {
+		i.diagnostics = append(i.diagnostics, Diagnostic{Scope: parent, Key: name, Message: msg})
+		return i
+	}
 	if i.scopes[parent] == nil {
 		i.scopes[parent] = make(Scope)
 	}
@@ -242,18 +451,41 @@ func (i *importMap) SetWithParent(name string, target string, parent string) IIm
 	return i
 }
 
-// GetScopes implements the IImportMap interface
+// GetScopes implements the IImportMap interface, returning a copy of the scopes taken under the
+// lock, so they can be iterated while the map is modified
 func (i *importMap) GetScopes() Scopes {
-	return i.scopes
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return copyScopes(i.scopes)
 }
 
-// GetImports implements the IImportMap interface
+// GetImports implements the IImportMap interface, returning a copy of the imports taken under the
+// lock, so they can be iterated while the map is modified
 func (i *importMap) GetImports() Imports {
-	return i.imports
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return copyStringMap(i.imports)
+}
+
+// GetMapUrl implements the IImportMap interface
+func (i *importMap) GetMapUrl() *url.URL {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	mapUrl := *i.mapUrl
+	return &mapUrl
+}
+
+// Diagnostics implements the IImportMap interface
+func (i *importMap) Diagnostics() []Diagnostic {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.diagnostics
 }
 
 // GetIntegrityValue implements the IImportMap interface
 func (i *importMap) GetIntegrityValue(target string, _ string) (string, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	targetRebased, err := rebase(target, i.mapUrl, i.rootUrl)
 	if err != nil {
 		return "", err
@@ -271,6 +503,14 @@ func (i *importMap) GetIntegrityValue(target string, _ string) (string, error) {
 
 // SetIntegrityValue implements the IImportMap interface
 func (i *importMap) SetIntegrityValue(target string, integrity string) error {
+	integrity, err := NormalizeIntegrity(integrity)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.ensureMaps()
 	i.integrity[target] = integrity
 	targetRebased, err := rebase(target, i.mapUrl, i.rootUrl)
 	if err != nil {
@@ -292,12 +532,20 @@ func (i *importMap) SetIntegrityValue(target string, integrity string) error {
 
 // Rebase is an implementation of the IImportMap interface.
 func (i *importMap) Rebase(mapUrl *url.URL, rootUrl *url.URL) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rebase(mapUrl, rootUrl)
+}
+
+// rebase implements Rebase, the caller holding the lock.
+func (i *importMap) rebase(mapUrl *url.URL, rootUrl *url.URL) error {
 	if mapUrl == nil {
 		return errors.New("invalid argument: mapUrl is nil")
 	}
+	i.indexes.reset()
 	if rootUrl == nil && i.mapUrl != nil {
 		if mapUrl.String() == i.mapUrl.String() {
-			rootUrl = i.mapUrl
+			rootUrl = i.rootUrl
 		} else {
 			if i.rootUrl == nil || (mapUrl.Scheme != "https" && mapUrl.Scheme != "http") {
 				rootUrl = nil
@@ -307,104 +555,65 @@ func (i *importMap) Rebase(mapUrl *url.URL, rootUrl *url.URL) error {
 		}
 	}
 
-	for importKey, target := range i.imports {
-		resolvedTarget, err := resolve(target, i.mapUrl, i.rootUrl)
+	rebaseUrl := func(target string) (string, error) {
+		resolved, err := resolve(target, i.mapUrl, i.rootUrl)
 		if err != nil {
-			return err
-		}
-		i.imports[importKey], err = rebase(resolvedTarget, i.mapUrl, i.rootUrl)
-		if err != nil {
-			return err
-		}
-
-		if !isPlain(importKey) {
-			resolvedImportKey, resolvErr := resolve(importKey, i.mapUrl, i.rootUrl)
-			if resolvErr != nil {
-				return resolvErr
-			}
-			newImport, rebaseErr := rebase(resolvedImportKey, mapUrl, rootUrl)
-			if rebaseErr != nil {
-				return rebaseErr
-			}
-
-			if newImport != importKey {
-				i.imports[newImport] = i.imports[importKey]
-				delete(i.imports, importKey)
-			}
+			return "", err
 		}
+		return rebase(resolved, mapUrl, rootUrl)
 	}
 
-	for scopeKey, scopeImports := range i.scopes {
-		changedScopeImportProps := false
-		for importKey, target := range scopeImports {
-			resolvedTarget, err := resolve(target, i.mapUrl, i.rootUrl)
+	rebaseSpecifierMap := func(specifierMap map[string]string) (map[string]string, error) {
+		result := make(map[string]string, len(specifierMap))
+		for importKey, target := range specifierMap {
+			newTarget, err := rebaseUrl(target)
 			if err != nil {
-				return err
-			}
-			scopeImports[importKey], err = rebase(resolvedTarget, i.mapUrl, i.rootUrl)
-			if err != nil {
-				return err
+				return nil, err
 			}
+			newImportKey := importKey
 			if !isPlain(importKey) {
-				resolvedImportKey, resolvErr := resolve(importKey, i.mapUrl, i.rootUrl)
-				if resolvErr != nil {
-					return resolvErr
-				}
-				newImport, rebaseErr := rebase(resolvedImportKey, mapUrl, rootUrl)
-				if rebaseErr != nil {
-					return rebaseErr
-				}
-
-				if newImport != importKey {
-					changedScopeImportProps = true
-					scopeImports[newImport] = scopeImports[importKey]
-					delete(scopeImports, importKey)
+				newImportKey, err = rebaseUrl(importKey)
+				if err != nil {
+					return nil, err
 				}
 			}
+			result[newImportKey] = newTarget
 		}
+		return result, nil
+	}
 
-		if changedScopeImportProps {
-			i.scopes[scopeKey] = scopeImports
-		}
+	imports, err := rebaseSpecifierMap(i.imports)
+	if err != nil {
+		return err
+	}
 
-		resolvedScopeKey, err := resolve(scopeKey, i.mapUrl, i.rootUrl)
-		if err != nil {
-			return err
-		}
-		newScope, rebaseErr := rebase(resolvedScopeKey, mapUrl, rootUrl)
+	scopes := make(Scopes, len(i.scopes))
+	for scopeKey, scopeImports := range i.scopes {
+		newScopeKey, rebaseErr := rebaseUrl(scopeKey)
 		if rebaseErr != nil {
 			return rebaseErr
 		}
-
-		if newScope != scopeKey {
-			i.scopes[newScope] = i.scopes[scopeKey]
-			delete(i.scopes, scopeKey)
+		scopes[newScopeKey], rebaseErr = rebaseSpecifierMap(scopeImports)
+		if rebaseErr != nil {
+			return rebaseErr
 		}
 	}
 
-	for integrityKey, integrityValue := range i.integrity {
-		resolvedIntegrityValue, err := resolve(integrityValue, i.mapUrl, i.rootUrl)
-		if err != nil {
-			return err
-		}
-		i.integrity[integrityKey], err = rebase(resolvedIntegrityValue, i.mapUrl, i.rootUrl)
-		if err != nil {
-			return err
-		}
-		if integrityKey != integrityValue {
-			i.integrity[integrityKey] = i.integrity[integrityValue]
-			delete(i.integrity, integrityValue)
+	integrity := make(Integrity, len(i.integrity))
+	for target, integrityValue := range i.integrity {
+		newTarget, rebaseErr := rebaseUrl(target)
+		if rebaseErr != nil {
+			return rebaseErr
 		}
+		integrity[newTarget] = integrityValue
 	}
 
+	i.imports = imports
+	i.scopes = scopes
+	i.integrity = integrity
 	i.mapUrl = mapUrl
 	i.rootUrl = rootUrl
-	return nil
-}
-
-// Flatten is an implementation of the IImportMap interface.
-func (i *importMap) Flatten() IImportMap {
-	// todo: implement
+	_, _ = i.sortedScopes()
 	return nil
 }
 
@@ -419,81 +628,160 @@ func (i *importMap) Replace(_ url.URL, _ url.URL) IImportMap {
 }
 
 func (i *importMap) Resolve(specifier string) (string, error) {
-	return i.ResolveWithParent(specifier, i.mapUrl)
+	return i.ResolveWithParent(specifier, i.GetMapUrl())
 }
 
 func (i *importMap) ResolveWithParent(specifier string, parentUrl *url.URL) (string, error) {
+	resolution, err := i.ResolveDetailed(specifier, parentUrl)
+	if err != nil {
+		return "", err
+	}
+	return resolution.URL, nil
+}
+
+func (i *importMap) ResolveDetailed(specifier string, parentUrl *url.URL) (Resolution, error) {
+	return i.resolveDetailed(specifier, parentUrl, nil)
+}
+
+// resolveDetailed implements ResolveDetailed, recording its decisions in trace when not nil
+func (i *importMap) resolveDetailed(specifier string, parentUrl *url.URL, trace *tracer) (Resolution, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
 	parentUrlRaw, err := resolve(parentUrl.String(), i.mapUrl, i.rootUrl)
 
 	if err != nil {
-		return "", err
+		return Resolution{}, err
 	}
+	trace.add(TraceParent, "parent %s", parentUrlRaw)
 
-	var specifierUrl *url.URL
-	if !isPlain(specifier) {
-		u, urlParseErr := url.Parse(specifier)
-		if urlParseErr != nil {
-			return "", urlParseErr
-		}
-		specifierUrl = parentUrl.ResolveReference(u)
+	// in ValidationError mode New fails for invalid entries, so these were left out by Set
+	if i.validationMode == ValidationError && len(i.diagnostics) > 0 {
+		err = &InvalidMapError{Diagnostics: append([]Diagnostic(nil), i.diagnostics...)}
+		trace.result("", err)
+		return Resolution{}, err
+	}
+
+	// the "#" keys are plain, so with subpath imports the "#" specifiers matching one are bare
+	kind, specifierUrl := SpecifierBare, (*url.URL)(nil)
+	if !i.subpathImports || !strings.HasPrefix(specifier, "#") || i.importsMatch(specifier) == "" {
+		kind, specifierUrl = ParseSpecifier(specifier, parentUrl)
+	}
+	if kind != SpecifierBare {
 		specifier = specifierUrl.String()
+		trace.add(TraceSpecifier, "URL-like specifier resolved to %s", specifier)
+	} else {
+		trace.add(TraceSpecifier, "bare specifier %q", specifier)
 	}
 
-	scopeMatches, err := getScopeMatches(parentUrlRaw, i.scopes, i.mapUrl, i.rootUrl)
+	scopeCandidates, err := i.sortedScopes()
 	if err != nil {
-		return "", err
+		return Resolution{}, err
+	}
+	scopeMatches, err := getScopeMatches(parentUrlRaw, scopeCandidates, i.validationMode == ValidationError)
+	if err != nil {
+		return Resolution{}, err
+	}
+	if len(scopeMatches) == 0 && len(i.scopes) > 0 {
+		trace.add(TraceScope, "none of the %d scopes contains the parent", len(i.scopes))
 	}
 
 	for _, scopeMatch := range scopeMatches {
-		mapMatch := getMapMatch(specifier, i.scopes[scopeMatch.First])
+		trace.add(TraceScope, "scope %q contains the parent", scopeMatch.First)
+		mapMatch := i.scopeMatch(specifier, scopeMatch.First)
 		if mapMatch == "" && specifierUrl != nil {
-			specifier, err = rebase(specifier, i.mapUrl, i.rootUrl)
+			specifier, err = i.traceRebase(specifier, i.rootUrl, trace)
 			if err != nil {
-				return "", err
+				return Resolution{}, err
 			}
-			mapMatch = getMapMatch(specifier, i.scopes[scopeMatch.First])
+			mapMatch = i.scopeMatch(specifier, scopeMatch.First)
 			if mapMatch == "" && i.rootUrl != nil {
-				specifier, err = rebase(specifier, i.mapUrl, nil)
+				specifier, err = i.traceRebase(specifier, nil, trace)
 				if err != nil {
-					return "", err
+					return Resolution{}, err
 				}
-				mapMatch = getMapMatch(specifier, i.scopes[scopeMatch.First])
+				mapMatch = i.scopeMatch(specifier, scopeMatch.First)
 			}
 		}
 		if mapMatch != "" {
-			target := i.scopes[scopeMatch.First][mapMatch]
-			return resolve(target+specifier[len(mapMatch):], i.mapUrl, i.rootUrl)
+			trace.add(TraceMatch, "scope %q maps %q -> %q", scopeMatch.First, mapMatch, i.scopes[scopeMatch.First][mapMatch])
+			resolved, err := i.resolveMatch(specifier, mapMatch, i.scopes[scopeMatch.First][mapMatch])
+			trace.result(resolved, err)
+			return Resolution{URL: resolved, Scope: scopeMatch.First, Key: mapMatch}, err
 		}
+		trace.add(TraceScope, "scope %q has no entry for the specifier", scopeMatch.First)
 	}
-	mapMatch := getMapMatch(specifier, i.imports)
+	mapMatch := i.importsMatch(specifier)
 	if mapMatch == "" && specifierUrl != nil {
-		specifier, err = rebase(specifier, i.mapUrl, i.rootUrl)
+		specifier, err = i.traceRebase(specifier, i.rootUrl, trace)
 		if err != nil {
-			return "", err
+			return Resolution{}, err
 		}
-		mapMatch = getMapMatch(specifier, i.imports)
+		mapMatch = i.importsMatch(specifier)
 		if mapMatch == "" && i.rootUrl != nil {
-			specifier, err = rebase(specifier, i.mapUrl, nil)
+			specifier, err = i.traceRebase(specifier, nil, trace)
 			if err != nil {
-				return "", err
+				return Resolution{}, err
 			}
-			mapMatch = getMapMatch(specifier, i.imports)
+			mapMatch = i.importsMatch(specifier)
 		}
 	}
 
 	if mapMatch != "" {
-		target := i.imports[mapMatch]
-		return resolve(target+specifier[len(mapMatch):], i.mapUrl, i.rootUrl)
+		trace.add(TraceMatch, "imports map %q -> %q", mapMatch, i.imports[mapMatch])
+		resolved, err := i.resolveMatch(specifier, mapMatch, i.imports[mapMatch])
+		trace.result(resolved, err)
+		return Resolution{URL: resolved, Key: mapMatch}, err
 	}
 
 	if specifierUrl != nil {
-		return specifierUrl.String(), nil
+		trace.add(TraceMatch, "no entry for the URL, it is used as is")
+		trace.result(specifierUrl.String(), nil)
+		return Resolution{URL: specifierUrl.String()}, nil
+	}
+	err = &UnresolvedError{Specifier: specifier, Parent: parentUrl.String()}
+	trace.result("", err)
+	return Resolution{}, err
+}
+
+// resolveMatch applies the mapping mapMatch -> target to specifier.
+func (i *importMap) resolveMatch(specifier string, mapMatch string, target string) (string, error) {
+	if strings.HasSuffix(mapMatch, "/") && !strings.HasSuffix(target, "/") {
+		return "", fmt.Errorf("invalid target %q for %q: targets of keys ending with \"/\" must end with \"/\"", target, mapMatch)
 	}
-	return "", fmt.Errorf("unable to resolve %s in %s", specifier, parentUrl.String())
+
+	if strings.HasSuffix(mapMatch, "*") {
+		// the part of the specifier matched by "*" replaces the "*" of the target, or is appended to it
+		captured := specifier[len(mapMatch)-1:]
+		if strings.Contains(target, "*") {
+			return resolve(strings.ReplaceAll(target, "*", captured), i.mapUrl, i.rootUrl)
+		}
+		return resolve(target+captured, i.mapUrl, i.rootUrl)
+	}
+
+	resolved, err := resolve(target+specifier[len(mapMatch):], i.mapUrl, i.rootUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasSuffix(mapMatch, "/") {
+		resolvedTarget, resolveErr := resolve(target, i.mapUrl, i.rootUrl)
+		if resolveErr != nil {
+			return "", resolveErr
+		}
+		if !strings.HasPrefix(resolved, resolvedTarget) {
+			return "", fmt.Errorf("resolution of %q backtracks above its target %q", specifier, target)
+		}
+	}
+
+	return resolved, nil
 }
 
+// GetIntegrity implements the IImportMap interface, returning a copy of the integrity values taken
+// under the lock, so they can be iterated while the map is modified
 func (i *importMap) GetIntegrity() Integrity {
-	return i.integrity
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return copyStringMap(i.integrity)
 }
 
 type scopeMatchTuple struct {
@@ -501,7 +789,8 @@ type scopeMatchTuple struct {
 	Second string
 }
 
-func getScopeMatches(parentUrl string, scopes Scopes, mapUrl *url.URL, rootUrl *url.URL) ([]scopeMatchTuple, error) {
+// sortScopes resolves the scope keys against the map URL, most specific first.
+func sortScopes(scopes Scopes, mapUrl *url.URL, rootUrl *url.URL) ([]scopeMatchTuple, error) {
 	scopeCandidates := make([]scopeMatchTuple, 0, len(scopes))
 	for scope := range scopes {
 		scopeUrl, err := resolve(scope, mapUrl, rootUrl)
@@ -510,18 +799,33 @@ func getScopeMatches(parentUrl string, scopes Scopes, mapUrl *url.URL, rootUrl *
 		}
 		scopeCandidates = append(scopeCandidates, scopeMatchTuple{
 			First:  scope,
-			Second: scopeUrl,
+			Second: normalizeUrlString(scopeUrl),
 		})
 	}
 
+	// the most specific scope comes first; scope keys resolving to the same URL are ordered
+	// lexicographically, so the outcome never depends on map iteration order
 	sort.Slice(scopeCandidates, func(i, j int) bool {
-		return len(scopeCandidates[i].Second) < len(scopeCandidates[j].Second)
+		if len(scopeCandidates[i].Second) != len(scopeCandidates[j].Second) {
+			return len(scopeCandidates[i].Second) > len(scopeCandidates[j].Second)
+		}
+		return scopeCandidates[i].First < scopeCandidates[j].First
 	})
+	return scopeCandidates, nil
+}
+
+// getScopeMatches returns the scopes of scopeCandidates, sorted by sortScopes, matching parentUrl.
+// In strict mode distinct scope keys resolving to the same URL are reported as an error.
+func getScopeMatches(parentUrl string, scopeCandidates []scopeMatchTuple, strict bool) ([]scopeMatchTuple, error) {
+	parentUrl = normalizeUrlString(parentUrl)
 
 	var result []scopeMatchTuple
 	for _, candidate := range scopeCandidates {
 		scopeUrl := candidate.Second
 		if scopeUrl == parentUrl || (strings.HasSuffix(scopeUrl, "/") && strings.HasPrefix(parentUrl, scopeUrl)) {
+			if strict && len(result) > 0 && result[len(result)-1].Second == scopeUrl {
+				return nil, fmt.Errorf("ambiguous scopes %q and %q both resolve to %s", result[len(result)-1].First, candidate.First, scopeUrl)
+			}
 			result = append(result, candidate)
 		}
 	}
@@ -540,7 +844,7 @@ func getMapMatch[T any](specifier string, inputMap map[string]T) string {
 			continue
 		}
 		if strings.HasPrefix(specifier, match[:len(match)-1]) {
-			if curMatch == "" || len(match) > len(curMatch) {
+			if curMatch == "" || len(match) > len(curMatch) || (len(match) == len(curMatch) && match < curMatch) {
 				curMatch = match
 			}
 		}
@@ -1,11 +1,16 @@
 package importmap
 
 import (
+	"errors"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
+// emptySha384 is the integrity value of an empty file
+const emptySha384 = "sha384-OLBgp1GsljhM2TJ+sbHjaiH9txEUvgdDTAzHv2P24donTt6/529l+9Ua0vFImLlb"
+
 func TestResolveImportMap(t *testing.T) {
 	baseUrlRaw := "https://site.com"
 	baseUrl, _ := url.Parse(baseUrlRaw)
@@ -76,3 +81,300 @@ func assertUrlsEqualsU(m IImportMap, inputUrl string, baseUrl *url.URL, expected
 		t.Errorf("expected %s, got %s", expectedUrl, result)
 	}
 }
+
+func TestInvalidKeysAreDropped(t *testing.T) {
+	m, err := New(WithMap(Data{
+		Imports: Imports{
+			"":           "/empty.js",
+			`pkg\sub.js`: "/sub.js",
+			"pkg":        "/pkg.js",
+		},
+		Scopes: Scopes{
+			"/scope/": {`a\b`: "/ab.js"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Set(`x\y`, "/xy.js")
+
+	if len(m.GetImports()) != 1 {
+		t.Errorf("expected only the valid import to remain, got %v", m.GetImports())
+	}
+	if len(m.GetScopes()["/scope/"]) != 0 {
+		t.Errorf("expected the scoped entry to be dropped, got %v", m.GetScopes()["/scope/"])
+	}
+	if len(m.Diagnostics()) != 4 {
+		t.Errorf("expected 4 diagnostics, got %v", m.Diagnostics())
+	}
+}
+
+func TestTrailingSlashTargets(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	data := Data{
+		Imports: Imports{
+			"pkg/":    "/pkg.js",
+			"better/": "/better/",
+		},
+	}
+
+	m, err := New(WithMapUrl(baseUrl), WithMap(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.GetImports()["pkg/"]; ok {
+		t.Error("expected pkg/ to be dropped")
+	}
+	if len(m.Diagnostics()) != 1 {
+		t.Errorf("expected 1 diagnostic, got %v", m.Diagnostics())
+	}
+
+	if _, err = New(WithMapUrl(baseUrl), WithMap(data), WithValidationMode(ValidationError)); err == nil {
+		t.Error("expected an error in ValidationError mode")
+	}
+
+	assertUrlsEqualsU(m, "better/a.js", baseUrl, "https://site.com/better/a.js", t)
+	if _, err = m.ResolveWithParent("better/../../secret.js", baseUrl); err == nil {
+		t.Error("expected backtracking above the target to fail")
+	}
+}
+
+func TestValidationModes(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "importmap.json")
+	if err := os.WriteFile(path, []byte(`{"imports": {"pkg/": "/pkg.js", "better/": "/better/"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// LoadFromFile drops the invalid entries or fails
+	m, err := LoadFromFile(path, WithMapUrl(baseUrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Diagnostics()) != 1 {
+		t.Errorf("expected 1 diagnostic, got %v", m.Diagnostics())
+	}
+	var invalid *InvalidMapError
+	if _, err = LoadFromFile(path, WithMapUrl(baseUrl), WithValidationMode(ValidationError)); !errors.As(err, &invalid) {
+		t.Errorf("expected an *InvalidMapError in ValidationError mode, got %v", err)
+	}
+
+	for _, mode := range []ValidationMode{ValidationWarn, ValidationError} {
+		set, _ := New(WithMapUrl(baseUrl), WithMap(Data{Imports: Imports{"a": "/a.js"}}), WithValidationMode(mode))
+		set.Set("pkg/", "/pkg.js")
+		scoped, _ := New(WithMapUrl(baseUrl), WithMap(Data{Imports: Imports{"a": "/a.js"}}), WithValidationMode(mode))
+		scoped.SetWithParent("pkg/", "/pkg.js", "/x/")
+
+		for _, m := range []IImportMap{set, scoped} {
+			if len(m.Diagnostics()) != 1 {
+				t.Errorf("expected the invalid entry to be reported, got %v", m.Diagnostics())
+			}
+			_, err = m.ResolveWithParent("a", baseUrl)
+			if mode == ValidationWarn && err != nil {
+				t.Errorf("expected the invalid entry to be left out in ValidationWarn mode, got %v", err)
+			}
+			if mode == ValidationError && !errors.As(err, &invalid) {
+				t.Errorf("expected an *InvalidMapError in ValidationError mode, got %v", err)
+			}
+		}
+
+		// resolutions backtracking above their target fail in both modes
+		m, _ := New(WithMapUrl(baseUrl), WithMap(Data{Imports: Imports{"better/": "/better/"}}), WithValidationMode(mode))
+		if _, err = m.ResolveWithParent("better/../../secret.js", baseUrl); err == nil {
+			t.Errorf("expected backtracking above the target to fail in mode %v", mode)
+		}
+	}
+}
+
+func TestNewInitializesSections(t *testing.T) {
+	m, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Set("a", "/a.js")
+	m.SetWithParent("b", "/b.js", "/scope/")
+	if err = m.SetIntegrityValue("/a.js", emptySha384); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := New(WithMap(Data{Scopes: Scopes{"/other/": nil}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Extend(other, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = other.Extend(m, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(other.GetImports()) != 1 {
+		t.Errorf("expected the import to be carried over, got %v", other.GetImports())
+	}
+}
+
+func TestScopeMatchOrder(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	data := Data{
+		Imports: Imports{"dep": "/dep.js"},
+		Scopes: Scopes{
+			"/a/":                   {"dep": "/dep-a.js"},
+			"/a/b/":                 {"dep": "/dep-ab.js"},
+			"https://site.com/a/b/": {"dep": "/dep-ab-absolute.js"},
+		},
+	}
+
+	m, _ := New(WithMapUrl(baseUrl), WithMap(data))
+	for n := 0; n < 20; n++ {
+		assertUrlsEquals(m, "dep", "https://site.com/a/b/c.js", "https://site.com/dep-ab.js", t)
+	}
+	assertUrlsEquals(m, "dep", "https://site.com/a/c.js", "https://site.com/dep-a.js", t)
+
+	strict, _ := New(WithMapUrl(baseUrl), WithMap(data), WithValidationMode(ValidationError))
+	parentUrl, _ := url.Parse("https://site.com/a/b/c.js")
+	if _, err := strict.ResolveWithParent("dep", parentUrl); err == nil {
+		t.Error("expected ambiguous scopes to fail in strict mode")
+	}
+}
+
+func TestFragmentAndQueryOnlySpecifiers(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(baseUrl), WithMap(Data{
+		Imports: Imports{
+			"#foo":                           "/bare-foo.js",
+			"?v=2":                           "/bare-query.js",
+			"https://site.com/mapped.js?v=2": "/mapped-v2.js",
+		},
+	}))
+
+	assertUrlsEquals(m, "#foo", "https://site.com/app/main.js?x=1", "https://site.com/app/main.js?x=1#foo", t)
+	assertUrlsEquals(m, "?v=3", "https://site.com/app/main.js#frag", "https://site.com/app/main.js?v=3", t)
+	assertUrlsEquals(m, "?v=2", "https://site.com/mapped.js", "https://site.com/mapped-v2.js", t)
+
+	// the keys are plain, so they are kept as they are
+	other, _ := New(WithMapUrl(baseUrl), WithMap(Data{Imports: Imports{"#bar": "/bar.js"}}))
+	if _, err := m.Extend(other, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rebase(&url.URL{Scheme: "https", Host: "site.com", Path: "/app/"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"#foo", "#bar", "?v=2"} {
+		if _, ok := m.GetImports()[key]; !ok {
+			t.Errorf("expected the key %q to be kept, got %v", key, m.GetImports())
+		}
+	}
+}
+
+func TestResolveDetailed(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(baseUrl), WithMap(Data{
+		Imports: Imports{
+			"lib/": "/lib/",
+		},
+		Scopes: Scopes{
+			"/admin/": {"lib/": "/admin-lib/"},
+		},
+	}))
+
+	parentUrl, _ := url.Parse("https://site.com/app.js")
+	resolution, err := m.ResolveDetailed("lib/a.js", parentUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolution != (Resolution{URL: "https://site.com/lib/a.js", Key: "lib/"}) {
+		t.Errorf("unexpected resolution %+v", resolution)
+	}
+
+	parentUrl, _ = url.Parse("https://site.com/admin/app.js")
+	resolution, err = m.ResolveDetailed("lib/a.js", parentUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolution != (Resolution{URL: "https://site.com/admin-lib/a.js", Scope: "/admin/", Key: "lib/"}) {
+		t.Errorf("unexpected resolution %+v", resolution)
+	}
+
+	resolution, err = m.ResolveDetailed("https://cdn.com/x.js", parentUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolution != (Resolution{URL: "https://cdn.com/x.js"}) {
+		t.Errorf("unexpected resolution %+v", resolution)
+	}
+}
+
+func TestCloneIsDeep(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports:   Imports{"a": "/a.js"},
+		Scopes:    Scopes{"/x/": {"a": "/x-a.js"}},
+		Integrity: Integrity{"/a.js": emptySha384},
+	}))
+
+	clone := m.Clone()
+	clone.Set("b", "/b.js")
+	clone.SetWithParent("a", "/changed.js", "/x/")
+	if err := clone.SetIntegrityValue("/a.js", "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.Rebase(&url.URL{Scheme: "https", Host: "other.com", Path: "/"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m.GetImports()["b"]; ok {
+		t.Error("expected the imports of the original to be left untouched")
+	}
+	if m.GetScopes()["/x/"]["a"] != "/x-a.js" {
+		t.Error("expected the scopes of the original to be left untouched")
+	}
+	if m.GetIntegrity()["/a.js"] != emptySha384 {
+		t.Error("expected the integrity of the original to be left untouched")
+	}
+	assertUrlsEquals(m, "a", "https://site.com/index.js", "https://site.com/a.js", t)
+
+	shallow := m.ShallowClone()
+	shallow.Set("c", "/c.js")
+	if _, ok := m.GetImports()["c"]; !ok {
+		t.Error("expected the shallow clone to share the imports of the original")
+	}
+}
+
+func TestGetMapUrl(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/maps/importmap.json")
+	m, _ := New(WithMapUrl(mapUrl))
+
+	got := m.GetMapUrl()
+	if got.String() != mapUrl.String() {
+		t.Errorf("expected %s, got %s", mapUrl, got)
+	}
+	got.Path = "/changed.json"
+	if m.GetMapUrl().String() != mapUrl.String() {
+		t.Error("expected the map URL of the import map to be left untouched")
+	}
+}
+
+func TestWildcardTargets(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"pkg/*":  "https://cdn.example/pkg/*/index.js",
+			"plain*": "/plain/",
+		},
+		Scopes: Scopes{
+			"/legacy/": {"pkg/*": "./legacy/*.js"},
+		},
+	}))
+
+	assertUrlsEquals(m, "pkg/button", "https://site.com/app.js", "https://cdn.example/pkg/button/index.js", t)
+	assertUrlsEquals(m, "pkg/forms/input", "https://site.com/app.js", "https://cdn.example/pkg/forms/input/index.js", t)
+	assertUrlsEquals(m, "plain-a.js", "https://site.com/app.js", "https://site.com/plain/-a.js", t)
+	assertUrlsEquals(m, "pkg/button", "https://site.com/legacy/app.js", "https://site.com/legacy/button.js", t)
+
+	if reverse := m.ResolveReverse("https://cdn.example/pkg/button/index.js"); len(reverse) != 1 || reverse[0] != "pkg/button" {
+		t.Errorf("unexpected reverse resolution %v", reverse)
+	}
+}
@@ -0,0 +1,166 @@
+package importmap
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// indexThreshold is the number of entries from which a specifier map is indexed; smaller maps
+// are scanned, which is faster than building an index for them.
+const indexThreshold = 32
+
+// matchIndex is a sorted index of the prefix keys of a specifier map, the keys ending with "/"
+// or "*", for longest-prefix lookups without scanning the map.
+type matchIndex struct {
+	// size is the number of entries of the indexed map, the index is rebuilt when it changes
+	size int
+	// prefixes are the keys without their last character, sorted
+	prefixes []string
+	// keys are the keys of the prefixes, the lowest one when "x/" and "x*" share a prefix
+	keys []string
+	// parents are the positions of the longest other prefix each prefix starts with, or -1
+	parents []int
+}
+
+func newMatchIndex(specifierMap map[string]string) *matchIndex {
+	keys := make(map[string]string)
+	for key := range specifierMap {
+		if !strings.HasSuffix(key, "/") && !strings.HasSuffix(key, "*") {
+			continue
+		}
+		prefix := key[:len(key)-1]
+		if existing, ok := keys[prefix]; !ok || key < existing {
+			keys[prefix] = key
+		}
+	}
+
+	index := &matchIndex{size: len(specifierMap)}
+	for prefix := range keys {
+		index.prefixes = append(index.prefixes, prefix)
+	}
+	sort.Strings(index.prefixes)
+
+	// the prefixes a prefix starts with precede it, the stack holds the chain of the last one
+	var stack []int
+	for pos, prefix := range index.prefixes {
+		index.keys = append(index.keys, keys[prefix])
+		for len(stack) > 0 && !strings.HasPrefix(prefix, index.prefixes[stack[len(stack)-1]]) {
+			stack = stack[:len(stack)-1]
+		}
+		parent := -1
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		index.parents = append(index.parents, parent)
+		stack = append(stack, pos)
+	}
+	return index
+}
+
+// match returns the key with the longest prefix of specifier, like getMapMatch without the exact
+// matches. The longest prefix of specifier is either the greatest prefix sorting before it or
+// one of the prefixes that one starts with.
+func (x *matchIndex) match(specifier string) string {
+	pos := sort.SearchStrings(x.prefixes, specifier)
+	if pos == len(x.prefixes) || x.prefixes[pos] != specifier {
+		pos--
+	}
+	for pos >= 0 && !strings.HasPrefix(specifier, x.prefixes[pos]) {
+		pos = x.parents[pos]
+	}
+	if pos < 0 {
+		return ""
+	}
+	return x.keys[pos]
+}
+
+// matchIndexes holds the indexes of the imports and scopes of a map, built on the first lookup,
+// and its scope keys resolved and sorted by sortScopes. Shallow clones share them along with the
+// entries, and the methods modifying the entries reset them.
+type matchIndexes struct {
+	mu      sync.Mutex
+	imports *matchIndex
+	scopes  map[string]*matchIndex
+
+	// sorted tells whether scopeCandidates and scopeErr are the outcome of sortScopes for the
+	// scopeCount scopes of the map
+	sorted          bool
+	scopeCount      int
+	scopeCandidates []scopeMatchTuple
+	scopeErr        error
+}
+
+// reset drops the indexes, the caller holding the write lock of the map.
+func (x *matchIndexes) reset() {
+	if x == nil {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.imports = nil
+	x.scopes = nil
+	x.sorted = false
+	x.scopeCandidates = nil
+	x.scopeErr = nil
+}
+
+// get returns the index of specifierMap, building it when missing or out of date.
+func (x *matchIndexes) get(scopeKey string, scoped bool, specifierMap map[string]string) *matchIndex {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	index := x.imports
+	if scoped {
+		index = x.scopes[scopeKey]
+	}
+	if index != nil && index.size == len(specifierMap) {
+		return index
+	}
+	index = newMatchIndex(specifierMap)
+	if !scoped {
+		x.imports = index
+		return index
+	}
+	if x.scopes == nil {
+		x.scopes = make(map[string]*matchIndex)
+	}
+	x.scopes[scopeKey] = index
+	return index
+}
+
+// sortedScopes returns the scope candidates of the map, sorting them when missing or out of date.
+// The caller holds a lock of the map.
+func (i *importMap) sortedScopes() ([]scopeMatchTuple, error) {
+	x := i.indexes
+	if x == nil {
+		return sortScopes(i.scopes, i.mapUrl, i.rootUrl)
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.sorted || x.scopeCount != len(i.scopes) {
+		x.scopeCandidates, x.scopeErr = sortScopes(i.scopes, i.mapUrl, i.rootUrl)
+		x.scopeCount = len(i.scopes)
+		x.sorted = true
+	}
+	return x.scopeCandidates, x.scopeErr
+}
+
+// importsMatch returns the key of the imports matching specifier, see getMapMatch.
+func (i *importMap) importsMatch(specifier string) string {
+	return i.indexedMatch(specifier, "", false, i.imports)
+}
+
+// scopeMatch returns the key of the scope scopeKey matching specifier, see getMapMatch.
+func (i *importMap) scopeMatch(specifier string, scopeKey string) string {
+	return i.indexedMatch(specifier, scopeKey, true, i.scopes[scopeKey])
+}
+
+func (i *importMap) indexedMatch(specifier string, scopeKey string, scoped bool, specifierMap map[string]string) string {
+	if len(specifierMap) < indexThreshold || i.indexes == nil {
+		return getMapMatch(specifier, specifierMap)
+	}
+	if _, ok := specifierMap[specifier]; ok {
+		return specifier
+	}
+	return i.indexes.get(scopeKey, scoped, specifierMap).match(specifier)
+}
@@ -0,0 +1,157 @@
+package importmap
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+// largeImports returns a generated map of n packages, each with a subpath prefix and a few
+// nested ones, like the maps of the JSPM generator
+func largeImports(n int) Imports {
+	imports := make(Imports, n*4)
+	for pkg := 0; pkg < n; pkg++ {
+		name := fmt.Sprintf("pkg-%d", pkg)
+		imports[name] = fmt.Sprintf("https://ga.jspm.io/npm:%s@1.0.0/index.js", name)
+		imports[name+"/"] = fmt.Sprintf("https://ga.jspm.io/npm:%s@1.0.0/", name)
+		imports[name+"/lib/"] = fmt.Sprintf("https://ga.jspm.io/npm:%s@1.0.0/dist/lib/", name)
+		imports["@scope/"+name+"/*"] = fmt.Sprintf("https://ga.jspm.io/npm:@scope/%s@1.0.0/*.js", name)
+	}
+	return imports
+}
+
+func TestMatchIndex(t *testing.T) {
+	imports := largeImports(50)
+	imports["/"] = "https://example.com/"
+	imports["pkg-1/lib*"] = "https://example.com/lib*.js"
+	imports["pkg-1/lib/x/"] = "https://example.com/x/"
+	imports["pkg-1/lib/x*"] = "https://example.com/x*.js"
+	index := newMatchIndex(imports)
+
+	for _, specifier := range []string{
+		"pkg-1", "pkg-1/", "pkg-1/a.js", "pkg-1/lib/a.js", "pkg-1/lib", "pkg-1/libs/a.js", "pkg-1/lib/x/y.js",
+		"pkg-1/lib/xy.js", "pkg-10/lib/a.js", "pkg-100/a.js", "@scope/pkg-7/a", "@scope/pkg-7", "unknown", "",
+	} {
+		expected := getMapMatch(specifier, imports)
+		if _, ok := imports[specifier]; !ok && index.match(specifier) != expected {
+			t.Errorf("expected %q to match %q, got %q", specifier, expected, index.match(specifier))
+		}
+	}
+}
+
+func TestIndexedResolution(t *testing.T) {
+	m, err := New(WithMap(Data{Imports: largeImports(50)}), WithMapUrl(&url.URL{Scheme: "https", Host: "example.com", Path: "/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolved, err := m.Resolve("pkg-42/lib/a.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "https://ga.jspm.io/npm:pkg-42@1.0.0/dist/lib/a.js" {
+		t.Errorf("expected the nested prefix to be used, got %s", resolved)
+	}
+
+	// entries set after a lookup are found, the index being rebuilt
+	m.Set("pkg-42/lib/a/", "https://example.com/a/")
+	resolved, err = m.Resolve("pkg-42/lib/a/b.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "https://example.com/a/b.js" {
+		t.Errorf("expected the new prefix to be used, got %s", resolved)
+	}
+}
+
+func BenchmarkGetMapMatch(b *testing.B) {
+	imports := largeImports(2500)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		getMapMatch("pkg-1234/lib/a.js", imports)
+	}
+}
+
+func BenchmarkMatchIndex(b *testing.B) {
+	imports := largeImports(2500)
+	index := newMatchIndex(imports)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		index.match("pkg-1234/lib/a.js")
+	}
+}
+
+func BenchmarkResolveLargeMap(b *testing.B) {
+	m, err := New(WithMap(Data{Imports: largeImports(2500)}), WithMapUrl(&url.URL{Scheme: "https", Host: "example.com", Path: "/"}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := m.Resolve("pkg-1234/lib/a.js"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSortedScopes(t *testing.T) {
+	m, err := New(WithMap(Data{
+		Imports: Imports{"dep": "/dep.js"},
+		Scopes: Scopes{
+			"/vendor/":     {"dep": "/vendor/dep.js"},
+			"/vendor/old/": {"dep": "/vendor/old/dep.js"},
+		},
+	}), WithMapUrl(&url.URL{Scheme: "https", Host: "example.com", Path: "/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := &url.URL{Scheme: "https", Host: "example.com", Path: "/vendor/old/new/a.js"}
+	resolved, err := m.ResolveWithParent("dep", parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "https://example.com/vendor/old/dep.js" {
+		t.Errorf("expected the most specific scope to be used, got %s", resolved)
+	}
+
+	// the scopes are sorted again once modified
+	m.SetWithParent("dep", "/vendor/old/new/dep.js", "/vendor/old/new/")
+	resolved, err = m.ResolveWithParent("dep", parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "https://example.com/vendor/old/new/dep.js" {
+		t.Errorf("expected the new scope to be used, got %s", resolved)
+	}
+
+	// and once rebased
+	if err = m.Rebase(&url.URL{Scheme: "https", Host: "cdn.example.com", Path: "/"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err = m.ResolveWithParent("dep", &url.URL{Scheme: "https", Host: "example.com", Path: "/vendor/a.js"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != "https://example.com/vendor/dep.js" {
+		t.Errorf("expected the rebased scope to be used, got %s", resolved)
+	}
+}
+
+func BenchmarkResolveManyScopes(b *testing.B) {
+	scopes := make(Scopes, 1000)
+	for n := 0; n < 1000; n++ {
+		scopes[fmt.Sprintf("https://ga.jspm.io/npm:pkg-%d@1.0.0/", n)] = Scope{"dep": fmt.Sprintf("https://ga.jspm.io/npm:dep@1.%d.0/index.js", n)}
+	}
+	m, err := New(WithMap(Data{Scopes: scopes}), WithMapUrl(&url.URL{Scheme: "https", Host: "example.com", Path: "/"}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	parent := &url.URL{Scheme: "https", Host: "ga.jspm.io", Path: "/npm:pkg-123@1.0.0/index.js"}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := m.ResolveWithParent("dep", parent); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package importmap
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InjectHTML returns an HTML document with data as its only import map.
+//
+// The first <script type="importmap"> block of the document gets data as its contents, keeping
+// its attributes except src, and the other import map blocks are removed since browsers would
+// merge them with it. Documents without an import map get one before their first script, which
+// import maps must precede, or at the end of their head. The map is indented like the block
+// unless opts say otherwise, and inlined on the line of the block with WithCompact.
+func InjectHTML(contents []byte, data Data, opts ...FormatOption) ([]byte, error) {
+	options := &FormatOptions{Indent: "  "}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	contents, err := decodeText(contents)
+	if err != nil {
+		return nil, err
+	}
+	doc := string(contents)
+
+	var blocks []htmlTag
+	scripts := extractTags(doc, "script")
+	for _, script := range scripts {
+		if strings.ToLower(strings.TrimSpace(script.attributes["type"])) == "importmap" {
+			blocks = append(blocks, script)
+		}
+	}
+
+	if len(blocks) == 0 {
+		pos := injectPosition(doc, scripts)
+		indent := lineIndent(doc, pos)
+		block, err := importMapBlock(`<script type="importmap">`, data, indent, options)
+		if err != nil {
+			return nil, err
+		}
+		if start := trimLineStart(doc, pos); start > 0 && doc[start-1] != '\n' {
+			block = "\n" + block
+		}
+		return []byte(doc[:pos] + block + "\n" + indent + doc[pos:]), nil
+	}
+
+	opening := doc[blocks[0].start:blocks[0].contentsStart]
+	if _, ok := blocks[0].attributes["src"]; ok {
+		opening = `<script type="importmap">`
+	}
+	block, err := importMapBlock(opening, data, lineIndent(doc, blocks[0].start), options)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString(doc[:blocks[0].start])
+	b.WriteString(block)
+	pos := blocks[0].end
+	for _, other := range blocks[1:] {
+		start := trimLineStart(doc, other.start)
+		b.WriteString(doc[pos:start])
+		pos = other.end
+		// the line of a removed block goes with it when nothing else is on it
+		end := strings.IndexByte(doc[pos:], '\n')
+		if (start == 0 || doc[start-1] == '\n') && end >= 0 && strings.TrimSpace(doc[pos:pos+end]) == "" {
+			pos += end + 1
+		}
+	}
+	b.WriteString(doc[pos:])
+	return []byte(b.String()), nil
+}
+
+// importMapBlock formats data as the contents of an import map script opened with opening,
+// indented like the script itself.
+func importMapBlock(opening string, data Data, indent string, options *FormatOptions) (string, error) {
+	inline := *options
+	inline.TrailingNewline = false
+	contents, err := format(data, indent, &inline)
+	if err != nil {
+		return "", err
+	}
+	if options.Compact {
+		return opening + string(contents) + "</script>", nil
+	}
+	return opening + "\n" + indent + string(contents) + "\n" + indent + "</script>", nil
+}
+
+// injectPosition returns where a new import map is inserted into doc: before the first script,
+// else before the closing head tag, else before the body, else at the start of the document.
+func injectPosition(doc string, scripts []htmlTag) int {
+	if len(scripts) > 0 {
+		return scripts[0].start
+	}
+	lower := strings.ToLower(doc)
+	for _, marker := range []string{"</head", "<body"} {
+		if pos := strings.Index(lower, marker); pos >= 0 {
+			return pos
+		}
+	}
+	return 0
+}
+
+// lineIndent returns the whitespace preceding pos on its line, or nothing when pos isn't the
+// first non-blank position of the line.
+func lineIndent(doc string, pos int) string {
+	start := trimLineStart(doc, pos)
+	if start > 0 && doc[start-1] != '\n' {
+		return ""
+	}
+	return doc[start:pos]
+}
+
+// trimLineStart moves pos back over the spaces and tabs preceding it.
+func trimLineStart(doc string, pos int) int {
+	for pos > 0 && (doc[pos-1] == ' ' || doc[pos-1] == '\t') {
+		pos--
+	}
+	return pos
+}
+
+// SetIntegrityAttributes sets the integrity attribute of the scripts, stylesheets and preloads of
+// an HTML document whose src or href is a key of integrity, replacing the existing values. The
+// URLs are compared as written, "./" prefixes aside.
+func SetIntegrityAttributes(contents []byte, integrity Integrity) ([]byte, error) {
+	contents, err := decodeText(contents)
+	if err != nil {
+		return nil, err
+	}
+	doc := string(contents)
+
+	values := make(map[string]string, len(integrity))
+	for target, value := range integrity {
+		values[strings.TrimPrefix(target, "./")] = value
+	}
+
+	type edit struct {
+		start, end int
+		opening    string
+	}
+	var edits []edit
+	for _, name := range []string{"script", "link"} {
+		for _, tag := range extractTags(doc, name) {
+			attribute := "src"
+			if name == "link" {
+				attribute = "href"
+			}
+			value, ok := values[strings.TrimPrefix(strings.TrimSpace(tag.attributes[attribute]), "./")]
+			if !ok || tag.attributes[attribute] == "" {
+				continue
+			}
+			edits = append(edits, edit{tag.start, tag.contentsStart, setIntegrityAttribute(doc[tag.start:tag.contentsStart], value)})
+		}
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var b strings.Builder
+	pos := 0
+	for _, e := range edits {
+		b.WriteString(doc[pos:e.start])
+		b.WriteString(e.opening)
+		pos = e.end
+	}
+	b.WriteString(doc[pos:])
+	return []byte(b.String()), nil
+}
+
+// integrityAttribute matches an integrity attribute with its value, quoted or not
+var integrityAttribute = regexp.MustCompile(`(?i)\sintegrity\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+
+// setIntegrityAttribute sets the integrity attribute of an opening tag to value.
+func setIntegrityAttribute(opening string, value string) string {
+	replacement := ` integrity="` + html.EscapeString(value) + `"`
+	if loc := integrityAttribute.FindStringIndex(opening); loc != nil {
+		return opening[:loc[0]] + replacement + opening[loc[1]:]
+	}
+	end := len(opening) - 1
+	if strings.HasSuffix(opening, "/>") {
+		end--
+	}
+	end = trimLineStart(opening, end)
+	return opening[:end] + replacement + opening[end:]
+}
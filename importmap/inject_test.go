@@ -0,0 +1,97 @@
+package importmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInjectHTMLReplacesImportMaps(t *testing.T) {
+	doc := "<html>\n" +
+		"  <head>\n" +
+		`    <script type="importmap" nonce="abc">{"imports": {"old": "/old.js"}}</script>` + "\n" +
+		`    <script type="importmap" src="other.json"></script>` + "\n" +
+		`    <script type="module" src="./app.js"></script>` + "\n" +
+		"  </head>\n" +
+		"</html>"
+
+	injected, err := InjectHTML([]byte(doc), Data{Imports: Imports{"react": "https://esm.invalid/react.js"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<html>\n" +
+		"  <head>\n" +
+		`    <script type="importmap" nonce="abc">` + "\n" +
+		"    {\n" +
+		`      "imports": {` + "\n" +
+		`        "react": "https://esm.invalid/react.js"` + "\n" +
+		"      }\n" +
+		"    }\n" +
+		"    </script>\n" +
+		`    <script type="module" src="./app.js"></script>` + "\n" +
+		"  </head>\n" +
+		"</html>"
+	if string(injected) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, injected)
+	}
+
+	m, err := ParseHTML(injected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GetImports()["react"] != "https://esm.invalid/react.js" {
+		t.Errorf("expected the injected map to be parsed back, got %v", m.GetImports())
+	}
+}
+
+func TestInjectHTMLInsertsImportMap(t *testing.T) {
+	data := Data{Imports: Imports{"a": "./a.js"}}
+	cases := map[string]string{
+		"before the first script": `<html><head><script type="module" src="./app.js"></script></head></html>`,
+		"at the end of the head":  "<html><head><title>App</title></head><body></body></html>",
+		"without a head":          "<p>App</p>",
+	}
+	for name, doc := range cases {
+		t.Run(name, func(t *testing.T) {
+			injected, err := InjectHTML([]byte(doc), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := ParseHTML(injected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.GetImports()["a"] == "" {
+				t.Errorf("expected the injected map in:\n%s", injected)
+			}
+			if module := strings.Index(string(injected), `type="module"`); module >= 0 && module < strings.Index(string(injected), "importmap") {
+				t.Errorf("expected the import map to precede the module scripts:\n%s", injected)
+			}
+		})
+	}
+}
+
+func TestSetIntegrityAttributes(t *testing.T) {
+	doc := `<html><head>` +
+		`<link rel="stylesheet" href="app.css" />` +
+		`<script type="module" src="./app.js" integrity='sha384-stale'></script>` +
+		`<script type="module" src="./other.js"></script>` +
+		`</head></html>`
+
+	updated, err := SetIntegrityAttributes([]byte(doc), Integrity{
+		"./app.js":  emptySha384,
+		"./app.css": emptySha384,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<html><head>` +
+		`<link rel="stylesheet" href="app.css" integrity="` + emptySha384 + `" />` +
+		`<script type="module" src="./app.js" integrity="` + emptySha384 + `"></script>` +
+		`<script type="module" src="./other.js"></script>` +
+		`</head></html>`
+	if string(updated) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, updated)
+	}
+}
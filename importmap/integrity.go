@@ -0,0 +1,125 @@
+package importmap
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// integrityDigestSizes maps the hash algorithms allowed by Subresource Integrity to their digest sizes
+var integrityDigestSizes = map[string]int{
+	"sha256": 32,
+	"sha384": 48,
+	"sha512": 64,
+}
+
+// IntegrityError is returned for integrity values which don't follow the Subresource Integrity grammar
+type IntegrityError struct {
+	Value  string
+	Reason string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("invalid integrity value %q: %s", e.Value, e.Reason)
+}
+
+// NormalizeIntegrity validates an integrity metadata value like "sha384-<base64> sha512-<base64>"
+// and returns it with its whitespace normalized to single spaces.
+//
+// Each hash must use sha256, sha384 or sha512 and carry a base64 digest of the matching size.
+// Options following a "?" are allowed but not interpreted.
+func NormalizeIntegrity(value string) (string, error) {
+	hashes := strings.Fields(value)
+	if len(hashes) == 0 {
+		return "", &IntegrityError{Value: value, Reason: "no hash"}
+	}
+
+	for _, hash := range hashes {
+		expression, _, _ := strings.Cut(hash, "?")
+		algorithm, digest, ok := strings.Cut(expression, "-")
+		if !ok {
+			return "", &IntegrityError{Value: value, Reason: fmt.Sprintf("%q is not formatted as <algorithm>-<digest>", hash)}
+		}
+
+		size, ok := integrityDigestSizes[algorithm]
+		if !ok {
+			return "", &IntegrityError{Value: value, Reason: fmt.Sprintf("unsupported algorithm %q", algorithm)}
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(digest)
+		if err != nil {
+			return "", &IntegrityError{Value: value, Reason: fmt.Sprintf("the %s digest is not valid base64", algorithm)}
+		}
+		if len(decoded) != size {
+			return "", &IntegrityError{Value: value, Reason: fmt.Sprintf("the %s digest must be %d bytes long, got %d", algorithm, size, len(decoded))}
+		}
+	}
+
+	return strings.Join(hashes, " "), nil
+}
+
+// ComputeIntegrity returns the Subresource Integrity value of contents with the given hash
+// algorithm, sha384 when empty.
+func ComputeIntegrity(contents []byte, algorithm string) (string, error) {
+	var digest []byte
+	switch algorithm {
+	case "sha256":
+		sum := sha256.Sum256(contents)
+		digest = sum[:]
+	case "", "sha384":
+		algorithm = "sha384"
+		sum := sha512.Sum384(contents)
+		digest = sum[:]
+	case "sha512":
+		sum := sha512.Sum512(contents)
+		digest = sum[:]
+	default:
+		return "", fmt.Errorf("unsupported integrity algorithm %q, use sha256, sha384 or sha512", algorithm)
+	}
+	return algorithm + "-" + base64.StdEncoding.EncodeToString(digest), nil
+}
+
+// IntegrityMismatchError is returned by VerifyIntegrity for contents matching none of the hashes
+type IntegrityMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *IntegrityMismatchError) Error() string {
+	return fmt.Sprintf("integrity mismatch: expected %s, got %s", e.Expected, e.Actual)
+}
+
+// VerifyIntegrity checks contents against an integrity metadata value. Like browsers do, only the
+// hashes with the strongest algorithm of the value are considered and any of them may match.
+func VerifyIntegrity(contents []byte, integrity string) error {
+	integrity, err := NormalizeIntegrity(integrity)
+	if err != nil {
+		return err
+	}
+
+	strongest := ""
+	var digests []string
+	for _, hash := range strings.Fields(integrity) {
+		expression, _, _ := strings.Cut(hash, "?")
+		algorithm, _, _ := strings.Cut(expression, "-")
+		switch {
+		case integrityDigestSizes[algorithm] > integrityDigestSizes[strongest]:
+			strongest, digests = algorithm, []string{expression}
+		case algorithm == strongest:
+			digests = append(digests, expression)
+		}
+	}
+
+	actual, err := ComputeIntegrity(contents, strongest)
+	if err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		if digest == actual {
+			return nil
+		}
+	}
+	return &IntegrityMismatchError{Expected: strings.Join(digests, " "), Actual: actual}
+}
@@ -0,0 +1,62 @@
+package importmap
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeIntegrity(t *testing.T) {
+	const emptySha256 = "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+
+	normalized, err := NormalizeIntegrity("  " + emptySha384 + "\n\t" + emptySha256 + "?ct=application/javascript ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized != emptySha384+" "+emptySha256+"?ct=application/javascript" {
+		t.Errorf("unexpected normalized value %q", normalized)
+	}
+
+	for _, value := range []string{
+		"",
+		"sha384",
+		"md5-1B2M2Y8AsgTpgAmY7PhCfg==",
+		"sha384-not*base64",
+		emptySha256[:7] + emptySha384[7:],
+	} {
+		_, err = NormalizeIntegrity(value)
+		var integrityErr *IntegrityError
+		if !errors.As(err, &integrityErr) {
+			t.Errorf("%q: expected an *IntegrityError, got %v", value, err)
+		}
+	}
+}
+
+func TestSetIntegrityValueRejectsMalformedValues(t *testing.T) {
+	m, _ := New()
+	if err := m.SetIntegrityValue("/a.js", "sha384-abc"); err == nil {
+		t.Error("expected a malformed integrity value to be rejected")
+	}
+	if err := m.SetIntegrityValue("/a.js", emptySha384); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	const emptySha256 = "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="
+	const otherSha384 = "sha384-" + "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+
+	if err := VerifyIntegrity(nil, emptySha384); err != nil {
+		t.Error(err)
+	}
+	if err := VerifyIntegrity(nil, otherSha384+" "+emptySha384); err != nil {
+		t.Errorf("expected any hash of the strongest algorithm to match, got %v", err)
+	}
+
+	var mismatch *IntegrityMismatchError
+	if err := VerifyIntegrity([]byte("x"), emptySha384); !errors.As(err, &mismatch) {
+		t.Errorf("expected an *IntegrityMismatchError, got %v", err)
+	}
+	if err := VerifyIntegrity(nil, emptySha256+" "+otherSha384); !errors.As(err, &mismatch) {
+		t.Errorf("expected weaker hashes to be ignored, got %v", err)
+	}
+}
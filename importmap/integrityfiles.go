@@ -0,0 +1,89 @@
+package importmap
+
+import (
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AddIntegrityFromFiles is an implementation of the IImportMap interface.
+func (i *importMap) AddIntegrityFromFiles(rootDir string, algorithm string) error {
+	if _, err := ComputeIntegrity(nil, algorithm); err != nil {
+		return err
+	}
+
+	files := i.localFiles(rootDir)
+	for _, target := range sortedKeys(files) {
+		file := files[target]
+		contents, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		integrity, err := ComputeIntegrity(contents, algorithm)
+		if err != nil {
+			return err
+		}
+		if err = i.SetIntegrityValue(target, integrity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localFiles returns the local files the targets of the map point at, by target.
+func (i *importMap) localFiles(rootDir string) map[string]string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	files := make(map[string]string)
+	add := func(target string) {
+		if file, ok := i.localFile(rootDir, target); ok {
+			files[target] = file
+		}
+	}
+	for _, target := range i.imports {
+		add(target)
+	}
+	for _, scope := range i.scopes {
+		for _, target := range scope {
+			add(target)
+		}
+	}
+	return files
+}
+
+// localFile returns the file a target of the map points at. Root-relative targets and URLs on the
+// origin of the map are looked up under rootDir, relative to the root URL of the map if there's
+// one, while file URLs point at their own path. Targets ending with "/" aren't files.
+func (i *importMap) localFile(rootDir string, target string) (string, bool) {
+	if strings.HasSuffix(target, "/") {
+		return "", false
+	}
+	resolved := i.canonicalUrl(target)
+	if strings.HasPrefix(resolved, "/") && !strings.HasPrefix(resolved, "//") {
+		return filepath.Join(rootDir, filepath.FromSlash(resolved)), true
+	}
+
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case u.Scheme == "file":
+		path, err := FilePath(u)
+		return path, err == nil
+	case sameOrigin(u, i.mapUrl):
+		p := u.Path
+		if i.rootUrl != nil && strings.HasPrefix(p, i.rootUrl.Path) {
+			p = "/" + strings.TrimPrefix(p, i.rootUrl.Path)
+		}
+		return filepath.Join(rootDir, filepath.FromSlash(p)), true
+	}
+	return "", false
+}
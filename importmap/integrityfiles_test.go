@@ -0,0 +1,50 @@
+package importmap
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddIntegrityFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"empty.js", filepath.Join("lib", "empty.js")} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mapUrl, _ := url.Parse("https://site.com/app/importmap.json")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"root":     "/empty.js",
+			"relative": "../lib/empty.js",
+			"missing":  "/missing.js",
+			"dir/":     "/lib/",
+			"remote":   "https://esm.sh/react@18",
+		},
+	}))
+
+	if err := m.AddIntegrityFromFiles(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	expected := Integrity{
+		"/empty.js":       emptySha384,
+		"../lib/empty.js": emptySha384,
+	}
+	integrity := m.GetIntegrity()
+	if len(integrity) != len(expected) || integrity["/empty.js"] != expected["/empty.js"] || integrity["../lib/empty.js"] != expected["../lib/empty.js"] {
+		t.Errorf("expected %v, got %v", expected, integrity)
+	}
+
+	if err := m.AddIntegrityFromFiles(dir, "md5"); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+	if integrity, _ := ComputeIntegrity(nil, "sha256"); integrity != "sha256-47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" {
+		t.Errorf("unexpected sha256 integrity %s", integrity)
+	}
+}
@@ -1,12 +1,17 @@
 package importmap
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"os"
+	"unicode/utf16"
 )
 
-// LoadFromFile  loads the contents of the import map file and returns an IImportMap instance
-func LoadFromFile(path string) (IImportMap, error) {
+// LoadFromFile  loads the contents of the import map file and returns an IImportMap instance.
+// Invalid entries are handled according to WithValidationMode, like by New.
+func LoadFromFile(path string, opts ...Option) (IImportMap, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, err
 	}
@@ -16,13 +21,24 @@ func LoadFromFile(path string) (IImportMap, error) {
 		return nil, err
 	}
 
+	return Parse(fileContents, opts...)
+}
+
+// Parse parses the contents of an import map json file and returns an IImportMap instance.
+// UTF-8 byte order marks are skipped and UTF-16 encoded contents are converted to UTF-8.
+func Parse(contents []byte, opts ...Option) (IImportMap, error) {
+	contents, err := decodeText(contents)
+	if err != nil {
+		return nil, err
+	}
+
 	data := Data{}
-	err = json.Unmarshal(fileContents, &data)
+	err = json.Unmarshal(contents, &data)
 	if err != nil {
 		return nil, err
 	}
 
-	m, err := New(WithMap(data))
+	m, err := New(append([]Option{WithMap(data)}, opts...)...)
 
 	if err != nil {
 		return nil, err
@@ -30,3 +46,40 @@ func LoadFromFile(path string) (IImportMap, error) {
 
 	return m, nil
 }
+
+// Serialize returns the canonical form of the import map as compact json, or laid out by opts.
+// Parsing the result with the same map and root URL yields a map with the same canonical form.
+func Serialize(m IImportMap, opts ...FormatOption) ([]byte, error) {
+	return Format(m.CanonicalForm(), append([]FormatOption{WithCompact()}, opts...)...)
+}
+
+// decodeText converts text files to UTF-8, detecting the encoding from the byte order mark.
+// Files without a byte order mark are assumed to be UTF-16 when their first character is an ASCII
+// character padded with a zero byte, which is how "{" and whitespace look in UTF-16.
+func decodeText(contents []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(contents, []byte{0xEF, 0xBB, 0xBF}):
+		return contents[3:], nil
+	case bytes.HasPrefix(contents, []byte{0xFF, 0xFE}):
+		return decodeUTF16(contents[2:], binary.LittleEndian)
+	case bytes.HasPrefix(contents, []byte{0xFE, 0xFF}):
+		return decodeUTF16(contents[2:], binary.BigEndian)
+	case len(contents) >= 2 && contents[0] != 0 && contents[0] < 0x80 && contents[1] == 0:
+		return decodeUTF16(contents, binary.LittleEndian)
+	case len(contents) >= 2 && contents[0] == 0 && contents[1] != 0 && contents[1] < 0x80:
+		return decodeUTF16(contents, binary.BigEndian)
+	}
+	return contents, nil
+}
+
+func decodeUTF16(contents []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(contents)%2 != 0 {
+		return nil, errors.New("invalid UTF-16 text: odd number of bytes")
+	}
+
+	units := make([]uint16, 0, len(contents)/2)
+	for idx := 0; idx < len(contents); idx += 2 {
+		units = append(units, order.Uint16(contents[idx:]))
+	}
+	return []byte(string(utf16.Decode(units))), nil
+}
@@ -0,0 +1,55 @@
+package importmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string, bigEndian bool, bom bool) []byte {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xFEFF}, units...)
+	}
+	result := make([]byte, 0, len(units)*2)
+	for _, unit := range units {
+		if bigEndian {
+			result = append(result, byte(unit>>8), byte(unit))
+		} else {
+			result = append(result, byte(unit), byte(unit>>8))
+		}
+	}
+	return result
+}
+
+func TestLoadFromFileEncodings(t *testing.T) {
+	const contents = `{"imports": {"ünïcode": "https://esm.sh/ünïcode"}}`
+
+	files := map[string][]byte{
+		"utf8.json":         []byte(contents),
+		"utf8-bom.json":     append([]byte{0xEF, 0xBB, 0xBF}, contents...),
+		"utf16le-bom.json":  encodeUTF16(contents, false, true),
+		"utf16be-bom.json":  encodeUTF16(contents, true, true),
+		"utf16le.json":      encodeUTF16(contents, false, false),
+		"utf16be.json":      encodeUTF16(contents, true, false),
+		"utf16le-crlf.json": encodeUTF16("\r\n"+contents, false, false),
+	}
+
+	dir := t.TempDir()
+	for name, raw := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := LoadFromFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if m.GetImports()["ünïcode"] != "https://esm.sh/ünïcode" {
+			t.Errorf("%s: unexpected imports %v", name, m.GetImports())
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package importmap
+
+import (
+	"net/url"
+	"sync"
+)
+
+// MergePolicy controls how the scopes of two maps are combined by Merge
+type MergePolicy int
+
+const (
+	// MergeScopes combines scopes present in both maps entry by entry, the second map winning on conflicts
+	MergeScopes MergePolicy = iota
+	// ReplaceScopes replaces scopes present in both maps with the scopes of the second map
+	ReplaceScopes
+)
+
+// Merge returns a new import map with the entries of a extended by the entries of b.
+//
+// Unlike Extend, neither a nor b is modified, so maps shared across goroutines can be composed safely
+// as long as nobody mutates them concurrently.
+func Merge(a IImportMap, b IImportMap, policy MergePolicy) (IImportMap, error) {
+	result := deepCopy(a)
+	return result.Extend(deepCopy(b), policy == ReplaceScopes)
+}
+
+// deepCopy copies m, including its nested scopes and URLs.
+func deepCopy(m IImportMap) *importMap {
+	data := Data{}
+	var diagnostics []Diagnostic
+	src := asImportMap(m)
+	if src != nil {
+		src.mu.RLock()
+		defer src.mu.RUnlock()
+		data.Imports, data.Scopes, data.Integrity, diagnostics = src.imports, src.scopes, src.integrity, src.diagnostics
+	} else {
+		data.Imports, data.Scopes, data.Integrity, diagnostics = m.GetImports(), m.GetScopes(), m.GetIntegrity(), m.Diagnostics()
+	}
+
+	result := &importMap{
+		mu:          &sync.RWMutex{},
+		indexes:     &matchIndexes{},
+		imports:     Imports(copyStringMap(data.Imports)),
+		integrity:   Integrity(copyStringMap(data.Integrity)),
+		scopes:      make(Scopes, len(data.Scopes)),
+		diagnostics: append([]Diagnostic(nil), diagnostics...),
+	}
+	for scopeKey, scope := range data.Scopes {
+		result.scopes[scopeKey] = Scope(copyStringMap(scope))
+	}
+	result.ensureMaps()
+
+	if src != nil {
+		result.mapUrl = copyUrl(src.mapUrl)
+		result.rootUrl = copyUrl(src.rootUrl)
+		result.validationMode = src.validationMode
+		result.subpathImports = src.subpathImports
+	}
+	if result.mapUrl == nil {
+		// maps from other implementations don't expose their URLs, fall back to the default of New
+		result.mapUrl, _ = defaultMapUrl()
+	}
+	return result
+}
+
+// asImportMap returns the implementation backing m, or nil if m comes from another package.
+func asImportMap(m IImportMap) *importMap {
+	switch v := m.(type) {
+	case *importMap:
+		return v
+	case *frozenImportMap:
+		return v.importMap
+	}
+	return nil
+}
+
+func copyStringMap[M ~map[string]string](m M) map[string]string {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+func copyScopes(scopes Scopes) Scopes {
+	if scopes == nil {
+		return nil
+	}
+	result := make(Scopes, len(scopes))
+	for scopeKey, scope := range scopes {
+		result[scopeKey] = copyStringMap(scope)
+	}
+	return result
+}
+
+func copyUrl(u *url.URL) *url.URL {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	if u.User != nil {
+		user := *u.User
+		c.User = &user
+	}
+	return &c
+}
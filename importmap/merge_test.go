@@ -0,0 +1,46 @@
+package importmap
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestMergeLeavesInputsUntouched(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/")
+	a, _ := New(WithMapUrl(baseUrl), WithMap(Data{
+		Imports: Imports{"a": "/a.js", "shared": "/a-shared.js"},
+		Scopes:  Scopes{"/x/": {"a": "/x-a.js"}},
+	}))
+	b, _ := New(WithMapUrl(baseUrl), WithMap(Data{
+		Imports: Imports{"b": "/b.js", "shared": "/b-shared.js"},
+		Scopes:  Scopes{"/x/": {"b": "/x-b.js"}},
+	}))
+
+	merged, err := Merge(a, b, MergeScopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a.GetImports()) != 2 || a.GetImports()["shared"] != "/a-shared.js" {
+		t.Errorf("a was modified: %v", a.GetImports())
+	}
+	if len(a.GetScopes()["/x/"]) != 1 {
+		t.Errorf("a's scope was modified: %v", a.GetScopes())
+	}
+	if len(b.GetImports()) != 2 {
+		t.Errorf("b was modified: %v", b.GetImports())
+	}
+
+	assertUrlsEqualsU(merged, "shared", baseUrl, "https://site.com/b-shared.js", t)
+	if len(merged.GetScopes()["/x/"]) != 2 {
+		t.Errorf("expected the scopes to be combined, got %v", merged.GetScopes())
+	}
+
+	replaced, err := Merge(a, b, ReplaceScopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replaced.GetScopes()["/x/"]) != 1 {
+		t.Errorf("expected the scope to be replaced, got %v", replaced.GetScopes())
+	}
+}
@@ -0,0 +1,156 @@
+package importmap
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Normalize is an implementation of the IImportMap interface.
+func (i *importMap) Normalize() IImportMap {
+	result := deepCopy(i)
+
+	result.imports = Imports(normalizeSpecifierMap(result.imports))
+	normalizedScopes := make(Scopes, len(result.scopes))
+	for scopeKey, scope := range result.scopes {
+		normalizedScopes[scopeKey] = normalizeSpecifierMap(scope)
+	}
+
+	// the entries are compared with the ones of the original scopes, which they fall back to once
+	// removed
+	scopes := make(Scopes, len(result.scopes))
+	for scopeKey, scope := range normalizedScopes {
+		normalized := make(Scope, len(scope))
+		scopeUrl := result.canonicalUrl(scopeKey)
+		for key, target := range scope {
+			if fallback, ok := result.fallbackTarget(normalizedScopes, scopeUrl, key); !ok || result.canonicalUrl(fallback) != result.canonicalUrl(target) {
+				normalized[key] = target
+			}
+		}
+		if len(normalized) > 0 {
+			scopes[normalizeAddress(scopeKey)] = normalized
+		}
+	}
+	result.scopes = scopes
+
+	integrity := make(Integrity, len(result.integrity))
+	for target, value := range result.integrity {
+		if normalizedValue, err := NormalizeIntegrity(value); err == nil {
+			value = normalizedValue
+		}
+		integrity[normalizeAddress(target)] = value
+	}
+	result.integrity = integrity
+
+	return result
+}
+
+// fallbackTarget returns the target of key in the most specific of scopes which is less specific
+// than scopeUrl and matches key, or in the top-level imports when none does. ok is false when
+// key isn't mapped there exactly.
+func (i *importMap) fallbackTarget(scopes Scopes, scopeUrl string, key string) (target string, ok bool) {
+	fallbackUrl, fallbackKey := "", ""
+	for scopeKey, scope := range scopes {
+		u := i.canonicalUrl(scopeKey)
+		if !strings.HasSuffix(u, "/") || len(u) >= len(scopeUrl) || len(u) <= len(fallbackUrl) || !strings.HasPrefix(scopeUrl, u) {
+			continue
+		}
+		if getMapMatch(key, scope) != "" {
+			fallbackUrl, fallbackKey = u, scopeKey
+		}
+	}
+	if fallbackUrl != "" {
+		target, ok = scopes[fallbackKey][key]
+		return target, ok
+	}
+	target, ok = i.imports[key]
+	return target, ok
+}
+
+func normalizeSpecifierMap(specifierMap map[string]string) map[string]string {
+	result := make(map[string]string, len(specifierMap))
+	for key, target := range specifierMap {
+		if !isPlain(key) {
+			key = normalizeAddress(key)
+		}
+		result[key] = normalizeAddress(target)
+	}
+	return result
+}
+
+// normalizeAddress removes the redundant "." and ".." segments of a URL or a relative URL starting
+// with "/", "./" or "../", lowercases its scheme and host, drops its default port and normalizes
+// its percent-encoding. Anything else is returned as is.
+func normalizeAddress(address string) string {
+	relative := strings.HasPrefix(address, "./") || strings.HasPrefix(address, "../")
+	rootRelative := strings.HasPrefix(address, "/") && !strings.HasPrefix(address, "//")
+	if !relative && !rootRelative && !isUrl(address) {
+		return address
+	}
+
+	u, err := url.Parse(normalizePercentEncoding(address))
+	if err != nil {
+		return address
+	}
+	if u.Scheme != "" {
+		u = normalizeUrl(u)
+	}
+
+	if u.Opaque == "" && u.Path != "" {
+		// the escaped path is cleaned so escaped slashes aren't taken for separators
+		escaped := u.EscapedPath()
+		cleaned := path.Clean(escaped)
+		if strings.HasSuffix(escaped, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		if relative && !strings.HasPrefix(cleaned, "../") {
+			if cleaned == "." || cleaned == "./" {
+				cleaned = "./"
+			} else {
+				cleaned = "./" + cleaned
+			}
+		}
+		if unescaped, err := url.PathUnescape(cleaned); err == nil {
+			u.Path, u.RawPath = unescaped, cleaned
+		}
+	}
+	return u.String()
+}
+
+// normalizePercentEncoding uppercases percent-encoded octets and decodes the ones of unreserved characters.
+func normalizePercentEncoding(s string) string {
+	var b strings.Builder
+	for n := 0; n < len(s); n++ {
+		if s[n] == '%' && n+2 < len(s) && isHex(s[n+1]) && isHex(s[n+2]) {
+			c := unhex(s[n+1])<<4 | unhex(s[n+2])
+			if isUnreserved(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteString("%" + strings.ToUpper(s[n+1:n+3]))
+			}
+			n += 2
+			continue
+		}
+		b.WriteByte(s[n])
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
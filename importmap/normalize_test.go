@@ -0,0 +1,79 @@
+package importmap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	cases := map[string]string{
+		"././a.js":       "./a.js",
+		"./x/../a.js":    "./a.js",
+		"./.hidden/a.js": "./.hidden/a.js",
+		"../x/./a.js":    "../x/a.js",
+		"/lib/./x/../":   "/lib/",
+		"HTTPS://Esm.SH:443/a/./b%7e%2f%c3%a9.js": "https://esm.sh/a/b~%2F%C3%A9.js",
+		"https://esm.sh/react@18?dev":             "https://esm.sh/react@18?dev",
+		"react":                                   "react",
+	}
+	for address, expected := range cases {
+		if actual := normalizeAddress(address); actual != expected {
+			t.Errorf("normalizeAddress(%q) = %q, expected %q", address, actual, expected)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"react": "https://ESM.sh/react@18",
+			"local": "././local.js",
+		},
+		Scopes: Scopes{
+			"./admin/./": {"react": "https://esm.sh/react@18", "ui": "/ui/../ui.js"},
+			"/legacy/":   {"local": "./local.js"},
+		},
+		Integrity: Integrity{
+			"https://esm.sh/./react@18": "  " + emptySha384 + "  ",
+		},
+	}))
+
+	normalized := m.Normalize()
+	expected := Data{
+		Imports: Imports{
+			"react": "https://esm.sh/react@18",
+			"local": "./local.js",
+		},
+		Scopes: Scopes{
+			"./admin/": {"ui": "/ui.js"},
+		},
+		Integrity: Integrity{
+			"https://esm.sh/react@18": emptySha384,
+		},
+	}
+	actual := Data{Imports: normalized.GetImports(), Scopes: normalized.GetScopes(), Integrity: normalized.GetIntegrity()}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if m.GetImports()["local"] != "././local.js" {
+		t.Error("expected the original map to be left untouched")
+	}
+}
+
+func TestNormalizeNestedScopes(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{"x": "/top.js"},
+		Scopes: Scopes{
+			"/a/":   {"x": "/a.js"},
+			"/a/b/": {"x": "/top.js"},
+		},
+	}))
+
+	// the entry of /a/b/ matches the top level, but removing it would fall back to the one of /a/
+	normalized := m.Normalize()
+	assertUrlsEquals(normalized, "x", "https://site.com/a/b/c.js", "https://site.com/top.js", t)
+	assertUrlsEquals(normalized, "x", "https://site.com/a/c.js", "https://site.com/a.js", t)
+}
@@ -0,0 +1,67 @@
+package importmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultPackageJSONKey is the package.json key holding the import map when none is given
+const DefaultPackageJSONKey = "importmap"
+
+// LoadFromPackageJSON loads the import map embedded under key in a package.json file
+func LoadFromPackageJSON(path string, key string, opts ...Option) (IImportMap, error) {
+	fileContents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := ParsePackageJSON(fileContents, key, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParsePackageJSON parses the import map embedded under key in the contents of a package.json file.
+// An empty key means DefaultPackageJSONKey.
+func ParsePackageJSON(contents []byte, key string, opts ...Option) (IImportMap, error) {
+	if key == "" {
+		key = DefaultPackageJSONKey
+	}
+
+	contents, err := decodeText(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := map[string]json.RawMessage{}
+	if err = json.Unmarshal(contents, &manifest); err != nil {
+		return nil, err
+	}
+
+	embedded, ok := manifest[key]
+	if !ok {
+		return nil, fmt.Errorf("no import map under the %q key", key)
+	}
+
+	return Parse(embedded, opts...)
+}
+
+// AddSubpathImports returns a copy of m with the subpath imports of a package.json, such as
+// "#internal/*", added to its imports. The entries m already has are kept.
+//
+// Specifiers starting with "#" are URLs relative to the importer for browsers, so the copy
+// resolves them through its "#" keys first, the way Node.js resolves subpath imports, and only
+// falls back to resolving them as URLs when no key matches.
+func AddSubpathImports(m IImportMap, imports Imports) IImportMap {
+	result := deepCopy(m)
+	for key, target := range imports {
+		if _, ok := result.imports[key]; !ok && strings.HasPrefix(key, "#") {
+			result.imports[key] = target
+		}
+	}
+	result.subpathImports = true
+	return result
+}
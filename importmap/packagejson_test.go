@@ -0,0 +1,68 @@
+package importmap
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFromPackageJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "package.json")
+	contents := `{
+		"name": "app",
+		"importmap": {"imports": {"react": "https://esm.sh/react@18.2.0"}},
+		"exports-map": {"imports": {"react": "https://esm.sh/react@17.0.2"}}
+	}`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadFromPackageJSON(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GetImports()["react"] != "https://esm.sh/react@18.2.0" {
+		t.Errorf("unexpected imports %v", m.GetImports())
+	}
+
+	m, err = LoadFromPackageJSON(path, "exports-map")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GetImports()["react"] != "https://esm.sh/react@17.0.2" {
+		t.Errorf("unexpected imports %v", m.GetImports())
+	}
+
+	if _, err = LoadFromPackageJSON(path, "missing"); err == nil {
+		t.Error("expected an error for a missing key")
+	}
+}
+
+func TestAddSubpathImports(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{Imports: Imports{"#config": "/config.js"}}))
+
+	withImports := AddSubpathImports(m, Imports{
+		"#internal/*": "https://site.com/src/internal/*.js",
+		"#config":     "https://site.com/src/config.js",
+	})
+
+	assertUrlsEquals(withImports, "#internal/db", "https://site.com/app.js", "https://site.com/src/internal/db.js", t)
+	assertUrlsEquals(withImports, "#config", "https://site.com/app.js", "https://site.com/config.js", t)
+	// unmatched "#" specifiers are still fragments of the importer's URL
+	assertUrlsEquals(withImports, "#other", "https://site.com/app.js", "https://site.com/app.js#other", t)
+	// without subpath imports, "#" specifiers are fragments even with a matching key
+	assertUrlsEquals(m, "#config", "https://site.com/app.js", "https://site.com/app.js#config", t)
+
+	// the "#" keys are kept as they are by Rebase
+	if err := withImports.Rebase(mapUrl, nil); err != nil {
+		t.Fatal(err)
+	}
+	assertUrlsEquals(withImports, "#internal/db", "https://site.com/app.js", "https://site.com/src/internal/db.js", t)
+
+	if _, ok := m.GetImports()["#internal/*"]; ok {
+		t.Error("expected the original map to be left untouched")
+	}
+}
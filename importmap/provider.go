@@ -0,0 +1,196 @@
+package importmap
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Provider is a CDN serving npm packages
+type Provider string
+
+const (
+	// ProviderJSPM serves packages from ga.jspm.io
+	ProviderJSPM Provider = "jspm.io"
+	// ProviderESMSH serves packages from esm.sh
+	ProviderESMSH Provider = "esm.sh"
+	// ProviderJSDelivr serves packages from cdn.jsdelivr.net
+	ProviderJSDelivr Provider = "jsdelivr"
+	// ProviderUnpkg serves packages from unpkg.com
+	ProviderUnpkg Provider = "unpkg"
+)
+
+// providerUrls are the URLs of the packages of each provider: the package reference goes between
+// the prefix and the suffix, the suffix being left out for directories
+var providerUrls = map[Provider]struct{ origin, prefix, suffix string }{
+	ProviderJSPM:     {"https://ga.jspm.io/", "https://ga.jspm.io/npm:", ""},
+	ProviderESMSH:    {"https://esm.sh/", "https://esm.sh/", ""},
+	ProviderJSDelivr: {"https://cdn.jsdelivr.net/", "https://cdn.jsdelivr.net/npm/", "/+esm"},
+	ProviderUnpkg:    {"https://unpkg.com/", "https://unpkg.com/", "?module"},
+}
+
+// esmshBuildPrefix matches the build version prefixes of esm.sh paths, like "/v135" or "/stable"
+var esmshBuildPrefix = regexp.MustCompile(`^/(v\d+|stable)/`)
+
+// PackageRef references a file or an export of an npm package
+type PackageRef struct {
+	// Name is the name of the package, scope included, e.g. "@lit/reactive-element"
+	Name string
+	// Version is the version, range or tag of the package, empty for the latest version
+	Version string
+	// Subpath is the path inside the package, starting with "/" when not empty
+	Subpath string
+}
+
+// String returns the reference as written in npm: specifiers, e.g. "preact@10.19.0/hooks"
+func (r PackageRef) String() string {
+	ref := r.Name
+	if r.Version != "" {
+		ref += "@" + r.Version
+	}
+	return ref + r.Subpath
+}
+
+// ParsePackageRef parses a package reference like "react", "react@18" or "@scope/pkg@1.0.0/sub"
+func ParsePackageRef(ref string) (PackageRef, bool) {
+	ref = strings.TrimPrefix(ref, "/")
+	nameEnd := 0
+	if strings.HasPrefix(ref, "@") {
+		slash := strings.IndexByte(ref, '/')
+		if slash < 2 {
+			return PackageRef{}, false
+		}
+		nameEnd = slash + 1
+	}
+	rest := ref[nameEnd:]
+	end := strings.IndexAny(rest, "@/")
+	if end < 0 {
+		end = len(rest)
+	}
+
+	result := PackageRef{Name: ref[:nameEnd+end]}
+	rest = rest[end:]
+	if strings.HasPrefix(rest, "@") {
+		version, subpath, _ := strings.Cut(rest[1:], "/")
+		result.Version = version
+		if subpath != "" || strings.HasSuffix(rest, "/") {
+			result.Subpath = "/" + subpath
+		}
+	} else {
+		result.Subpath = rest
+	}
+	if result.Name == "" || strings.HasSuffix(result.Name, "/") {
+		return PackageRef{}, false
+	}
+	return result, true
+}
+
+// PackageURL returns the URL of a package reference on the provider
+func (p Provider) PackageURL(ref PackageRef) (string, error) {
+	urls, ok := providerUrls[p]
+	if !ok {
+		return "", fmt.Errorf("unknown provider %q", p)
+	}
+	if strings.HasSuffix(ref.Subpath, "/") {
+		return urls.prefix + ref.String(), nil
+	}
+	return urls.prefix + ref.String() + urls.suffix, nil
+}
+
+// ParsePackageURL returns the package reference of a URL of the provider, and false for the
+// URLs which aren't npm packages of the provider.
+func (p Provider) ParsePackageURL(rawUrl string) (PackageRef, bool) {
+	urls, ok := providerUrls[p]
+	if !ok || !strings.HasPrefix(rawUrl, urls.origin) {
+		return PackageRef{}, false
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return PackageRef{}, false
+	}
+
+	path := u.Path
+	switch p {
+	case ProviderJSPM:
+		if path, ok = strings.CutPrefix(path, "/npm:"); !ok {
+			return PackageRef{}, false
+		}
+	case ProviderESMSH:
+		path = esmshBuildPrefix.ReplaceAllString(path, "/")
+		if strings.HasPrefix(path, "/jsr/") || strings.HasPrefix(path, "/gh/") {
+			return PackageRef{}, false
+		}
+	case ProviderJSDelivr:
+		if path, ok = strings.CutPrefix(path, "/npm/"); !ok {
+			return PackageRef{}, false
+		}
+		path = strings.TrimSuffix(path, "/+esm")
+	}
+	return ParsePackageRef(path)
+}
+
+// ConvertProvider returns a copy of m with the package URLs of the from provider rewritten to
+// the URLs of the same packages, versions and subpaths on the to provider, in the targets as well
+// as the scope keys. The scope of the whole origin of from becomes the one of to.
+//
+// The integrity values of the rewritten URLs are dropped, since the providers serve different
+// builds of the packages.
+func ConvertProvider(m IImportMap, from Provider, to Provider) (IImportMap, error) {
+	fromUrls, ok := providerUrls[from]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", from)
+	}
+	toUrls, ok := providerUrls[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", to)
+	}
+
+	convert := func(rawUrl string) (string, error) {
+		if rawUrl == fromUrls.origin {
+			return toUrls.origin, nil
+		}
+		ref, ok := from.ParsePackageURL(rawUrl)
+		if !ok {
+			return rawUrl, nil
+		}
+		return to.PackageURL(ref)
+	}
+	convertSpecifierMap := func(specifierMap map[string]string) (map[string]string, error) {
+		result := make(map[string]string, len(specifierMap))
+		for key, target := range specifierMap {
+			converted, err := convert(target)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	}
+
+	result := deepCopy(m)
+	imports, err := convertSpecifierMap(result.imports)
+	if err != nil {
+		return nil, err
+	}
+	result.imports = imports
+
+	scopes := make(Scopes, len(result.scopes))
+	for scopeKey, scope := range result.scopes {
+		convertedKey, err := convert(scopeKey)
+		if err != nil {
+			return nil, err
+		}
+		if scopes[convertedKey], err = convertSpecifierMap(scope); err != nil {
+			return nil, err
+		}
+	}
+	result.scopes = scopes
+
+	for target := range result.integrity {
+		if converted, err := convert(target); err != nil || converted != target {
+			delete(result.integrity, target)
+		}
+	}
+	return result, nil
+}
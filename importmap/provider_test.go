@@ -0,0 +1,93 @@
+package importmap
+
+import (
+	"testing"
+)
+
+func TestParsePackageURL(t *testing.T) {
+	cases := []struct {
+		provider Provider
+		url      string
+		expected PackageRef
+	}{
+		{ProviderESMSH, "https://esm.sh/react@18.2.0", PackageRef{"react", "18.2.0", ""}},
+		{ProviderESMSH, "https://esm.sh/v135/preact@10.19.0/hooks?target=es2022", PackageRef{"preact", "10.19.0", "/hooks"}},
+		{ProviderJSDelivr, "https://cdn.jsdelivr.net/npm/@lit/reactive-element@2.0.4/+esm", PackageRef{"@lit/reactive-element", "2.0.4", ""}},
+		{ProviderUnpkg, "https://unpkg.com/lodash-es@4.17.21/debounce.js?module", PackageRef{"lodash-es", "4.17.21", "/debounce.js"}},
+		{ProviderJSPM, "https://ga.jspm.io/npm:react-dom@18.2.0/", PackageRef{"react-dom", "18.2.0", "/"}},
+		{ProviderJSPM, "https://ga.jspm.io/npm:lit", PackageRef{"lit", "", ""}},
+	}
+	for _, c := range cases {
+		ref, ok := c.provider.ParsePackageURL(c.url)
+		if !ok || ref != c.expected {
+			t.Errorf("expected %+v for %s, got %+v", c.expected, c.url, ref)
+		}
+	}
+
+	for _, rawUrl := range []string{"https://esm.sh/jsr/@std/path@1", "https://unpkg.com/react", "https://cdn.jsdelivr.net/gh/user/repo/file.js"} {
+		for _, provider := range []Provider{ProviderESMSH, ProviderJSDelivr} {
+			if ref, ok := provider.ParsePackageURL(rawUrl); ok {
+				t.Errorf("expected %s not to be a %s package, got %+v", rawUrl, provider, ref)
+			}
+		}
+	}
+}
+
+func TestConvertProvider(t *testing.T) {
+	m, err := Parse([]byte(`{
+		"imports": {
+			"react": "https://ga.jspm.io/npm:react@18.2.0/index.js",
+			"@lit/reactive-element/": "https://ga.jspm.io/npm:@lit/reactive-element@2.0.4/",
+			"local": "/local.js"
+		},
+		"scopes": {
+			"https://ga.jspm.io/": {
+				"scheduler": "https://ga.jspm.io/npm:scheduler@0.23.0/index.js"
+			}
+		},
+		"integrity": {
+			"https://ga.jspm.io/npm:react@18.2.0/index.js": "sha384-stale",
+			"https://example.invalid/other.js": "sha384-kept"
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := ConvertProvider(m, ProviderJSPM, ProviderJSDelivr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imports := converted.GetImports()
+	expected := Imports{
+		"react":                  "https://cdn.jsdelivr.net/npm/react@18.2.0/index.js/+esm",
+		"@lit/reactive-element/": "https://cdn.jsdelivr.net/npm/@lit/reactive-element@2.0.4/",
+		"local":                  "/local.js",
+	}
+	for key, target := range expected {
+		if imports[key] != target {
+			t.Errorf("expected %s for %s, got %s", target, key, imports[key])
+		}
+	}
+
+	scope := converted.GetScopes()["https://cdn.jsdelivr.net/"]
+	if scope["scheduler"] != "https://cdn.jsdelivr.net/npm/scheduler@0.23.0/index.js/+esm" {
+		t.Errorf("expected the scope to move to the new provider, got %v", converted.GetScopes())
+	}
+
+	integrity := converted.GetIntegrity()
+	if _, ok := integrity["https://ga.jspm.io/npm:react@18.2.0/index.js"]; ok {
+		t.Error("expected the integrity of the converted target to be dropped")
+	}
+	if integrity["https://example.invalid/other.js"] != "sha384-kept" {
+		t.Errorf("expected the integrity of other URLs to be kept, got %v", integrity)
+	}
+
+	if m.GetImports()["react"] != "https://ga.jspm.io/npm:react@18.2.0/index.js" {
+		t.Error("expected the original map to be left untouched")
+	}
+	if _, err = ConvertProvider(m, ProviderJSPM, "skypack"); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
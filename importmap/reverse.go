@@ -0,0 +1,48 @@
+package importmap
+
+import (
+	"sort"
+	"strings"
+)
+
+// ResolveReverse is an implementation of the IImportMap interface.
+func (i *importMap) ResolveReverse(rawUrl string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	rawUrl = normalizeUrlString(rawUrl)
+	found := make(map[string]bool)
+
+	collect := func(specifierMap map[string]string) {
+		for key, target := range specifierMap {
+			if !isPlain(key) {
+				continue
+			}
+			target = normalizeUrlString(i.canonicalUrl(target))
+			switch {
+			case strings.HasSuffix(key, "/") && strings.HasSuffix(target, "/"):
+				if strings.HasPrefix(rawUrl, target) {
+					found[key+rawUrl[len(target):]] = true
+				}
+			case strings.HasSuffix(key, "*") && strings.Count(target, "*") == 1:
+				prefix, suffix, _ := strings.Cut(target, "*")
+				if len(rawUrl) >= len(prefix)+len(suffix) && strings.HasPrefix(rawUrl, prefix) && strings.HasSuffix(rawUrl, suffix) {
+					found[key[:len(key)-1]+rawUrl[len(prefix):len(rawUrl)-len(suffix)]] = true
+				}
+			case target == rawUrl:
+				found[key] = true
+			}
+		}
+	}
+
+	collect(i.imports)
+	for _, scope := range i.scopes {
+		collect(scope)
+	}
+
+	specifiers := make([]string, 0, len(found))
+	for specifier := range found {
+		specifiers = append(specifiers, specifier)
+	}
+	sort.Strings(specifiers)
+	return specifiers
+}
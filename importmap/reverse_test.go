@@ -0,0 +1,35 @@
+package importmap
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestResolveReverse(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{
+			"react":           "https://esm.sh/react@18",
+			"lodash/":         "https://esm.sh/lodash@4/",
+			"local":           "./local.js",
+			"https://a.com/x": "https://esm.sh/react@18",
+		},
+		Scopes: Scopes{
+			"/legacy/": {"preact/compat": "https://esm.sh/react@18", "react": "https://esm.sh/react@17"},
+		},
+	}))
+
+	cases := map[string][]string{
+		"https://esm.sh/react@18":          {"preact/compat", "react"},
+		"https://esm.sh/react@17":          {"react"},
+		"https://esm.sh/lodash@4/chunk.js": {"lodash/chunk.js"},
+		"https://site.com/local.js":        {"local"},
+		"https://esm.sh/vue@3":             {},
+	}
+	for rawUrl, expected := range cases {
+		if actual := m.ResolveReverse(rawUrl); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("ResolveReverse(%q) = %v, expected %v", rawUrl, actual, expected)
+		}
+	}
+}
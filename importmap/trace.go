@@ -0,0 +1,71 @@
+package importmap
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// TraceKind is the kind of a resolution step
+type TraceKind string
+
+const (
+	// TraceParent records the URL the parent was resolved to
+	TraceParent TraceKind = "parent"
+	// TraceSpecifier records how the specifier was parsed
+	TraceSpecifier TraceKind = "specifier"
+	// TraceScope records a scope considered for the parent
+	TraceScope TraceKind = "scope"
+	// TraceRebase records a URL specifier rebased to look it up again
+	TraceRebase TraceKind = "rebase"
+	// TraceMatch records the entry chosen
+	TraceMatch TraceKind = "match"
+	// TraceResult records the final URL, or why there is none
+	TraceResult TraceKind = "result"
+)
+
+// TraceStep is a decision of a resolution, see ResolveTrace
+type TraceStep struct {
+	Kind    TraceKind
+	Message string
+}
+
+func (s TraceStep) String() string {
+	return string(s.Kind) + ": " + s.Message
+}
+
+// tracer collects the steps of a resolution. The nil tracer discards them, so resolutions
+// without tracing don't pay for formatting.
+type tracer struct {
+	steps []TraceStep
+}
+
+func (t *tracer) add(kind TraceKind, format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, TraceStep{Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+func (t *tracer) result(resolved string, err error) {
+	if err != nil {
+		t.add(TraceResult, "failed: %v", err)
+		return
+	}
+	t.add(TraceResult, "resolved to %s", resolved)
+}
+
+// traceRebase rebases a URL specifier against the map URL and rootUrl, recording the change
+func (i *importMap) traceRebase(specifier string, rootUrl *url.URL, trace *tracer) (string, error) {
+	rebased, err := rebase(specifier, i.mapUrl, rootUrl)
+	if err == nil && rebased != specifier {
+		trace.add(TraceRebase, "%s rebased to %s", specifier, rebased)
+	}
+	return rebased, err
+}
+
+// ResolveTrace implements the IImportMap interface
+func (i *importMap) ResolveTrace(specifier string, parentUrl *url.URL) (Resolution, []TraceStep, error) {
+	trace := &tracer{}
+	resolution, err := i.resolveDetailed(specifier, parentUrl, trace)
+	return resolution, trace.steps, err
+}
@@ -0,0 +1,65 @@
+package importmap
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestResolveTrace(t *testing.T) {
+	mapUrl, _ := url.Parse("https://site.com/")
+	m, err := New(WithMapUrl(mapUrl), WithMap(Data{
+		Imports: Imports{"react": "/react-18.js", "/app/old.js": "/app/new.js"},
+		Scopes:  Scopes{"/legacy/": {"react": "/react-17.js"}, "/other/": {"vue": "/vue.js"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	legacyParent, _ := url.Parse("https://site.com/legacy/index.js")
+	resolution, steps, err := m.ResolveTrace("react", legacyParent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolution.URL != "https://site.com/react-17.js" || resolution.Scope != "/legacy/" {
+		t.Errorf("unexpected resolution %+v", resolution)
+	}
+	kinds := make([]TraceKind, len(steps))
+	for i, step := range steps {
+		kinds[i] = step.Kind
+	}
+	expected := []TraceKind{TraceParent, TraceSpecifier, TraceScope, TraceMatch, TraceResult}
+	if len(kinds) != len(expected) {
+		t.Fatalf("expected the steps %v, got %v", expected, steps)
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Fatalf("expected the steps %v, got %v", expected, steps)
+		}
+	}
+	if last := steps[len(steps)-1].String(); last != "result: resolved to https://site.com/react-17.js" {
+		t.Errorf("unexpected final step %q", last)
+	}
+
+	_, steps, err = m.ResolveTrace("https://site.com/app/old.js", mapUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rebased bool
+	for _, step := range steps {
+		rebased = rebased || (step.Kind == TraceRebase && strings.Contains(step.Message, "rebased to /app/old.js"))
+	}
+	if !rebased {
+		t.Errorf("expected the rebase of the URL specifier in the trace, got %v", steps)
+	}
+
+	_, steps, err = m.ResolveTrace("missing", legacyParent)
+	var unresolved *UnresolvedError
+	if !errors.As(err, &unresolved) {
+		t.Fatalf("expected an *UnresolvedError, got %v", err)
+	}
+	if last := steps[len(steps)-1]; last.Kind != TraceResult || !strings.HasPrefix(last.Message, "failed: ") {
+		t.Errorf("expected the failure as the last step, got %v", last)
+	}
+}
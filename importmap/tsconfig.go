@@ -0,0 +1,146 @@
+package importmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tsConfig holds the module resolution fields of a tsconfig.json file
+type tsConfig struct {
+	Extends         json.RawMessage `json:"extends"`
+	CompilerOptions struct {
+		BaseUrl *string             `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// tsPaths are the paths of a tsconfig.json file with the directory of the config defining them,
+// and the baseUrl, absolute, when set
+type tsPaths struct {
+	paths   map[string][]string
+	dir     string
+	baseDir string
+}
+
+// FromTSConfig converts the compilerOptions.paths of a tsconfig.json file, or of the configs it
+// extends, into the imports of an import map, so the path aliases of TypeScript and the ones of
+// the bundle can't drift apart. The targets are resolved against compilerOptions.baseUrl, or the
+// directory of the config defining the paths, and written relative to the file, which is the
+// map URL unless one is given in opts.
+//
+// "alias/*" patterns become "alias/" prefixes, other patterns with a "*" are kept as wildcards.
+// Import maps have no fallbacks, so only the first target of each path is used.
+func FromTSConfig(path string, opts ...Option) (IImportMap, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := loadTSPaths(absPath, map[string]bool{})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	imports := make(Imports)
+	if resolved.baseDir != "" {
+		resolved.dir = resolved.baseDir
+	}
+	if resolved.paths != nil {
+		keys := make([]string, 0, len(resolved.paths))
+		for key := range resolved.paths {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			targets := resolved.paths[key]
+			if len(targets) == 0 {
+				continue
+			}
+			target := filepath.Join(resolved.dir, filepath.FromSlash(targets[0]))
+			target, err = filepath.Rel(filepath.Dir(absPath), target)
+			if err != nil {
+				return nil, err
+			}
+			target = filepath.ToSlash(target)
+			if !strings.HasPrefix(target, "../") {
+				target = "./" + target
+			}
+			if strings.HasSuffix(key, "/*") && strings.HasSuffix(target, "/*") {
+				key, target = strings.TrimSuffix(key, "*"), strings.TrimSuffix(target, "*")
+			}
+			imports[key] = target
+		}
+	}
+
+	fileUrl := FileURL(absPath)
+	return New(append([]Option{WithMapUrl(fileUrl)}, append(opts, WithMap(Data{Imports: imports}))...)...)
+}
+
+// loadTSPaths returns the paths and baseUrl of the tsconfig.json file at path, inherited from the
+// local configs it extends when it doesn't set them. Configs extended from packages are ignored, since they
+// don't define project paths.
+func loadTSPaths(path string, visited map[string]bool) (*tsPaths, error) {
+	if visited[path] {
+		return nil, fmt.Errorf("%s extends itself", path)
+	}
+	visited[path] = true
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if contents, err = decodeText(contents); err != nil {
+		return nil, err
+	}
+	config := tsConfig{}
+	if err = json.Unmarshal(stripJSONC(contents), &config); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+
+	var extends []string
+	if len(config.Extends) > 0 {
+		var single string
+		if err = json.Unmarshal(config.Extends, &single); err == nil {
+			extends = []string{single}
+		} else if err = json.Unmarshal(config.Extends, &extends); err != nil {
+			return nil, fmt.Errorf("invalid extends: %w", err)
+		}
+	}
+	// the last extended config wins, like in TypeScript
+	inherited := &tsPaths{}
+	for _, parent := range extends {
+		if !strings.HasPrefix(parent, ".") && !filepath.IsAbs(parent) {
+			continue
+		}
+		parentPath := filepath.Join(dir, parent)
+		if filepath.IsAbs(parent) {
+			parentPath = parent
+		}
+		if filepath.Ext(parentPath) != ".json" {
+			parentPath += ".json"
+		}
+		parentPaths, err := loadTSPaths(parentPath, visited)
+		if err != nil {
+			return nil, err
+		}
+		if parentPaths.paths != nil {
+			inherited.paths, inherited.dir = parentPaths.paths, parentPaths.dir
+		}
+		if parentPaths.baseDir != "" {
+			inherited.baseDir = parentPaths.baseDir
+		}
+	}
+
+	options := config.CompilerOptions
+	if options.Paths != nil {
+		inherited.paths, inherited.dir = options.Paths, dir
+	}
+	if options.BaseUrl != nil {
+		inherited.baseDir = filepath.Join(dir, filepath.FromSlash(*options.BaseUrl))
+	}
+	return inherited, nil
+}
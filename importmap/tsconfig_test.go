@@ -0,0 +1,74 @@
+package importmap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromTSConfig(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tsconfig.base.json": `{
+			"compilerOptions": {
+				"baseUrl": "./src",
+				"paths": {"@shared/*": ["../shared/*"]}
+			}
+		}`,
+		"app/tsconfig.json": `{
+			// the paths are relative to the baseUrl of the base config
+			"extends": ["@tsconfig/strictest", "../tsconfig.base"],
+			"compilerOptions": {
+				"strict": true,
+				"paths": {
+					"@/*": ["components/*", "fallback/*"],
+					"config": ["config/index.ts"],
+					"icons/*.svg": ["../assets/icons/*.svg"],
+				},
+			},
+		}`,
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := FromTSConfig(filepath.Join(dir, "app", "tsconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Imports{
+		"@/":          "../src/components/",
+		"config":      "../src/config/index.ts",
+		"icons/*.svg": "../assets/icons/*.svg",
+	}
+	if len(m.GetImports()) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, m.GetImports())
+	}
+	for key, target := range expected {
+		if m.GetImports()[key] != target {
+			t.Errorf("expected %s for %s, got %s", target, key, m.GetImports()[key])
+		}
+	}
+
+	resolved, err := m.Resolve("@/button.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectedUrl := "file://" + filepath.ToSlash(filepath.Join(dir, "src", "components", "button.ts")); resolved != expectedUrl {
+		t.Errorf("expected %s, got %s", expectedUrl, resolved)
+	}
+
+	m, err = FromTSConfig(filepath.Join(dir, "tsconfig.base.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GetImports()["@shared/"] != "./shared/" {
+		t.Errorf("expected the paths of the base config relative to its baseUrl, got %v", m.GetImports())
+	}
+}
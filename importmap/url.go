@@ -10,13 +10,21 @@ func resolve(inputUrl string, mapUrl *url.URL, rootUrl *url.URL) (string, error)
 	if strings.HasPrefix(inputUrl, "/") {
 		if rootUrl != nil {
 			var tempUrl string
-			if inputUrl[1] == '/' {
+			if len(inputUrl) > 1 && inputUrl[1] == '/' {
 				tempUrl = inputUrl[1:]
 			} else {
 				tempUrl = inputUrl
 			}
 
-			return url.JoinPath(rootUrl.String(), ".", tempUrl)
+			// the query and fragment are kept apart, joining them would escape them into the path
+			u, err := url.Parse(tempUrl)
+			if err != nil {
+				return "", err
+			}
+			joined := rootUrl.JoinPath(".", u.EscapedPath())
+			joined.RawQuery, joined.ForceQuery = u.RawQuery, u.ForceQuery
+			joined.Fragment, joined.RawFragment = u.Fragment, u.RawFragment
+			return joined.String(), nil
 		} else {
 			return inputUrl, nil
 		}
@@ -76,14 +84,129 @@ func sameOrigin(inputUrl *url.URL, baseUrl *url.URL) bool {
 	if inputUrl == nil || baseUrl == nil {
 		return false
 	}
-	return inputUrl.Scheme == baseUrl.Scheme && inputUrl.Host == baseUrl.Host && inputUrl.Port() == baseUrl.Port()
+	inputUrl, baseUrl = normalizeUrl(inputUrl), normalizeUrl(baseUrl)
+	return inputUrl.Scheme == baseUrl.Scheme && inputUrl.Host == baseUrl.Host
+}
+
+// defaultPorts are the ports implied by the schemes of the URL standard
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"ftp":   "21",
+}
+
+// normalizeUrl returns a copy of u with its scheme and host lowercased and the port left out
+// when it is the default port of the scheme, so equivalent URLs serialize the same way.
+func normalizeUrl(u *url.URL) *url.URL {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.Host = strings.ToLower(c.Host)
+	if port := c.Port(); port != "" && port == defaultPorts[c.Scheme] {
+		c.Host = strings.TrimSuffix(c.Host, ":"+port)
+	}
+	return &c
+}
+
+// normalizeUrlString normalizes an absolute URL string, leaving anything else untouched.
+func normalizeUrlString(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Scheme == "" {
+		return rawUrl
+	}
+	return normalizeUrl(u).String()
+}
+
+// SpecifierKind classifies an import specifier
+type SpecifierKind int
+
+const (
+	// SpecifierBare is a bare specifier like "react" or "lodash/chunk" which can only be resolved through the map
+	SpecifierBare SpecifierKind = iota
+	// SpecifierRelative is a specifier starting with "/", "./", "../", "#" or "?", resolved against the parent URL
+	SpecifierRelative
+	// SpecifierURL is an absolute URL with a valid scheme like "https://esm.sh/react" or "node:fs"
+	SpecifierURL
+)
+
+// String returns a human-readable name of the kind
+func (k SpecifierKind) String() string {
+	switch k {
+	case SpecifierRelative:
+		return "relative"
+	case SpecifierURL:
+		return "url"
+	default:
+		return "bare"
+	}
+}
+
+// specialSchemes are the schemes for which the URL standard requires a host
+var specialSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ws":    true,
+	"wss":   true,
+	"ftp":   true,
+}
+
+// ParseSpecifier implements the "parse a URL-like import specifier" algorithm of the import maps spec.
+//
+// Specifiers starting with "/", "./" or "../" are resolved against baseUrl (they are returned unresolved
+// when baseUrl is nil). Fragment-only and query-only specifiers like "#foo" or "?v=2" are resolved the
+// same way: they refer to the parent module itself, so they never match the keys of the map, which
+// the spec leaves plain. Any other specifier is URL-like only if it is an absolute URL with a valid
+// scheme. Everything else, including URL-like specifiers that fail to parse, is a bare specifier and
+// the returned URL is nil.
+func ParseSpecifier(specifier string, baseUrl *url.URL) (SpecifierKind, *url.URL) {
+	if isRelative(specifier) || strings.HasPrefix(specifier, "#") || strings.HasPrefix(specifier, "?") {
+		u, err := url.Parse(specifier)
+		if err != nil {
+			return SpecifierBare, nil
+		}
+		if baseUrl != nil {
+			u = baseUrl.ResolveReference(u)
+		}
+		return SpecifierRelative, u
+	}
+
+	colon := strings.IndexByte(specifier, ':')
+	if colon <= 0 || !isValidScheme(specifier[:colon]) {
+		return SpecifierBare, nil
+	}
+
+	u, err := url.Parse(specifier)
+	if err != nil || u.Scheme == "" {
+		return SpecifierBare, nil
+	}
+	if specialSchemes[u.Scheme] && u.Host == "" {
+		return SpecifierBare, nil
+	}
+	return SpecifierURL, u
+}
+
+// isValidScheme reports whether scheme matches the URL standard's scheme grammar:
+// an ASCII letter followed by letters, digits, "+", "-" or ".".
+func isValidScheme(scheme string) bool {
+	for idx, c := range scheme {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case idx > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return scheme != ""
 }
 
 func isUrl(inputUrl string) bool {
-	_, err := url.ParseRequestURI(inputUrl)
-	return err == nil
+	kind, _ := ParseSpecifier(inputUrl, nil)
+	return kind == SpecifierURL
 }
 
+// isRelative reports whether specifier starts with "/", "./" or "../", the relative URLs the spec
+// treats as URL-like.
 func isRelative(specifier string) bool {
 	return strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") || strings.HasPrefix(specifier, "/")
 }
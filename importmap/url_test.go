@@ -36,3 +36,101 @@ func TestRebaseUrlNested(t *testing.T) {
 		t.Errorf("expected %s, got %s", "file:///test/", sut)
 	}
 }
+
+func TestParseSpecifier(t *testing.T) {
+	baseUrl, _ := url.Parse("https://site.com/app/main.js")
+
+	cases := []struct {
+		specifier string
+		kind      SpecifierKind
+		url       string
+	}{
+		{"react", SpecifierBare, ""},
+		{"@scope/pkg/sub.js", SpecifierBare, ""},
+		{"lodash/chunk", SpecifierBare, ""},
+		{"1foo:bar", SpecifierBare, ""},
+		{"https:no-host", SpecifierBare, ""},
+		{"./dep.js", SpecifierRelative, "https://site.com/app/dep.js"},
+		{"../dep.js", SpecifierRelative, "https://site.com/dep.js"},
+		{"/dep.js", SpecifierRelative, "https://site.com/dep.js"},
+		{"https://esm.sh/react", SpecifierURL, "https://esm.sh/react"},
+		{"node:fs", SpecifierURL, "node:fs"},
+		{"#foo", SpecifierRelative, "https://site.com/app/main.js#foo"},
+		{"?v=2", SpecifierRelative, "https://site.com/app/main.js?v=2"},
+	}
+
+	for _, c := range cases {
+		kind, u := ParseSpecifier(c.specifier, baseUrl)
+		if kind != c.kind {
+			t.Errorf("%s: expected kind %s, got %s", c.specifier, c.kind, kind)
+			continue
+		}
+		if c.url == "" && u != nil {
+			t.Errorf("%s: expected no url, got %s", c.specifier, u)
+		}
+		if c.url != "" && (u == nil || u.String() != c.url) {
+			t.Errorf("%s: expected %s, got %v", c.specifier, c.url, u)
+		}
+	}
+}
+
+func TestOriginNormalization(t *testing.T) {
+	a, _ := url.Parse("HTTPS://CDN.example:443/x.js")
+	b, _ := url.Parse("https://cdn.example/y.js")
+	c, _ := url.Parse("https://cdn.example:8443/y.js")
+
+	if !sameOrigin(a, b) {
+		t.Error("expected default ports and host case to be ignored")
+	}
+	if sameOrigin(b, c) {
+		t.Error("expected non-default ports to be significant")
+	}
+
+	baseUrl, _ := url.Parse("https://site.com/")
+	m, _ := New(WithMapUrl(baseUrl), WithMap(Data{
+		Imports: Imports{"dep": "/dep.js"},
+		Scopes: Scopes{
+			"https://cdn.example:443/": {"dep": "/cdn-dep.js"},
+		},
+	}))
+	assertUrlsEquals(m, "dep", "https://CDN.example/pkg/index.js", "https://site.com/cdn-dep.js", t)
+}
+
+func TestQueryAndFragment(t *testing.T) {
+	m, err := New(WithMap(Data{Imports: Imports{
+		"react": "https://esm.sh/react?dev",
+		"app":   "/js/app.js?v=2#main",
+		"lib/":  "./lib/",
+	}}), WithMapUrl(&url.URL{Scheme: "https", Host: "example.com", Path: "/app/importmap.json"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"react":          "https://esm.sh/react?dev",
+		"app":            "https://example.com/js/app.js?v=2#main",
+		"lib/a.js?x#top": "https://example.com/app/lib/a.js?x#top",
+		"/root.js?":      "https://example.com/root.js?",
+	}
+	check := func() {
+		t.Helper()
+		for specifier, resolved := range expected {
+			actual, err := m.Resolve(specifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual != resolved {
+				t.Errorf("expected %s to resolve to %s, got %s", specifier, resolved, actual)
+			}
+		}
+	}
+	check()
+
+	if err = m.Rebase(&url.URL{Scheme: "https", Host: "example.com", Path: "/other/importmap.json"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if target := m.GetImports()["app"]; target != "/js/app.js?v=2#main" {
+		t.Errorf("expected the rebased target to keep its query and fragment, got %s", target)
+	}
+	check()
+}
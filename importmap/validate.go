@@ -0,0 +1,69 @@
+package importmap
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Validate is an implementation of the IImportMap interface.
+func (i *importMap) Validate() []Diagnostic {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	violations := append([]Diagnostic(nil), i.diagnostics...)
+
+	validateSpecifierMap := func(scope string, specifierMap map[string]string) {
+		for _, key := range sortedKeys(specifierMap) {
+			target := specifierMap[key]
+			if msg := checkEntry(key, target); msg != "" {
+				violations = append(violations, Diagnostic{Scope: scope, Key: key, Message: msg})
+			} else if msg = checkTarget(target); msg != "" {
+				violations = append(violations, Diagnostic{Scope: scope, Key: key, Message: msg})
+			}
+		}
+	}
+
+	validateSpecifierMap("", i.imports)
+	for _, scopeKey := range sortedKeys(i.scopes) {
+		if _, err := url.Parse(scopeKey); err != nil {
+			violations = append(violations, Diagnostic{Scope: scopeKey, Message: "scope keys must be valid URLs", WholeScope: true})
+			continue
+		}
+		validateSpecifierMap(scopeKey, i.scopes[scopeKey])
+	}
+
+	for _, target := range sortedKeys(i.integrity) {
+		if msg := checkTarget(target); msg != "" {
+			violations = append(violations, Diagnostic{Key: target, Message: "integrity keys must be URLs"})
+		} else if _, err := NormalizeIntegrity(i.integrity[target]); err != nil {
+			violations = append(violations, Diagnostic{Key: target, Message: err.Error()})
+		}
+	}
+
+	return violations
+}
+
+// checkTarget returns why target isn't a valid address, or an empty string if it is. Addresses
+// must be absolute URLs or URLs relative to the map starting with "/", "./" or "../".
+func checkTarget(target string) string {
+	if strings.HasPrefix(target, "/") || strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") {
+		if _, err := url.Parse(target); err != nil {
+			return "invalid target: " + err.Error()
+		}
+		return ""
+	}
+	if !isUrl(target) {
+		return "the target must be a URL or start with \"/\", \"./\" or \"../\""
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of m in lexicographic order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
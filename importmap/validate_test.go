@@ -0,0 +1,53 @@
+package importmap
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	m, err := New(WithMap(Data{
+		Imports: Imports{
+			"":        "/empty.js",
+			"react":   "https://esm.sh/react@18",
+			"bare":    "react",
+			"lib/":    "/lib.js",
+			"./local": "./local.js",
+		},
+		Scopes: Scopes{
+			"http://[::1": {"a": "/a.js"},
+			"/admin/":     {"a": "admin-a.js"},
+		},
+		Integrity: Integrity{
+			"https://esm.sh/react@18": "sha384-invalid",
+			"not a url":               "sha384-OLBgp1GsljhM2TJ+sbHjaiH9txEUvgdDTAzHv2P24donTt6/529l+9Ua0vFImLlb",
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	for _, violation := range m.Validate() {
+		messages = append(messages, violation.String())
+	}
+	all := strings.Join(messages, "\n")
+
+	expected := []string{
+		`ignored import map entry ""`,
+		`ignored import map entry "lib/"`,
+		`ignored import map entry "bare": the target must be a URL`,
+		`ignored import map entry "a" in scope "/admin/": the target must be a URL`,
+		`ignored import map scope "http://[::1": scope keys must be valid URLs`,
+		`ignored import map entry "not a url": integrity keys must be URLs`,
+		`ignored import map entry "https://esm.sh/react@18"`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(all, e) {
+			t.Errorf("expected a violation containing %q, got:\n%s", e, all)
+		}
+	}
+	if len(messages) != len(expected) {
+		t.Errorf("expected %d violations, got:\n%s", len(expected), all)
+	}
+}
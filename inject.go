@@ -0,0 +1,59 @@
+package esbuild_plugin_importmap
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+)
+
+// injectHTML replaces the import map of Config.InjectHTMLPath with the residual map, rebased to
+// the directory of the HTML file. The file is only written when its import map changed, so watch
+// mode servers don't reload the page for nothing. Failed builds leave the file untouched.
+func (p *plugin) injectHTML(result *api.BuildResult) error {
+	if len(result.Errors) > 0 {
+		return nil
+	}
+
+	path, err := filepath.Abs(p.config.InjectHTMLPath)
+	if err != nil {
+		return err
+	}
+	data, err := p.residualMap(result, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	injected, err := importmap.InjectHTML(contents, data, p.config.MapFormat...)
+	if err != nil {
+		return fmt.Errorf("failed to inject the import map into %s: %w", path, err)
+	}
+	if p.config.HTMLIntegrityAttributes {
+		outputs, err := p.outputIntegrity(result, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if injected, err = importmap.SetIntegrityAttributes(injected, outputs); err != nil {
+			return fmt.Errorf("failed to set the integrity attributes of %s: %w", path, err)
+		}
+	}
+	if bytes.Equal(injected, contents) {
+		return nil
+	}
+	return os.WriteFile(path, injected, 0o644)
+}
+
+// WithHTMLInjection writes the import map the bundle needs at runtime, the one WithResidualMap
+// emits, into the <script type="importmap"> block of the HTML file at path after each build,
+// adding the block when missing. Local targets are rebased to the directory of the file, so the
+// page and the bundle never drift apart.
+func WithHTMLInjection(path string) Option {
+	return func(config *Config) {
+		config.InjectHTMLPath = path
+	}
+}
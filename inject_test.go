@@ -0,0 +1,75 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginHTMLInjection(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "public", "index.html")
+	if err := os.MkdirAll(filepath.Dir(htmlPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := `<html><head><script type="importmap">{"imports": {"stale": "/stale.js"}}</script></head></html>`
+	if err := os.WriteFile(htmlPath, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"react":   "https://esm.invalid/react@18.2.0",
+			"local":   "file://" + filepath.ToSlash(dir) + "/lib/local.js",
+			"bundled": "https://esm.invalid/bundled.js",
+		}}),
+		WithFetcher(fixtureFetcher{"https://esm.invalid/bundled.js": "export const bundled = 1;"}),
+		WithExternal([]string{"react", "local"}),
+		WithHTMLInjection(htmlPath),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import React from 'react'; import local from 'local'; import {bundled} from 'bundled'; console.log(React, local, bundled);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	m, err := importmap.LoadFromHTML(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imports := m.GetImports()
+	if len(imports) != 2 {
+		t.Fatalf("expected only the external specifiers in the page, got %v", imports)
+	}
+	if imports["react"] != "https://esm.invalid/react@18.2.0" {
+		t.Errorf("expected the remote target to stay absolute, got %s", imports["react"])
+	}
+	if imports["local"] != "file://"+filepath.ToSlash(dir)+"/lib/local.js" {
+		t.Errorf("expected the local target to resolve to the same file, got %s", imports["local"])
+	}
+	contents, err := os.ReadFile(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(contents), `"local": "../lib/local.js"`) {
+		t.Errorf("expected the local target relative to the page, got:\n%s", contents)
+	}
+
+	info, err := os.Stat(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildWithPlugin(t, "import React from 'react'; import local from 'local'; console.log(React, local);", plugin)
+	rebuilt, err := os.Stat(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rebuilt.ModTime().Equal(info.ModTime()) {
+		t.Error("expected the page to be left untouched when its import map didn't change")
+	}
+}
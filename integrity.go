@@ -0,0 +1,54 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+)
+
+// IntegrityBehavior controls what the plugin does with loaded modules not matching the integrity
+// value the import map has for them
+type IntegrityBehavior int
+
+const (
+	// IntegrityError fails the import
+	IntegrityError IntegrityBehavior = iota
+	// IntegrityWarn reports a warning and uses the module anyway
+	IntegrityWarn
+	// IntegrityIgnore doesn't verify modules
+	IntegrityIgnore
+)
+
+// WithIntegrityCheck sets what happens with loaded modules not matching their integrity value.
+// Mismatches fail the build by default.
+func WithIntegrityCheck(behavior IntegrityBehavior) Option {
+	return func(config *Config) {
+		config.IntegrityCheck = behavior
+	}
+}
+
+// verifyIntegrity checks the contents loaded from path against the integrity value the import map
+// of its entry point has for it, returning the warnings to report when mismatches are tolerated.
+func (p *plugin) verifyIntegrity(path string, contents []byte) ([]api.Message, error) {
+	if p.config.IntegrityCheck == IntegrityIgnore {
+		return nil, nil
+	}
+
+	importMap := p.currentMap()
+	if p.hasEntryPointMaps() {
+		importMap = p.mapFor(p.entryPoints.get(path))
+	}
+	integrity, err := importMap.GetIntegrityValue(path, "")
+	if err != nil {
+		return nil, nil
+	}
+
+	if err = importmap.VerifyIntegrity(contents, integrity); err != nil {
+		err = fmt.Errorf("%s: %w", path, err)
+		if p.config.IntegrityCheck == IntegrityWarn {
+			return []api.Message{{Text: err.Error()}}, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,50 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginVerifiesIntegrity(t *testing.T) {
+	const contents = "export const pkg = 'pinned';"
+	integrity, err := importmap.ComputeIntegrity([]byte(contents), "sha384")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := importmap.Data{
+		Imports: importmap.Imports{
+			"pkg":      "https://mirror.invalid/pkg.js",
+			"tampered": "https://mirror.invalid/tampered.js",
+		},
+		Integrity: importmap.Integrity{
+			"https://mirror.invalid/pkg.js":      integrity,
+			"https://mirror.invalid/tampered.js": integrity,
+		},
+	}
+	fetcher := fixtureFetcher{
+		"https://mirror.invalid/pkg.js":      contents,
+		"https://mirror.invalid/tampered.js": "export const pkg = 'tampered';",
+	}
+
+	plugin, err := NewPlugin(WithMap(data), WithFetcher(fetcher))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin); len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	result := buildWithPlugin(t, "import {pkg} from 'tampered'; console.log(pkg);", plugin)
+	if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Text, "integrity mismatch") {
+		t.Errorf("expected an integrity mismatch error, got %v", result.Errors)
+	}
+
+	plugin, err = NewPlugin(WithMap(data), WithFetcher(fetcher), WithIntegrityCheck(IntegrityWarn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = buildWithPlugin(t, "import {pkg} from 'tampered'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 || len(result.Warnings) != 1 {
+		t.Errorf("expected a warning only, got %v and %v", result.Errors, result.Warnings)
+	}
+}
@@ -0,0 +1,93 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"regexp"
+)
+
+// ModuleFormat is the module system a downloaded module is written for
+type ModuleFormat int
+
+const (
+	// FormatUnknown is used for scripts without imports or exports of any module system
+	FormatUnknown ModuleFormat = iota
+	FormatESM
+	FormatCommonJS
+	// FormatUMD is used for modules defining themselves with AMD, CommonJS or a global
+	FormatUMD
+)
+
+func (f ModuleFormat) String() string {
+	switch f {
+	case FormatESM:
+		return "esm"
+	case FormatCommonJS:
+		return "commonjs"
+	case FormatUMD:
+		return "umd"
+	}
+	return "unknown"
+}
+
+// InteropHeuristics are the patterns used to detect the format of downloaded modules,
+// tried in the order ESM, UMD and CommonJS. Nil patterns never match.
+type InteropHeuristics struct {
+	ESM      *regexp.Regexp
+	UMD      *regexp.Regexp
+	CommonJS *regexp.Regexp
+}
+
+// DefaultInteropHeuristics detect the module formats served by CDNs like unpkg and jsDelivr
+var DefaultInteropHeuristics = InteropHeuristics{
+	ESM:      regexp.MustCompile(`(?m)^\s*(import\s*[\w{*'"]|export\s*(default|const|let|var|function|class|async|[{*]))`),
+	UMD:      regexp.MustCompile(`typeof\s+define\s*===?\s*["']function["']\s*&&\s*define\.amd`),
+	CommonJS: regexp.MustCompile(`\bmodule\.exports\b|\bexports\.[\w$]+\s*=|\brequire\s*\(`),
+}
+
+// amdShim hides any AMD loader of the page from UMD modules, so they take their CommonJS branch
+// which esbuild bundles and exposes to ES module importers.
+const amdShim = "var define;\n"
+
+// detectFormat returns the format of a module according to heuristics.
+func (h InteropHeuristics) detectFormat(contents string) ModuleFormat {
+	switch {
+	case h.ESM != nil && h.ESM.MatchString(contents):
+		return FormatESM
+	case h.UMD != nil && h.UMD.MatchString(contents):
+		return FormatUMD
+	case h.CommonJS != nil && h.CommonJS.MatchString(contents):
+		return FormatCommonJS
+	}
+	return FormatUnknown
+}
+
+// interopContents returns the contents of a downloaded module as they are bundled and vendored,
+// converted by interop when Interop is set and loader is a JavaScript one.
+func (p *plugin) interopContents(result *fetchResult, loader api.Loader) string {
+	if p.config.Interop == nil || (loader != api.LoaderJS && loader != api.LoaderJSX) {
+		return result.contents
+	}
+	return interop(result.contents, p.config.Interop.detectFormat(result.contents))
+}
+
+// interop returns the contents of a module in a form esbuild bundles as CommonJS. esbuild wraps
+// CommonJS modules and exposes module.exports to importers, so only UMD modules need changes.
+func interop(contents string, format ModuleFormat) string {
+	if format == FormatUMD {
+		return amdShim + contents
+	}
+	return contents
+}
+
+// WithCommonJSInterop detects CommonJS and UMD modules, e.g. the main files of older packages
+// served by unpkg, and converts them so esbuild bundles them as ES modules. The heuristics
+// default to DefaultInteropHeuristics.
+func WithCommonJSInterop(heuristics ...InteropHeuristics) Option {
+	return func(config *Config) {
+		h := DefaultInteropHeuristics
+		if len(heuristics) > 0 {
+			h = heuristics[0]
+		}
+		config.Interop = &h
+	}
+}
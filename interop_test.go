@@ -0,0 +1,63 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const umdModule = `(function (root, factory) {
+  if (typeof define === 'function' && define.amd) {
+    define([], factory);
+  } else if (typeof module === 'object' && module.exports) {
+    module.exports = factory();
+  } else {
+    root.answer = factory();
+  }
+}(this, function () {
+  return { answer: 42 };
+}));
+`
+
+func TestDetectFormat(t *testing.T) {
+	cases := map[string]ModuleFormat{
+		"import React from 'react';\nexport default React;": FormatESM,
+		"export{a as b};":                    FormatESM,
+		umdModule:                            FormatUMD,
+		"module.exports = require('./lib');": FormatCommonJS,
+		"exports.answer = 42;":               FormatCommonJS,
+		"window.answer = 42;":                FormatUnknown,
+		"const s = 'import x from y'; f(s);": FormatUnknown,
+	}
+	for contents, expected := range cases {
+		if actual := DefaultInteropHeuristics.detectFormat(contents); actual != expected {
+			t.Errorf("detectFormat(%q) = %s, expected %s", contents, actual, expected)
+		}
+	}
+
+	custom := InteropHeuristics{CommonJS: regexp.MustCompile(`\bglobalThis\.`)}
+	if format := custom.detectFormat("globalThis.answer = 42;"); format != FormatCommonJS {
+		t.Errorf("expected custom heuristics to be used, got %s", format)
+	}
+}
+
+func TestPluginCommonJSInterop(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"answer": "https://unpkg.invalid/answer.js"}}),
+		WithFetcher(fixtureFetcher{"https://unpkg.invalid/answer.js": umdModule}),
+		WithCommonJSInterop(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {answer} from 'answer'; console.log(answer);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "var define;") || !strings.Contains(output, "__commonJS") {
+		t.Errorf("expected the UMD module to be bundled as CommonJS, got:\n%s", output)
+	}
+}
@@ -0,0 +1,140 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+)
+
+// onStart prepares the plugin for a build of an api.Context, the first one as well as the
+// incremental and watch mode rebuilds. esbuild runs the start callbacks concurrently, so the
+// steps, which depend on each other through the import map, run in this single callback.
+func (p *plugin) onStart() (api.OnStartResult, error) {
+	var result api.OnStartResult
+
+	p.downloads.reset()
+	p.offline.take()
+	p.entryPoints.reset()
+	if p.reportsUnused() {
+		p.usage.reset()
+	}
+	if p.tracksExternals() {
+		p.externals.reset()
+	}
+	if p.config.OnStats != nil {
+		p.stats.take()
+		p.resolutions.take()
+	}
+	if p.config.ImportMap != nil {
+		p.resolutions.invalidate()
+	}
+
+	if p.config.ImportMapPath != "" {
+		reloaded := p.reloadImportMap()
+		result.Errors = append(result.Errors, reloaded.Errors...)
+	}
+	if p.config.ImportMapURL != "" && !p.config.Offline {
+		result.Warnings = append(result.Warnings, p.refreshImportMapURL()...)
+	}
+	// with resolution warnings, the problems are reported at the imports they affect instead
+	p.mapsMu.Lock()
+	validate := !p.validated && !p.config.ResolutionWarnings
+	p.validated = p.validated || validate
+	p.mapsMu.Unlock()
+	if validate {
+		for _, diagnostic := range p.currentMap().Validate() {
+			result.Warnings = append(result.Warnings, api.Message{Text: diagnostic.String()})
+		}
+	}
+
+	if p.config.LockfilePath != "" {
+		if err := p.loadLockfile(); err != nil {
+			return result, err
+		}
+	}
+	if p.config.Warmup && !p.config.Offline {
+		p.warmup(p.ctx)
+	}
+	return result, nil
+}
+
+// onEnd writes the maps and lockfile of a build, reports its summaries and frees the modules
+// downloaded during the build, which the cache directory and the HTTP cache keep if enabled.
+func (p *plugin) onEnd(result *api.BuildResult) (api.OnEndResult, error) {
+	defer p.downloads.reset()
+
+	if p.config.OnStats != nil {
+		stats := p.stats.take()
+		stats.ResolutionCacheSize, stats.ResolutionCacheHits, stats.ResolutionCacheInvalidations = p.resolutions.take()
+		p.config.OnStats(stats)
+	}
+	if p.config.LockfilePath != "" {
+		if err := p.writeLockfile(result); err != nil {
+			return api.OnEndResult{}, err
+		}
+	}
+	if p.config.VendorDir != "" {
+		if err := p.writeVendorMap(); err != nil {
+			return api.OnEndResult{}, err
+		}
+	}
+	if p.config.ExternalsMapPath != "" {
+		if err := p.writeExternalsMap(); err != nil {
+			return api.OnEndResult{}, err
+		}
+	}
+	if p.config.ResidualMap {
+		if err := p.emitResidualMap(result); err != nil {
+			return api.OnEndResult{}, err
+		}
+	}
+	if p.config.InjectHTMLPath != "" {
+		if err := p.injectHTML(result); err != nil {
+			return api.OnEndResult{}, err
+		}
+	}
+
+	return api.OnEndResult{
+		Errors:   p.offlineErrors(),
+		Warnings: append(p.throttlingWarnings(), p.unusedMappingWarnings(result)...),
+	}, nil
+}
+
+// replaceImportMap makes importMap the map of the next builds.
+func (p *plugin) replaceImportMap(importMap importmap.IImportMap) error {
+	entryPointMaps, err := newEntryPointMaps(p.config, importMap)
+	if err != nil {
+		return err
+	}
+	p.mapsMu.Lock()
+	p.importMap, p.entryPointMaps = importMap, entryPointMaps
+	p.validated = false
+	p.mapsMu.Unlock()
+	p.resolutions.invalidate()
+	return nil
+}
+
+// currentMap returns the import map of the plugin.
+func (p *plugin) currentMap() importmap.IImportMap {
+	p.mapsMu.RLock()
+	defer p.mapsMu.RUnlock()
+	return p.importMap
+}
+
+// refreshImportMapURL downloads the import map at Config.ImportMapURL again, which the HTTP
+// cache turns into a conditional request. The previous map is kept when the download fails.
+func (p *plugin) refreshImportMapURL() []api.Message {
+	importMap, err := loadImportMapURL(p.ctx, p.config, p.fetcher)
+	if err == nil {
+		importMap, err = addPackageImports(p.config, importMap)
+	}
+	if err != nil {
+		return []api.Message{{Text: "failed to refresh the import map, using the previous one: " + err.Error()}}
+	}
+	if importmap.Diff(p.currentMap(), importMap).Empty() {
+		return nil
+	}
+	if err = p.replaceImportMap(importMap); err != nil {
+		return []api.Message{{Text: "failed to refresh the import map, using the previous one: " + err.Error()}}
+	}
+	return nil
+}
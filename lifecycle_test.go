@@ -0,0 +1,108 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPluginRefreshesImportMapURL(t *testing.T) {
+	var version atomic.Int32
+	version.Store(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/importmap.json":
+			target := "./pkg@1.js"
+			if version.Load() == 2 {
+				target = "./pkg@2.js"
+			}
+			_, _ = w.Write([]byte(`{"imports": {"pkg": "` + target + `", "dir/": "./file.js"}}`))
+		case "/pkg@1.js":
+			_, _ = w.Write([]byte("export const pkg = 'v1';"))
+		case "/pkg@2.js":
+			_, _ = w.Write([]byte("export const pkg = 'v2';"))
+		}
+	}))
+	defer server.Close()
+
+	plugin, err := NewPlugin(WithImportMapURL(server.URL + "/importmap.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, ctxErr := api.Context(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, "import {pkg} from 'pkg'; console.log(pkg);"),
+			plugin,
+		},
+	})
+	if ctxErr != nil {
+		t.Fatal(ctxErr)
+	}
+	defer ctx.Dispose()
+
+	result := ctx.Rebuild()
+	if len(result.Errors) > 0 || !strings.Contains(string(result.OutputFiles[0].Contents), `"v1"`) {
+		t.Fatalf("unexpected result %v", result.Errors)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0].Text, `ignored import map entry "dir/"`) {
+		t.Errorf("expected the invalid entry to be reported, got %v", result.Warnings)
+	}
+
+	result = ctx.Rebuild()
+	if len(result.Warnings) != 0 {
+		t.Errorf("expected the unchanged map not to be reported again, got %v", result.Warnings)
+	}
+
+	version.Store(2)
+	result = ctx.Rebuild()
+	if len(result.Errors) > 0 || !strings.Contains(string(result.OutputFiles[0].Contents), `"v2"`) {
+		t.Errorf("expected the refreshed map to be used, got %v", result.Errors)
+	}
+}
+
+// TestPluginReplaceImportMapConcurrently replaces the map while imports are resolved, run it
+// with -race.
+func TestPluginReplaceImportMapConcurrently(t *testing.T) {
+	p := newTestPlugin(t)
+	newMap := func(target string) importmap.IImportMap {
+		m, err := importmap.New(importmap.WithMap(importmap.Data{Imports: importmap.Imports{"pkg": target}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	if err := p.replaceImportMap(newMap("https://cdn.invalid/pkg@1.js")); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				result, err := p.onResolve(api.OnResolveArgs{Path: "pkg", Importer: "/app/index.js", Namespace: "file", Kind: api.ResolveJSImportStatement})
+				if err != nil || !strings.HasPrefix(result.Path, "https://cdn.invalid/pkg@") {
+					t.Errorf("unexpected resolution %v, %v", result.Path, err)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 100; j++ {
+		if err := p.replaceImportMap(newMap(fmt.Sprintf("https://cdn.invalid/pkg@%d.js", j))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,193 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// semaphore is a counting semaphore whose limit can be changed while it is in use.
+type semaphore struct {
+	mu      sync.Mutex
+	limit   int
+	active  int
+	waiters []chan struct{}
+}
+
+func newSemaphore(limit int) *semaphore {
+	if limit < 1 {
+		limit = 1
+	}
+	return &semaphore{limit: limit}
+}
+
+// acquire blocks until a slot is available or ctx is done.
+func (s *semaphore) acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active < s.limit && len(s.waiters) == 0 {
+		s.active++
+		s.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	s.waiters = append(s.waiters, ch)
+	s.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for idx, waiter := range s.waiters {
+			if waiter == ch {
+				s.waiters = append(s.waiters[:idx], s.waiters[idx+1:]...)
+				s.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		s.mu.Unlock()
+		// the slot was granted while we were giving up
+		s.release()
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	s.mu.Lock()
+	s.active--
+	s.wake()
+	s.mu.Unlock()
+}
+
+// setLimit changes the number of slots; active holders are not interrupted.
+func (s *semaphore) setLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	s.mu.Lock()
+	s.limit = limit
+	s.wake()
+	s.mu.Unlock()
+}
+
+func (s *semaphore) getLimit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit
+}
+
+func (s *semaphore) wake() {
+	for s.active < s.limit && len(s.waiters) > 0 {
+		ch := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		s.active++
+		close(ch)
+	}
+}
+
+// fetchLimiter bounds the number of remote downloads, both in total and per origin.
+//
+// The per-origin limit adapts to rate limiting: it is halved every time an origin throttles
+// a request, and grows back by one after as many successful downloads as the current limit.
+type fetchLimiter struct {
+	total   *semaphore
+	perHost int
+
+	mu        sync.Mutex
+	hosts     map[string]*semaphore
+	successes map[string]int
+	throttled map[string]int
+}
+
+func newFetchLimiter(total int, perHost int) *fetchLimiter {
+	return &fetchLimiter{
+		total:     newSemaphore(total),
+		perHost:   perHost,
+		hosts:     make(map[string]*semaphore),
+		successes: make(map[string]int),
+		throttled: make(map[string]int),
+	}
+}
+
+// throttle reduces the parallelism for host after it rate limited a request.
+func (l *fetchLimiter) throttle(host string) {
+	hostSem := l.host(host)
+	hostSem.setLimit(hostSem.getLimit() / 2)
+
+	l.mu.Lock()
+	l.successes[host] = 0
+	l.throttled[host]++
+	l.mu.Unlock()
+}
+
+// succeed records a download from host which wasn't rate limited.
+func (l *fetchLimiter) succeed(host string) {
+	hostSem := l.host(host)
+	limit := hostSem.getLimit()
+	if limit >= l.perHost {
+		return
+	}
+
+	l.mu.Lock()
+	l.successes[host]++
+	grow := l.successes[host] >= limit
+	if grow {
+		l.successes[host] = 0
+	}
+	l.mu.Unlock()
+
+	if grow {
+		hostSem.setLimit(limit + 1)
+	}
+}
+
+// throttledHost describes an origin which rate limited downloads
+type throttledHost struct {
+	host     string
+	requests int
+	limit    int
+}
+
+// takeThrottled returns the origins which rate limited downloads since the last call.
+func (l *fetchLimiter) takeThrottled() []throttledHost {
+	l.mu.Lock()
+	throttled := l.throttled
+	l.throttled = make(map[string]int)
+	l.mu.Unlock()
+
+	result := make([]throttledHost, 0, len(throttled))
+	for host, requests := range throttled {
+		result = append(result, throttledHost{host: host, requests: requests, limit: l.host(host).getLimit()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].host < result[j].host
+	})
+	return result
+}
+
+func (l *fetchLimiter) host(host string) *semaphore {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.hosts[host]
+	if !ok {
+		s = newSemaphore(l.perHost)
+		l.hosts[host] = s
+	}
+	return s
+}
+
+// acquire reserves a download slot for host. The returned function releases it.
+func (l *fetchLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	hostSem := l.host(host)
+	if err := hostSem.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := l.total.acquire(ctx); err != nil {
+		hostSem.release()
+		return nil, err
+	}
+	return func() {
+		l.total.release()
+		hostSem.release()
+	}, nil
+}
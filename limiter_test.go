@@ -0,0 +1,136 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchLimiterPerHost(t *testing.T) {
+	limiter := newFetchLimiter(10, 2)
+
+	var active, peak int32
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.acquire(context.Background(), "esm.sh")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			cur := atomic.AddInt32(&active, 1)
+			for {
+				prev := atomic.LoadInt32(&peak)
+				if cur <= prev || atomic.CompareAndSwapInt32(&peak, prev, cur) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 parallel fetches per host, got %d", peak)
+	}
+}
+
+func TestSemaphoreAcquireCancelled(t *testing.T) {
+	s := newSemaphore(1)
+	if err := s.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.acquire(ctx); err == nil {
+		t.Fatal("expected acquire to fail once the context is done")
+	}
+
+	s.release()
+	if err := s.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultMaxConcurrentFetches(t *testing.T) {
+	n := defaultMaxConcurrentFetches()
+	if n < defaultMaxFetchesPerHost || n > maxDefaultConcurrentFetches {
+		t.Errorf("unexpected default parallelism %d", n)
+	}
+}
+
+func TestFetchLimiterAdaptsToThrottling(t *testing.T) {
+	limiter := newFetchLimiter(10, 6)
+
+	limiter.throttle("esm.sh")
+	limiter.throttle("esm.sh")
+	if limit := limiter.host("esm.sh").getLimit(); limit != 1 {
+		t.Errorf("expected the limit to be halved twice, got %d", limit)
+	}
+	if limit := limiter.host("cdn.jsdelivr.net").getLimit(); limit != 6 {
+		t.Errorf("expected other origins to be unaffected, got %d", limit)
+	}
+
+	for n := 0; n < 20; n++ {
+		limiter.succeed("esm.sh")
+	}
+	if limit := limiter.host("esm.sh").getLimit(); limit != 6 {
+		t.Errorf("expected the limit to recover, got %d", limit)
+	}
+
+	throttled := limiter.takeThrottled()
+	if len(throttled) != 1 || throttled[0].host != "esm.sh" || throttled[0].requests != 2 {
+		t.Errorf("unexpected throttling report %v", throttled)
+	}
+	if len(limiter.takeThrottled()) != 0 {
+		t.Error("expected the report to be reset")
+	}
+}
+
+// peakFetcher records the peak number of downloads in flight
+type peakFetcher struct {
+	active, peak atomic.Int32
+}
+
+func (f *peakFetcher) Fetch(ctx context.Context, rawUrl string) ([]byte, http.Header, string, error) {
+	cur := f.active.Add(1)
+	for {
+		prev := f.peak.Load()
+		if cur <= prev || f.peak.CompareAndSwap(prev, cur) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	f.active.Add(-1)
+	return []byte("export default 1;"), http.Header{}, rawUrl, nil
+}
+
+func TestWithMaxConcurrentFetches(t *testing.T) {
+	fetcher := &peakFetcher{}
+	p := newTestPlugin(t, WithMaxConcurrentFetches(3), WithFetcher(fetcher))
+
+	var wg sync.WaitGroup
+	for n := 0; n < 12; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if _, err := p.fetch(context.Background(), fmt.Sprintf("https://cdn%d.invalid/mod.js", n)); err != nil {
+				t.Error(err)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	if peak := fetcher.peak.Load(); peak > 3 {
+		t.Errorf("expected at most 3 parallel fetches, got %d", peak)
+	}
+}
@@ -0,0 +1,174 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"github.com/evanw/esbuild/pkg/api"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// extensionLoaders are the loaders of the file extensions of modules
+var extensionLoaders = map[string]api.Loader{
+	".js":   api.LoaderJS,
+	".mjs":  api.LoaderJS,
+	".cjs":  api.LoaderJS,
+	".jsx":  api.LoaderJSX,
+	".ts":   api.LoaderTS,
+	".mts":  api.LoaderTS,
+	".cts":  api.LoaderTS,
+	".tsx":  api.LoaderTSX,
+	".json": api.LoaderJSON,
+	".css":  api.LoaderCSS,
+}
+
+// DefaultAssetLoaders are the loaders of the images, fonts and WebAssembly modules mapped by the
+// import map, which are emitted as files next to the bundle
+var DefaultAssetLoaders = map[string]api.Loader{
+	".wasm":  api.LoaderFile,
+	".png":   api.LoaderFile,
+	".jpg":   api.LoaderFile,
+	".jpeg":  api.LoaderFile,
+	".gif":   api.LoaderFile,
+	".webp":  api.LoaderFile,
+	".avif":  api.LoaderFile,
+	".svg":   api.LoaderFile,
+	".ico":   api.LoaderFile,
+	".woff":  api.LoaderFile,
+	".woff2": api.LoaderFile,
+	".ttf":   api.LoaderFile,
+	".otf":   api.LoaderFile,
+	".eot":   api.LoaderFile,
+}
+
+// WithAssetLoaders replaces DefaultAssetLoaders, e.g. to inline small images with
+// api.LoaderDataURL or to import WebAssembly modules as bytes with api.LoaderBinary.
+// Extensions include the leading dot.
+func WithAssetLoaders(loaders map[string]api.Loader) Option {
+	return func(config *Config) {
+		config.AssetLoaders = loaders
+	}
+}
+
+// mediaTypeLoaders are the loaders of the media types served by CDNs
+var mediaTypeLoaders = map[string]api.Loader{
+	"application/javascript":   api.LoaderJS,
+	"application/x-javascript": api.LoaderJS,
+	"application/ecmascript":   api.LoaderJS,
+	"text/javascript":          api.LoaderJS,
+	"text/ecmascript":          api.LoaderJS,
+	"text/jsx":                 api.LoaderJSX,
+	"application/typescript":   api.LoaderTS,
+	"application/x-typescript": api.LoaderTS,
+	"text/typescript":          api.LoaderTS,
+	"application/json":         api.LoaderJSON,
+	"text/json":                api.LoaderJSON,
+	"text/css":                 api.LoaderCSS,
+}
+
+// WithLoaderOverrides forces the loaders of modules, e.g. api.LoaderText for ".svg" files from a
+// CDN or for ".glsl" shaders. Keys are either extensions, including the leading dot, or patterns
+// of URLs and file URLs with a "*" wildcard like "https://cdn.example.com/shaders/*". Overrides
+// take precedence over the extensions, WithAssetLoaders and the Content-Type of downloads, and
+// patterns over extensions, the longest pattern first.
+func WithLoaderOverrides(overrides map[string]api.Loader) Option {
+	return func(config *Config) {
+		config.LoaderOverrides = overrides
+	}
+}
+
+// overrideLoader returns the loader Config.LoaderOverrides forces for rawPath, whose file
+// extension is ext.
+func (p *plugin) overrideLoader(rawPath string, ext string) (api.Loader, bool) {
+	patterns := make([]string, 0, len(p.config.LoaderOverrides))
+	for key := range p.config.LoaderOverrides {
+		if strings.Contains(key, "*") {
+			patterns = append(patterns, key)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if matchesExternal([]string{pattern}, rawPath) {
+			return p.config.LoaderOverrides[pattern], true
+		}
+	}
+
+	if ext == "" {
+		return api.LoaderNone, false
+	}
+	for key, loader := range p.config.LoaderOverrides {
+		if strings.HasPrefix(key, ".") && strings.ToLower(key) == ext {
+			return loader, true
+		}
+	}
+	return api.LoaderNone, false
+}
+
+// extensionLoader returns the loader of the file extension of rawPath, which may be a URL, or the
+// one forced by WithLoaderOverrides. Extensions of versioned URLs like "react@18.2" are not file
+// extensions.
+func (p *plugin) extensionLoader(rawPath string) (api.Loader, bool) {
+	name := rawPath
+	if u, err := url.Parse(rawPath); err == nil && u.Scheme != "" {
+		name = u.Path
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if len(p.config.LoaderOverrides) > 0 {
+		if loader, ok := p.overrideLoader(rawPath, ext); ok {
+			return loader, true
+		}
+	}
+	if loader, ok := extensionLoaders[ext]; ok {
+		return loader, true
+	}
+
+	assetLoaders := p.config.AssetLoaders
+	if assetLoaders == nil {
+		assetLoaders = DefaultAssetLoaders
+	}
+	loader, ok := assetLoaders[ext]
+	return loader, ok
+}
+
+// contentTypeLoader returns the loader of the Content-Type of a response.
+func contentTypeLoader(header http.Header) (api.Loader, bool) {
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return api.LoaderNone, false
+	}
+	loader, ok := mediaTypeLoaders[mediaType]
+	return loader, ok
+}
+
+// sniffLoader guesses the loader of contents served without a meaningful extension or
+// Content-Type: JSON documents are told apart from scripts, which are the default.
+func sniffLoader(contents string) api.Loader {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(contents, "\uFEFF"))
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return api.LoaderJSON
+	}
+	if strings.HasPrefix(trimmed, "@charset") || strings.HasPrefix(trimmed, "@import") {
+		return api.LoaderCSS
+	}
+	return api.LoaderJS
+}
+
+// remoteLoader returns the loader of a downloaded module, by the extension of its URL, by its
+// Content-Type for extensionless URLs like "https://esm.sh/react@18", or by its contents.
+func (p *plugin) remoteLoader(rawUrl string, result *fetchResult) api.Loader {
+	if loader, ok := p.extensionLoader(rawUrl); ok {
+		return loader
+	}
+	if loader, ok := contentTypeLoader(result.header); ok {
+		return loader
+	}
+	return sniffLoader(result.contents)
+}
@@ -0,0 +1,127 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteLoader(t *testing.T) {
+	p := newTestPlugin(t)
+	for _, tt := range []struct {
+		url         string
+		contentType string
+		contents    string
+		loader      api.Loader
+	}{
+		{"https://esm.sh/app.tsx", "text/javascript", "", api.LoaderTSX},
+		{"https://esm.sh/react@18", "application/javascript; charset=utf-8", "", api.LoaderJS},
+		{"https://esm.sh/react@18.2", "text/typescript", "", api.LoaderTS},
+		{"https://cdn.example/data", "application/json", "{}", api.LoaderJSON},
+		{"https://cdn.example/theme", "text/css", "", api.LoaderCSS},
+		{"https://cdn.example/data", "text/plain", ` [1, 2] `, api.LoaderJSON},
+		{"https://cdn.example/theme", "", "@import url(base.css);", api.LoaderCSS},
+		{"https://cdn.example/mod", "application/octet-stream", "export default {}", api.LoaderJS},
+		{"https://cdn.example/logo.svg", "image/svg+xml", "<svg/>", api.LoaderFile},
+		// the extensions of modules take precedence over the Content-Type CDNs serve them with
+		{"https://cdn.example/mod.mjs", "text/plain", "", api.LoaderJS},
+		{"https://cdn.example/mod.cjs", "text/plain", "", api.LoaderJS},
+		{"https://cdn.example/mod.mts", "text/javascript", "", api.LoaderTS},
+		{"https://cdn.example/mod.cts", "text/javascript", "", api.LoaderTS},
+		{"https://cdn.example/MOD.MTS", "text/javascript", "", api.LoaderTS},
+		{"https://cdn.example/theme.css", "text/plain", "", api.LoaderCSS},
+		{"https://cdn.example/data.json", "text/javascript", "", api.LoaderJSON},
+	} {
+		result := &fetchResult{contents: tt.contents, header: http.Header{"Content-Type": []string{tt.contentType}}}
+		if loader := p.remoteLoader(tt.url, result); loader != tt.loader {
+			t.Errorf("%s (%s): expected the %s loader, got %s", tt.url, tt.contentType, loaderName(tt.loader), loaderName(loader))
+		}
+	}
+}
+
+func TestPluginLoadsExtensionlessJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"answer": 42}`))
+	}))
+	defer server.Close()
+
+	plugin, err := NewPlugin(WithMap(importmap.Data{Imports: importmap.Imports{"data": server.URL + "/data"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import data from 'data'; console.log(data.answer);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), "42") {
+		t.Errorf("expected the JSON document to be bundled, got:\n%s", result.OutputFiles[0].Contents)
+	}
+}
+
+func TestPluginLoadsRemoteAssets(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"logo": "https://mirror.invalid/logo.svg"}}),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/logo.svg": "<svg/>"}),
+		WithAssetLoaders(map[string]api.Loader{".svg": api.LoaderDataURL}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import logo from 'logo'; console.log(logo);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), "data:image/svg+xml,") {
+		t.Errorf("expected the image to be inlined, got:\n%s", result.OutputFiles[0].Contents)
+	}
+}
+
+func TestLoaderOverrides(t *testing.T) {
+	p := newTestPlugin(t, WithLoaderOverrides(map[string]api.Loader{
+		".svg":                          api.LoaderText,
+		".GLSL":                         api.LoaderText,
+		"https://cdn.example/raw/*":     api.LoaderBinary,
+		"https://cdn.example/raw/app/*": api.LoaderBase64,
+		"https://cdn.example/*.ts?raw":  api.LoaderText,
+		"file:///app/vendor/*.js":       api.LoaderJSX,
+	}))
+	for _, tt := range []struct {
+		url    string
+		loader api.Loader
+	}{
+		{"https://cdn.example/logo.svg", api.LoaderText},
+		{"https://cdn.example/shaders/blur.glsl", api.LoaderText},
+		{"https://cdn.example/raw/logo.svg", api.LoaderBinary},
+		{"https://cdn.example/raw/app/main.js", api.LoaderBase64},
+		{"https://cdn.example/app.ts?raw", api.LoaderText},
+		{"https://cdn.example/app.ts", api.LoaderTS},
+		{"file:///app/vendor/legacy.js", api.LoaderJSX},
+		{"https://cdn.example/logo.png", api.LoaderFile},
+	} {
+		result := &fetchResult{header: http.Header{"Content-Type": []string{"text/javascript"}}}
+		if loader := p.remoteLoader(tt.url, result); loader != tt.loader {
+			t.Errorf("%s: expected the %s loader, got %s", tt.url, loaderName(tt.loader), loaderName(loader))
+		}
+	}
+}
+
+func TestPluginWithLoaderOverrides(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"shader": "https://mirror.invalid/blur.glsl"}}),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/blur.glsl": "void main() {}"}),
+		WithLoaderOverrides(map[string]api.Loader{".glsl": api.LoaderText}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import shader from 'shader'; console.log(shader);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), `"void main() {}"`) {
+		t.Errorf("expected the shader to be bundled as text, got:\n%s", result.OutputFiles[0].Contents)
+	}
+}
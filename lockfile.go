@@ -0,0 +1,175 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"sync"
+)
+
+const (
+	// DefaultLockfile is the conventional name of the lockfile
+	DefaultLockfile = "importmap.lock.json"
+
+	lockfileVersion = 1
+)
+
+// Lockfile records the content hash of every remote module of a build, so later builds can detect
+// modules changing upstream
+type Lockfile struct {
+	Version int `json:"version"`
+	// Modules maps the URLs of the modules to their Subresource Integrity hash
+	Modules map[string]string `json:"modules"`
+}
+
+// LockfileMode controls how the plugin uses its lockfile
+type LockfileMode int
+
+const (
+	// LockfileVerify fails the build when a locked module changed and adds the new modules to the lock
+	LockfileVerify LockfileMode = iota
+	// LockfileFrozen fails the build when a locked module changed or a module isn't locked,
+	// leaving the lockfile untouched
+	LockfileFrozen
+	// LockfileUpdate rewrites the lockfile with the modules of the build
+	LockfileUpdate
+)
+
+// LockfileDriftError is returned for modules whose content doesn't match the lockfile
+type LockfileDriftError struct {
+	URL      string
+	Locked   string
+	Received string
+}
+
+func (e *LockfileDriftError) Error() string {
+	return fmt.Sprintf("%s changed since it was locked: expected %s, got %s", e.URL, e.Locked, e.Received)
+}
+
+// NewLockfile creates an empty lockfile
+func NewLockfile() *Lockfile {
+	return &Lockfile{Version: lockfileVersion, Modules: make(map[string]string)}
+}
+
+// ReadLockfile reads the lockfile at path.
+func ReadLockfile(path string) (*Lockfile, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lock := NewLockfile()
+	if err = json.Unmarshal(contents, lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Version != lockfileVersion {
+		return nil, fmt.Errorf("unsupported lockfile version %d in %s", lock.Version, path)
+	}
+	if lock.Modules == nil {
+		lock.Modules = make(map[string]string)
+	}
+	return lock, nil
+}
+
+// WriteFile writes the lockfile to path.
+func (l *Lockfile) WriteFile(path string) error {
+	contents, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(contents, '\n'), 0o644)
+}
+
+// Update locks rawUrl to the hash of contents.
+func (l *Lockfile) Update(rawUrl string, contents []byte) error {
+	integrity, err := importmap.ComputeIntegrity(contents, "")
+	if err != nil {
+		return err
+	}
+	l.Modules[rawUrl] = integrity
+	return nil
+}
+
+// Verify checks contents against the hash locked for rawUrl, returning a *LockfileDriftError on
+// mismatch. It reports whether rawUrl is locked.
+func (l *Lockfile) Verify(rawUrl string, contents []byte) (bool, error) {
+	locked, ok := l.Modules[rawUrl]
+	if !ok {
+		return false, nil
+	}
+	err := importmap.VerifyIntegrity(contents, locked)
+	var mismatch *importmap.IntegrityMismatchError
+	if errors.As(err, &mismatch) {
+		return true, &LockfileDriftError{URL: rawUrl, Locked: locked, Received: mismatch.Actual}
+	}
+	return true, err
+}
+
+// WithLockfile verifies remote modules against the lockfile at path, creating it if needed,
+// according to mode.
+func WithLockfile(path string, mode LockfileMode) Option {
+	return func(config *Config) {
+		config.LockfilePath = path
+		config.LockfileMode = mode
+	}
+}
+
+// lockState is the lockfile of the current build
+type lockState struct {
+	mu      sync.Mutex
+	lock    *Lockfile
+	changed bool
+}
+
+// loadLockfile reads the lockfile when a build starts. Update mode starts from scratch, so modules no
+// longer used are dropped.
+func (p *plugin) loadLockfile() error {
+	lock := NewLockfile()
+	if p.config.LockfileMode != LockfileUpdate {
+		var err error
+		if lock, err = ReadLockfile(p.config.LockfilePath); errors.Is(err, os.ErrNotExist) {
+			lock = NewLockfile()
+		} else if err != nil {
+			return err
+		}
+	}
+
+	p.lock.mu.Lock()
+	defer p.lock.mu.Unlock()
+	p.lock.lock = lock
+	p.lock.changed = p.config.LockfileMode == LockfileUpdate
+	return nil
+}
+
+// checkLockfile verifies a downloaded module against the lockfile, locking it when allowed.
+func (p *plugin) checkLockfile(rawUrl string, contents []byte) error {
+	p.lock.mu.Lock()
+	defer p.lock.mu.Unlock()
+
+	if p.config.LockfileMode != LockfileUpdate {
+		locked, err := p.lock.lock.Verify(rawUrl, contents)
+		if locked || err != nil {
+			return err
+		}
+		if p.config.LockfileMode == LockfileFrozen {
+			return fmt.Errorf("%s is not in the lockfile %s", rawUrl, p.config.LockfilePath)
+		}
+	}
+
+	p.lock.changed = true
+	return p.lock.lock.Update(rawUrl, contents)
+}
+
+// writeLockfile writes the lockfile after a successful build which changed it.
+func (p *plugin) writeLockfile(result *api.BuildResult) error {
+	p.lock.mu.Lock()
+	defer p.lock.mu.Unlock()
+
+	if !p.lock.changed || len(result.Errors) > 0 || p.config.LockfileMode == LockfileFrozen {
+		return nil
+	}
+	p.lock.changed = false
+	return p.lock.lock.WriteFile(p.config.LockfilePath)
+}
@@ -0,0 +1,60 @@
+package esbuild_plugin_importmap
+
+import (
+	"errors"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginWithLockfile(t *testing.T) {
+	const rawUrl = "https://mirror.invalid/pkg@1.0.0/index.js"
+	data := importmap.Data{Imports: importmap.Imports{"pkg": rawUrl}}
+	path := filepath.Join(t.TempDir(), DefaultLockfile)
+	build := func(contents string, mode LockfileMode) []string {
+		t.Helper()
+		plugin, err := NewPlugin(WithMap(data), WithLockfile(path, mode), WithFetcher(fixtureFetcher{rawUrl: contents}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var errs []string
+		for _, message := range buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin).Errors {
+			errs = append(errs, message.Text)
+		}
+		return errs
+	}
+
+	if errs := build("export const pkg = 1;", LockfileFrozen); len(errs) == 0 || !strings.Contains(errs[0], "is not in the lockfile") {
+		t.Errorf("expected unlocked modules to fail in frozen mode, got %v", errs)
+	}
+	if errs := build("export const pkg = 1;", LockfileVerify); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	lock, err := ReadLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = lock.Verify(rawUrl, []byte("export const pkg = 1;")); err != nil || len(lock.Modules) != 1 {
+		t.Errorf("expected the module to be locked, got %v, %v", lock.Modules, err)
+	}
+
+	if errs := build("export const pkg = 1;", LockfileFrozen); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if errs := build("export const pkg = 2;", LockfileVerify); len(errs) == 0 || !strings.Contains(errs[0], "changed since it was locked") {
+		t.Errorf("expected a drift error, got %v", errs)
+	}
+	if errs := build("export const pkg = 2;", LockfileUpdate); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	lock, err = ReadLockfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var drift *LockfileDriftError
+	if _, err = lock.Verify(rawUrl, []byte("export const pkg = 1;")); !errors.As(err, &drift) {
+		t.Errorf("expected the lock to be updated, got %v", err)
+	}
+}
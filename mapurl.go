@@ -0,0 +1,57 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+)
+
+// WithImportMapURL sets the URL of the import map json file, downloaded by NewPlugin with the
+// TLS settings and Fetcher of the plugin
+func WithImportMapURL(rawUrl string) Option {
+	return func(config *Config) {
+		config.ImportMapURL = rawUrl
+	}
+}
+
+// loadImportMapURL downloads the import map at Config.ImportMapURL with fetcher, or the Fetcher
+// of config when nil. Relative URLs of the map are resolved against the URL it was served from,
+// the other map options of config applying as for the other sources.
+func loadImportMapURL(ctx context.Context, config *Config, fetcher Fetcher) (importmap.IImportMap, error) {
+	if config.Offline {
+		return nil, &OfflineError{URL: config.ImportMapURL}
+	}
+	if fetcher == nil {
+		fetcher = config.Fetcher
+	}
+	if fetcher == nil {
+		client, err := newHTTPClient(config)
+		if err != nil {
+			return nil, err
+		}
+		fetcher = &HTTPFetcher{Client: client}
+	}
+
+	body, _, finalUrl, err := fetcher.Fetch(ctx, config.ImportMapURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the import map: %w", err)
+	}
+	if finalUrl == "" {
+		finalUrl = config.ImportMapURL
+	}
+	mapUrl, err := url.Parse(finalUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := mapOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	m, err := importmap.Parse(body, append(opts, importmap.WithMapUrl(mapUrl))...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid import map %s: %w", finalUrl, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,62 @@
+package esbuild_plugin_importmap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPluginWithImportMapURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/maps/importmap.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"imports": {"pkg": "./pkg.js"}}`))
+	})
+	mux.HandleFunc("/maps/pkg.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript")
+		_, _ = w.Write([]byte("export const pkg = 'remote map';"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	plugin, err := NewPlugin(WithImportMapURL(server.URL + "/maps/importmap.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), "remote map") {
+		t.Errorf("expected the target to be resolved against the map URL, got:\n%s", result.OutputFiles[0].Contents)
+	}
+
+	_, err = NewPlugin(WithImportMapURL(server.URL + "/missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "failed to load the import map") {
+		t.Errorf("expected an error for a missing map, got %v", err)
+	}
+}
+
+func TestPluginWithImportMapURLEnvironment(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithImportMapURL("https://maps.invalid/importmap.json"),
+		WithEnvironment("production"),
+		WithFetcher(fixtureFetcher{
+			"https://maps.invalid/importmap.json": `{
+				"imports": {"pkg": "https://cdn.invalid/pkg.development.js"},
+				"environments": {"production": {"imports": {"pkg": "https://cdn.invalid/pkg.production.js"}}}
+			}`,
+			"https://cdn.invalid/pkg.production.js": "export const pkg = 'production';",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "production") {
+		t.Errorf("expected the production build of pkg, got:\n%s", output)
+	}
+}
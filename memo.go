@@ -0,0 +1,100 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// resolutionKey identifies a resolution: the import map of the entry point, the specifier and
+// the parent URL
+type resolutionKey struct {
+	entryPoint string
+	specifier  string
+	parent     string
+}
+
+type resolutionEntry struct {
+	resolution importmap.Resolution
+	err        error
+}
+
+// resolutionCache memoizes resolutions, so specifiers imported by hundreds of modules only go
+// through the scopes and URL math once. It is emptied when the import map is replaced, and when
+// a build starts for maps given with WithImportMap, which their owner may modify between builds.
+type resolutionCache struct {
+	mu      sync.Mutex
+	entries map[resolutionKey]resolutionEntry
+	// directoryScoped tells, by entry point, whether all the scope keys of the map end with "/"
+	directoryScoped map[string]bool
+	// hits and invalidations are counted since the last take
+	hits          int
+	invalidations int
+}
+
+// resolve returns the resolution of specifier from parentUrl by importMap, the map of entryPoint,
+// from the cache when it was resolved before.
+//
+// Bare specifiers only depend on the scopes matching the parent, which are the same for all the
+// modules of a directory when the scope keys are directories, so the resolutions are shared by
+// the modules of the directory then.
+func (c *resolutionCache) resolve(importMap importmap.IImportMap, entryPoint string, specifier string, parentUrl *url.URL) (importmap.Resolution, error) {
+	key := resolutionKey{entryPoint: entryPoint, specifier: specifier, parent: parentUrl.String()}
+	c.mu.Lock()
+	directoryScoped, ok := c.directoryScoped[entryPoint]
+	if !ok {
+		directoryScoped = true
+		for scopeKey := range importMap.GetScopes() {
+			directoryScoped = directoryScoped && strings.HasSuffix(scopeKey, "/")
+		}
+		if c.directoryScoped == nil {
+			c.directoryScoped = make(map[string]bool)
+		}
+		c.directoryScoped[entryPoint] = directoryScoped
+	}
+	if kind, _ := importmap.ParseSpecifier(specifier, nil); kind == importmap.SpecifierBare && directoryScoped {
+		dir := *parentUrl
+		dir.RawQuery, dir.Fragment = "", ""
+		dir.Path = dir.Path[:strings.LastIndex(dir.Path, "/")+1]
+		dir.RawPath = ""
+		key.parent = dir.String()
+	}
+	entry, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	c.mu.Unlock()
+	if ok {
+		return entry.resolution, entry.err
+	}
+
+	resolution, err := importMap.ResolveDetailed(specifier, parentUrl)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[resolutionKey]resolutionEntry)
+	}
+	c.entries[key] = resolutionEntry{resolution: resolution, err: err}
+	return resolution, err
+}
+
+// invalidate empties the cache.
+func (c *resolutionCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) > 0 {
+		c.invalidations++
+	}
+	c.entries = nil
+	c.directoryScoped = nil
+}
+
+// take returns the size of the cache with the hits and invalidations since the last call.
+func (c *resolutionCache) take() (size int, hits int, invalidations int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size, hits, invalidations = len(c.entries), c.hits, c.invalidations
+	c.hits, c.invalidations = 0, 0
+	return size, hits, invalidations
+}
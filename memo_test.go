@@ -0,0 +1,54 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"testing"
+)
+
+func TestResolutionCache(t *testing.T) {
+	p := newTestPlugin(t)
+	replace := func(data importmap.Data) {
+		m, err := importmap.New(importmap.WithMap(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = p.replaceImportMap(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolve := func(importer string) string {
+		result, err := p.onResolve(api.OnResolveArgs{Path: "pkg", Importer: importer, Namespace: "file", Kind: api.ResolveJSImportStatement})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Path
+	}
+
+	replace(importmap.Data{
+		Imports: importmap.Imports{"pkg": "https://cdn.invalid/pkg@2.js"},
+		Scopes:  importmap.Scopes{"file:///legacy/": {"pkg": "https://cdn.invalid/pkg@1.js"}},
+	})
+	p.resolutions.take()
+	for _, importer := range []string{"/app/a.js", "/app/b.js", "/app/a.js", "/legacy/c.js"} {
+		resolve(importer)
+	}
+	if path := resolve("/legacy/d.js"); path != "https://cdn.invalid/pkg@1.js" {
+		t.Errorf("expected the scoped target from the cache, got %s", path)
+	}
+	if size, hits, invalidations := p.resolutions.take(); size != 2 || hits != 3 || invalidations != 0 {
+		t.Errorf("expected the modules of a directory to share their resolutions, got size %d, %d hits, %d invalidations", size, hits, invalidations)
+	}
+
+	// a scope of a single module makes the resolutions depend on the module
+	replace(importmap.Data{
+		Imports: importmap.Imports{"pkg": "https://cdn.invalid/pkg@2.js"},
+		Scopes:  importmap.Scopes{"file:///app/a.js": {"pkg": "https://cdn.invalid/pkg@1.js"}},
+	})
+	if resolve("/app/a.js") != "https://cdn.invalid/pkg@1.js" || resolve("/app/b.js") != "https://cdn.invalid/pkg@2.js" {
+		t.Error("expected the resolutions of the modules of the directory to differ")
+	}
+	if size, hits, invalidations := p.resolutions.take(); size != 2 || hits != 0 || invalidations != 1 {
+		t.Errorf("expected the replaced map to invalidate the cache, got size %d, %d hits, %d invalidations", size, hits, invalidations)
+	}
+}
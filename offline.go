@@ -0,0 +1,108 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OfflineError is returned in offline mode for modules found neither in the cache directory nor
+// in the vendor directory
+type OfflineError struct {
+	URL string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("%s is not available offline: it is neither cached nor vendored", e.URL)
+}
+
+// WithOffline makes the plugin serve remote modules from the cache directory and the vendor
+// directory only, for hermetic builds. Modules found in neither fail the build, which lists them.
+func WithOffline() Option {
+	return func(config *Config) {
+		config.Offline = true
+	}
+}
+
+// offlineMisses records the URLs requiring network access during an offline build
+type offlineMisses struct {
+	mu   sync.Mutex
+	urls map[string]bool
+}
+
+func (m *offlineMisses) record(rawUrl string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.urls == nil {
+		m.urls = make(map[string]bool)
+	}
+	m.urls[rawUrl] = true
+}
+
+// take returns the recorded URLs, sorted, and forgets them.
+func (m *offlineMisses) take() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	urls := make([]string, 0, len(m.urls))
+	for rawUrl := range m.urls {
+		urls = append(urls, rawUrl)
+	}
+	m.urls = nil
+	sort.Strings(urls)
+	return urls
+}
+
+// readVendored returns the copy of rawUrl in the vendor directory, if any.
+func (p *plugin) readVendored(rawUrl string) (*fetchResult, bool) {
+	if p.config.VendorDir == "" {
+		return nil, false
+	}
+
+	name := vendorFileName(rawUrl, "", false)
+	if p.config.HashVendorFiles {
+		// hashed names depend on the content, so they are looked up in the vendor map
+		contents, err := os.ReadFile(filepath.Join(p.config.VendorDir, VendorMapFile))
+		if err != nil {
+			return nil, false
+		}
+		data := importmap.Data{}
+		if err = json.Unmarshal(contents, &data); err != nil {
+			return nil, false
+		}
+		target, ok := data.Imports[rawUrl]
+		if !ok {
+			return nil, false
+		}
+		name = path.Base(target)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(p.config.VendorDir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, false
+	}
+	return &fetchResult{
+		contents:  string(contents),
+		body:      string(contents),
+		finalUrl:  rawUrl,
+		fromCache: true,
+		vendored:  true,
+	}, true
+}
+
+// offlineErrors reports the modules of the build which would have required network access.
+func (p *plugin) offlineErrors() []api.Message {
+	urls := p.offline.take()
+	if len(urls) == 0 {
+		return nil
+	}
+	return []api.Message{{
+		Text: "the build requires network access, which is disabled in offline mode, for:\n  " + strings.Join(urls, "\n  "),
+	}}
+}
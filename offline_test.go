@@ -0,0 +1,83 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginOffline(t *testing.T) {
+	data := importmap.Data{Imports: importmap.Imports{
+		"pkg":     "https://mirror.invalid/pkg@1.0.0/index.js",
+		"missing": "https://mirror.invalid/missing@1.0.0/index.js",
+	}}
+	dir := t.TempDir()
+
+	// a first online build vendors the module
+	plugin, err := NewPlugin(
+		WithMap(data),
+		WithVendorDir(dir),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/pkg@1.0.0/index.js": "export const pkg = 'vendored';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin); len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	plugin, err = NewPlugin(WithMap(data), WithVendorDir(dir), WithOffline(), WithFetcher(fixtureFetcher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), `"vendored"`) {
+		t.Errorf("expected the vendored module, got:\n%s", result.OutputFiles[0].Contents)
+	}
+
+	result = buildWithPlugin(t, "import {pkg} from 'pkg'; import 'missing'; console.log(pkg);", plugin)
+	var listed bool
+	for _, message := range result.Errors {
+		listed = listed || strings.Contains(message.Text, "offline mode, for:\n  https://mirror.invalid/missing@1.0.0/index.js")
+	}
+	if !listed {
+		t.Errorf("expected the missing module to be listed, got %v", result.Errors)
+	}
+}
+
+func TestPluginOfflineTransformedModule(t *testing.T) {
+	integrity, err := importmap.ComputeIntegrity([]byte(umdModule), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := importmap.Data{
+		Imports:   importmap.Imports{"umd": "https://mirror.invalid/umd@1.0.0/index.js"},
+		Integrity: importmap.Integrity{"https://mirror.invalid/umd@1.0.0/index.js": integrity},
+	}
+	dir := t.TempDir()
+	opts := []Option{WithMap(data), WithVendorDir(dir), WithCommonJSInterop()}
+
+	plugin, err := NewPlugin(append(opts, WithFetcher(fixtureFetcher{"https://mirror.invalid/umd@1.0.0/index.js": umdModule}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := buildWithPlugin(t, "import umd from 'umd'; console.log(umd);", plugin); len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	// the vendored copy is already transformed, so it's neither verified nor transformed again
+	plugin, err = NewPlugin(append(opts, WithOffline(), WithFetcher(fixtureFetcher{}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import umd from 'umd'; console.log(umd);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); strings.Count(output, "var define;") != 1 {
+		t.Errorf("expected the UMD shim once, got:\n%s", output)
+	}
+}
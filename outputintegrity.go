@@ -0,0 +1,55 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"path/filepath"
+)
+
+// integrityOutputExtensions are the extensions of the output files browsers check the integrity of
+var integrityOutputExtensions = map[string]bool{
+	".js":  true,
+	".mjs": true,
+	".css": true,
+}
+
+// outputIntegrity returns the integrity values of the scripts and stylesheets of a build, keyed by
+// their URL relative to dir like the targets of the residual map.
+func (p *plugin) outputIntegrity(result *api.BuildResult, dir string) (importmap.Integrity, error) {
+	integrity := make(importmap.Integrity)
+	if p.config.OutputIntegrity == "" {
+		return integrity, nil
+	}
+	for _, file := range result.OutputFiles {
+		if !integrityOutputExtensions[filepath.Ext(file.Path)] {
+			continue
+		}
+		value, err := importmap.ComputeIntegrity(file.Contents, p.config.OutputIntegrity)
+		if err != nil {
+			return nil, err
+		}
+		integrity[p.outputUrl(importmap.FileURL(file.Path).String(), dir)] = value
+	}
+	return integrity, nil
+}
+
+// WithOutputIntegrity adds the integrity of the scripts and stylesheets esbuild outputs to the
+// import maps emitted by WithResidualMap and WithHTMLInjection, so browsers verify the bundle as
+// well as the externals. algorithm is sha256, sha384 or sha512, sha384 when empty.
+func WithOutputIntegrity(algorithm string) Option {
+	return func(config *Config) {
+		if algorithm == "" {
+			algorithm = "sha384"
+		}
+		config.OutputIntegrity = algorithm
+	}
+}
+
+// WithHTMLIntegrityAttributes also sets the integrity attributes of the scripts and stylesheets
+// of the page updated by WithHTMLInjection which reference outputs of the build. It requires
+// WithOutputIntegrity.
+func WithHTMLIntegrityAttributes() Option {
+	return func(config *Config) {
+		config.HTMLIntegrityAttributes = true
+	}
+}
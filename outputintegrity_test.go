@@ -0,0 +1,69 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginOutputIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	outdir := filepath.Join(dir, "dist")
+	htmlPath := filepath.Join(outdir, "index.html")
+	if err := os.MkdirAll(outdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	doc := `<html><head><script type="module" src="./index.js"></script></head></html>`
+	if err := os.WriteFile(htmlPath, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := newResidualMapPlugin(t, dir,
+		WithOutputIntegrity(""),
+		WithHTMLInjection(htmlPath),
+		WithHTMLIntegrityAttributes(),
+	)
+	buildResidualMap(t, plugin, outdir, true)
+
+	bundle, err := os.ReadFile(filepath.Join(outdir, "index.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := importmap.ComputeIntegrity(bundle, "sha384")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(outdir, ResidualMapFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := importmap.Data{}
+	if err = json.Unmarshal(contents, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Integrity["./index.js"] != expected {
+		t.Errorf("expected the integrity of the bundle in the map, got %s", contents)
+	}
+
+	page, err := os.ReadFile(htmlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `src="./index.js" integrity="`+expected+`"`) {
+		t.Errorf("expected the integrity attribute of the bundle in the page, got:\n%s", page)
+	}
+}
+
+func TestPluginOutputIntegrityOptions(t *testing.T) {
+	importMap := WithMap(importmap.Data{Imports: importmap.Imports{"a": "./a.js"}})
+	if _, err := NewPlugin(importMap, WithOutputIntegrity("md5")); err == nil {
+		t.Error("expected an unsupported algorithm to be rejected")
+	}
+	if _, err := NewPlugin(importMap, WithHTMLIntegrityAttributes()); err == nil {
+		t.Error("expected the integrity attributes to require WithOutputIntegrity")
+	}
+}
@@ -0,0 +1,33 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap/generate"
+	"path/filepath"
+)
+
+// addPackageImports adds the subpath imports of Config.PackageImportsPath to importMap. The
+// entries of the import map take precedence.
+func addPackageImports(config *Config, importMap importmap.IImportMap) (importmap.IImportMap, error) {
+	if config.PackageImportsPath == "" {
+		return importMap, nil
+	}
+	dir := config.PackageImportsPath
+	if filepath.Base(dir) == "package.json" {
+		dir = filepath.Dir(dir)
+	}
+	imports, err := generate.PackageImports(dir)
+	if err != nil {
+		return nil, err
+	}
+	return importmap.AddSubpathImports(importMap, imports), nil
+}
+
+// WithPackageImports resolves the subpath imports of a package.json, like "#internal/*", through
+// the plugin, so private aliases work the same in the bundle as in Node.js. path is the
+// package.json file or its directory. Mappings of the import map for the same keys win.
+func WithPackageImports(path string) Option {
+	return func(config *Config) {
+		config.PackageImportsPath = path
+	}
+}
@@ -0,0 +1,49 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginWithPackageImports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json":         `{"imports": {"#internal/*": "./src/internal/*.js"}}`,
+		"src/internal/db.js":   "export const db = 'internal db';",
+		"src/internal/util.js": "export const util = 'internal util';",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"#internal/util": "file://" + filepath.ToSlash(dir) + "/src/internal/db.js",
+		}}),
+		WithPackageImports(filepath.Join(dir, "package.json")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {db} from '#internal/db'; import * as util from '#internal/util'; console.log(db, util);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "internal db") {
+		t.Errorf("expected the subpath import to be resolved, got:\n%s", output)
+	}
+	if strings.Contains(output, "internal util") {
+		t.Errorf("expected the mapping of the import map to win, got:\n%s", output)
+	}
+}
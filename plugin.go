@@ -1,18 +1,21 @@
 package esbuild_plugin_importmap
 
 import (
-	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
-	"io"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap/generate"
 	"net/http"
-	"net/url"
 	"os"
-	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 const namespace = "importmap-url"
@@ -20,7 +23,228 @@ const namespace = "importmap-url"
 // Config is the configuration object for the plugin
 type Config struct {
 	ImportMapData *importmap.Data
-	ImportMap     importmap.IImportMap
+	// ImportMaps are composed on top of ImportMapData in order, see WithMaps
+	ImportMaps []importmap.Data
+	ImportMap  importmap.IImportMap
+	// ImportMapPath is the path of an import map json file, which takes precedence over ImportMap.
+	// The map of a package.json file is read from its PackageJSONKey, the one of a deno.json file
+	// from its imports and scopes, the one of a tsconfig.json file from its paths.
+	ImportMapPath  string
+	PackageJSONKey string
+	// ImportMapURL is the URL of an import map json file, which takes precedence over ImportMap
+	ImportMapURL string
+	// ImportMapHTMLPath is the path of an HTML file whose import maps take precedence over ImportMap
+	ImportMapHTMLPath string
+
+	// MaxConcurrentFetches bounds the number of remote downloads in flight.
+	// Zero means a default derived from GOMAXPROCS.
+	MaxConcurrentFetches int
+	// MaxFetchesPerHost bounds the number of remote downloads in flight per origin.
+	// Zero means 6, like browsers do.
+	MaxFetchesPerHost int
+	// FetchTimeout bounds each download. Zero means one minute, a negative value no timeout.
+	FetchTimeout time.Duration
+	// FetchRetries is how many times failed downloads are retried, with an exponential backoff
+	// starting at RetryBaseDelay
+	FetchRetries   int
+	RetryBaseDelay time.Duration
+	// OnUnresolved controls what happens with specifiers the import map has no mapping for
+	OnUnresolved UnresolvedBehavior
+	// RootCAs replaces the system certificate pool used to verify remote servers
+	RootCAs *x509.CertPool
+	// CABundlePaths are PEM files with certificates trusted in addition to the system ones
+	CABundlePaths []string
+	// ClientCertificates are presented to servers requesting mutual TLS
+	ClientCertificates []tls.Certificate
+	// ClientCertificateFiles are PEM certificate and key file pairs loaded into ClientCertificates
+	ClientCertificateFiles [][2]string
+	// MaxRedirects is how many redirects a download follows. Zero means 10.
+	MaxRedirects int
+	// Proxy is the URL of the proxy used for remote downloads instead of the one of the environment
+	Proxy string
+	// CheckContentType rejects downloaded modules whose Content-Type doesn't fit the loader,
+	// like HTML pages served by SPA fallbacks or captive portals
+	CheckContentType bool
+	// VendorDir is the directory downloaded modules are written to, together with an import map
+	// pointing at them. Vendoring is disabled when empty.
+	VendorDir string
+	// HashVendorFiles names vendored modules after their content hash, for long-term immutable caching
+	HashVendorFiles bool
+	// PublicPath is the URL the output directory is served from. It is used for the targets of the
+	// vendored import map and for mapped files inside the output directory, which are kept external.
+	// Defaults to the PublicPath of the build options.
+	PublicPath string
+	// Shims maps specifiers to the source of the modules served for them when the import map
+	// has no mapping for them
+	Shims map[string]string
+	// GlobalShims injects the process and Buffer globals from the modules the import map or the
+	// shims provide
+	GlobalShims bool
+	// UnknownShims are the specifiers passed to WithShims without a built-in shim, which
+	// NewPlugin rejects
+	UnknownShims []string
+	// VendorCheckInterval is how often upstream is polled for changes of the vendored modules
+	// with mutable tags, OnVendorChange being called with the modules needing a refresh or the
+	// error of a failed check
+	VendorCheckInterval time.Duration
+	OnVendorChange      func([]VendorChange, error)
+	// Fetcher downloads remote modules, defaulting to an HTTPFetcher using the TLS settings above
+	Fetcher Fetcher
+	// Context bounds the downloads of the plugin, which are also aborted when the build context is disposed
+	Context context.Context
+	// EntryPointMaps are overlays of the import map applied to the modules reached from an entry point
+	EntryPointMaps map[string]importmap.Data
+	// Interop enables the conversion of CommonJS and UMD modules detected with its heuristics
+	Interop *InteropHeuristics
+	// DeriveSubpaths resolves subpaths of packages mapped by their root only
+	DeriveSubpaths bool
+	// OnUnusedMappings is called after each successful build with the entries of the map no import
+	// resolved with, which are reported as warnings too with WarnUnusedMappings
+	OnUnusedMappings   func([]UnusedMapping)
+	WarnUnusedMappings bool
+	// ResolutionWarnings reports recoverable resolution problems as warnings located at the import
+	ResolutionWarnings bool
+	// AssetLoaders are the loaders of the extensions of mapped assets, DefaultAssetLoaders when nil
+	AssetLoaders map[string]api.Loader
+	// LoaderOverrides force the loaders of extensions and URL patterns, see WithLoaderOverrides
+	LoaderOverrides map[string]api.Loader
+	// RegistryProvider is the CDN of npm: and jsr: specifiers, esm.sh when empty
+	RegistryProvider generate.Provider
+	// Schemes are the handlers of custom URL schemes, keyed by lower case scheme
+	Schemes map[string]SchemeHandler
+	// External are patterns of mapped specifiers left external, in addition to the External
+	// build option
+	External []string
+	// ExternalsMapPath is where the import map of the mapped specifiers marked external in the
+	// build options is written after each build
+	ExternalsMapPath string
+	// ResidualMap emits the import map of the mapped specifiers left external to the output
+	// directory after each build
+	ResidualMap bool
+	// InjectHTMLPath is the path of an HTML file whose import map is replaced with the residual
+	// map after each build
+	InjectHTMLPath string
+	// PackageImportsPath is the package.json whose subpath imports are added to the import map
+	PackageImportsPath string
+	// BaseURL is the URL the import maps given as data or files are resolved against
+	BaseURL string
+	// RootDir is the directory the root of the site is served from
+	RootDir string
+	// ResolveFilter is the esbuild filter of the paths the plugin resolves, see WithResolveFilter
+	ResolveFilter string
+	// IncludedSpecifiers and ExcludedSpecifiers select the specifiers the plugin resolves, see
+	// WithIncludedSpecifiers and WithExcludedSpecifiers
+	IncludedSpecifiers []string
+	ExcludedSpecifiers []string
+	// ResolveSymlinks loads the local files of the map from their real path, see WithResolveSymlinks
+	ResolveSymlinks bool
+	// Environment selects the environment overrides of the import maps, see importmap.Data
+	Environment string
+	// Conditions select the runtime overrides of the import maps, see importmap.Data
+	Conditions []string
+	// OutputIntegrity is the hash algorithm of the integrity values of the output files added
+	// to the emitted import maps, none when empty
+	OutputIntegrity string
+	// HTMLIntegrityAttributes sets the integrity attributes of the output files referenced by
+	// the HTML file at InjectHTMLPath
+	HTMLIntegrityAttributes bool
+	// MapFormat lays out the json of the import maps the plugin writes
+	MapFormat []importmap.FormatOption
+	// Hooks are called around downloads and resolution misses
+	Hooks Hooks
+	// IntegrityCheck controls what happens with loaded modules not matching the integrity value
+	// the import map has for them
+	IntegrityCheck IntegrityBehavior
+	// CacheDir is the directory downloaded modules are kept in across builds. Caching is disabled
+	// when empty.
+	CacheDir string
+	// LockfilePath is the lockfile remote modules are verified against according to LockfileMode
+	LockfilePath string
+	LockfileMode LockfileMode
+	// Offline serves remote modules from the cache and vendor directories only
+	Offline bool
+	// OnStats is called with the metrics of each build when it ends
+	OnStats func(Stats)
+	// Warmup makes the plugin resolve and connect to every remote origin in the map
+	// when a build starts.
+	Warmup bool
+	// Debug logs the trace of the resolution of each import
+	Debug bool
+}
+
+// Hooks lets embedders observe and control what the plugin does, e.g. to log downloads or block
+// packages, without replacing its resolve and load callbacks
+type Hooks struct {
+	// OnBeforeFetch is called before downloading a module. It returns the URL to download instead,
+	// or an error vetoing the download, which fails the import.
+	OnBeforeFetch func(ctx context.Context, rawUrl string) (string, error)
+	// OnAfterFetch is called with a downloaded module and returns its contents to use instead
+	OnAfterFetch func(rawUrl string, contents string) (string, error)
+	// OnResolveMiss is called with the specifiers the import map has no mapping for
+	OnResolveMiss func(specifier string, importer string)
+	// OnFetchStart is called when a remote module is requested, and OnFetchComplete once it was
+	// downloaded or, with fromCache, served without a download by the cache or vendor directories
+	// or a download of the same build. Both may be called concurrently.
+	OnFetchStart    func(rawUrl string)
+	OnFetchComplete func(rawUrl string, bytes int, fromCache bool)
+}
+
+// UnresolvedBehavior controls what the plugin does with specifiers the import map has no mapping for
+type UnresolvedBehavior int
+
+const (
+	// UnresolvedError fails the build
+	UnresolvedError UnresolvedBehavior = iota
+	// UnresolvedWarn reports a warning and leaves the specifier to other plugins and esbuild
+	UnresolvedWarn
+	// UnresolvedPassthrough silently leaves the specifier to other plugins and esbuild, which
+	// resolves the ones imported by local files, mapped or not, from their node_modules
+	UnresolvedPassthrough
+	// UnresolvedExternal marks the import as external, leaving it untouched in the output
+	UnresolvedExternal
+)
+
+type plugin struct {
+	config *Config
+	// mapsMu guards importMap and entryPointMaps, which OnStart replaces when the map changes
+	// while other builds sharing the plugin may be resolving imports, along with mapModTime and
+	// validated
+	mapsMu    sync.RWMutex
+	importMap importmap.IImportMap
+	// mapModTime is the modification time of the import map file when it was loaded
+	mapModTime time.Time
+	// validated is set once the diagnostics of the current import map were reported
+	validated bool
+	limiter   *fetchLimiter
+	client    *http.Client
+	fetcher   Fetcher
+	vendored  vendorStore
+	downloads fetchGroup
+	offline   offlineMisses
+	lock      lockState
+	stats     statsCollector
+	// resolutions memoizes the resolutions of the import maps
+	resolutions resolutionCache
+	// cacheDir is CacheDir with the home directory expanded
+	cacheDir string
+
+	// entryPointMaps are the maps of the entry points with an overlay, entryPoints tracking
+	// the entry point each module was reached from
+	entryPointMaps map[string]importmap.IImportMap
+	entryPoints    entryPointTracker
+	build          api.PluginBuild
+	// ctx is done when Config.Context is or the build context the plugin is set up in is disposed
+	ctx context.Context
+
+	usage     usageTracker
+	externals externalsTracker
+	// external are the external patterns of the config and the build options, left to the
+	// browser's import map
+	external []string
+
+	// publicPath and outdir are captured from the build options during setup
+	publicPath string
+	outdir     string
 }
 
 type Option func(config *Config)
@@ -29,30 +253,152 @@ type Option func(config *Config)
 func NewPlugin(opts ...Option) (api.Plugin, error) {
 	config := &Config{}
 
-	for _, opt := range opts {
-		opt(config)
+	if err := applyOptions(config, opts); err != nil {
+		return api.Plugin{}, err
+	}
+
+	sources := 0
+	for _, source := range []string{config.ImportMapPath, config.ImportMapURL, config.ImportMapHTMLPath} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return api.Plugin{}, fmt.Errorf("only one of an import map path, URL or HTML file can be provided")
+	}
+	mapOpts, err := mapOptions(config)
+	if err != nil {
+		return api.Plugin{}, err
+	}
+	if config.OutputIntegrity != "" {
+		if _, err := importmap.ComputeIntegrity(nil, config.OutputIntegrity); err != nil {
+			return api.Plugin{}, err
+		}
+	} else if config.HTMLIntegrityAttributes {
+		return api.Plugin{}, fmt.Errorf("the integrity attributes of the HTML file require WithOutputIntegrity")
+	}
+
+	if config.ImportMapPath != "" {
+		if config.ImportMap, err = loadImportMapPath(config); err != nil {
+			return api.Plugin{}, err
+		}
+	}
+
+	if config.ImportMapHTMLPath != "" {
+		htmlOpts := []importmap.HTMLOption{importmap.WithHTMLMapOptions(mapOpts...)}
+		if baseUrl, _ := parseBaseURL(config); baseUrl != nil {
+			htmlOpts = append(htmlOpts, importmap.WithHTMLBaseUrl(baseUrl))
+		}
+		config.ImportMap, err = importmap.LoadFromHTML(config.ImportMapHTMLPath, htmlOpts...)
+		if err != nil {
+			return api.Plugin{}, err
+		}
+	}
+
+	if config.ImportMapURL != "" {
+		config.ImportMap, err = loadImportMapURL(configContext(config), config, nil)
+		if err != nil {
+			return api.Plugin{}, err
+		}
 	}
 
 	var importMap importmap.IImportMap
 	if config.ImportMapData != nil {
-		var err error
 		importMap, err = importmap.New(
-			importmap.WithMap(*config.ImportMapData),
+			append([]importmap.Option{importmap.WithMap(*config.ImportMapData)}, mapOpts...)...,
 		)
 
 		if err != nil {
 			return api.Plugin{}, err
 		}
 	}
+	for _, data := range config.ImportMaps {
+		m, err := importmap.New(append([]importmap.Option{importmap.WithMap(data)}, mapOpts...)...)
+		if err != nil {
+			return api.Plugin{}, err
+		}
+		if importMap == nil {
+			importMap = m
+			continue
+		}
+		if importMap, err = importmap.Merge(importMap, m, importmap.MergeScopes); err != nil {
+			return api.Plugin{}, err
+		}
+	}
 	if config.ImportMap != nil {
 		importMap = config.ImportMap
 	}
 	if importMap == nil {
 		return api.Plugin{}, fmt.Errorf("no importmap was provided")
 	}
+	if importMap, err = addPackageImports(config, importMap); err != nil {
+		return api.Plugin{}, err
+	}
+
+	p, err := newPlugin(config, importMap)
+	if err != nil {
+		return api.Plugin{}, err
+	}
+
 	return api.Plugin{
 		Name:  "importmap-url",
-		Setup: setup(importMap),
+		Setup: p.setup,
+	}, nil
+}
+
+func newPlugin(config *Config, importMap importmap.IImportMap) (*plugin, error) {
+	maxFetches := config.MaxConcurrentFetches
+	if maxFetches <= 0 {
+		maxFetches = defaultMaxConcurrentFetches()
+	}
+	maxFetchesPerHost := config.MaxFetchesPerHost
+	if maxFetchesPerHost <= 0 {
+		maxFetchesPerHost = defaultMaxFetchesPerHost
+	}
+
+	client, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := config.Fetcher
+	if fetcher == nil {
+		fetcher = &HTTPFetcher{Client: client, Cache: NewHTTPCache()}
+	}
+
+	cacheDir, err := expandHome(config.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ResolveFilter != "" {
+		if _, err = regexp.Compile(config.ResolveFilter); err != nil {
+			return nil, fmt.Errorf("invalid resolve filter: %w", err)
+		}
+	}
+	if len(config.UnknownShims) > 0 {
+		return nil, fmt.Errorf("no built-in shim for %s", strings.Join(config.UnknownShims, ", "))
+	}
+
+	entryPointMaps, err := newEntryPointMaps(config, importMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plugin{
+		config:     config,
+		importMap:  importMap,
+		limiter:    newFetchLimiter(maxFetches, maxFetchesPerHost),
+		client:     client,
+		fetcher:    fetcher,
+		vendored:   vendorStore{files: make(map[string]string)},
+		cacheDir:   cacheDir,
+		mapModTime: modTime(config.ImportMapPath),
+		ctx:        configContext(config),
+
+		entryPointMaps: entryPointMaps,
+		entryPoints:    entryPointTracker{modules: make(map[string]string)},
+		usage:          usageTracker{used: make(map[mappingKey]bool)},
+		externals:      externalsTracker{resolutions: make(map[mappingKey][]importmap.Resolution)},
 	}, nil
 }
 
@@ -63,100 +409,451 @@ func WithMap(importMap importmap.Data) Option {
 	}
 }
 
-// WithImportMapPath sets the path to the import map json file
+// WithImportMap sets an import map built beforehand, e.g. by the importmap/generate package
+func WithImportMap(importMap importmap.IImportMap) Option {
+	return func(config *Config) {
+		config.ImportMap = importMap
+	}
+}
+
+// WithMaps composes several import maps, later maps taking precedence over earlier ones and over
+// the map set by WithMap. Imports and integrity values of later maps replace the ones of earlier
+// maps, while scopes are merged entry by entry, the later entry winning.
+func WithMaps(maps ...importmap.Data) Option {
+	return func(config *Config) {
+		config.ImportMaps = append(config.ImportMaps, maps...)
+	}
+}
+
+// WithImportMapPath sets the path to the import map json file, loaded by NewPlugin.
+// A package.json file is accepted too, its map being read from the key set by WithPackageJSONKey,
+// as well as a deno.json or deno.jsonc file and a tsconfig.json file, whose path aliases are
+// converted by importmap.FromTSConfig.
 func WithImportMapPath(path string) Option {
 	return func(config *Config) {
-		data, err := importmap.LoadFromFile(path)
-		if err != nil {
-			panic(err)
-		}
-		config.ImportMap = data
+		config.ImportMapPath = path
 	}
 }
 
-func setup(importMap importmap.IImportMap) func(b api.PluginBuild) {
-	return func(b api.PluginBuild) {
-		b.OnResolve(api.OnResolveOptions{
-			Filter: "^[^.].*$",
-		}, onResolve(importMap))
+// WithImportMapHTML reads the import maps of an HTML file, e.g. the page served to the browser,
+// in NewPlugin. Relative URLs are resolved against the file, or its <base href>.
+func WithImportMapHTML(path string) Option {
+	return func(config *Config) {
+		config.ImportMapHTMLPath = path
+	}
+}
+
+// WithPackageJSONKey sets the package.json key holding the import map, importmap.DefaultPackageJSONKey by default
+func WithPackageJSONKey(key string) Option {
+	return func(config *Config) {
+		config.PackageJSONKey = key
+	}
+}
+
+// WithOnUnresolved sets what happens with specifiers the import map has no mapping for
+func WithOnUnresolved(behavior UnresolvedBehavior) Option {
+	return func(config *Config) {
+		config.OnUnresolved = behavior
+	}
+}
+
+// WithRootCAs sets the certificate pool used to verify remote servers instead of the system one
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(config *Config) {
+		config.RootCAs = pool
+	}
+}
+
+// WithCABundle trusts the certificates of a PEM file in addition to the system ones,
+// e.g. the root certificate of a TLS intercepting corporate proxy
+func WithCABundle(path string) Option {
+	return func(config *Config) {
+		config.CABundlePaths = append(config.CABundlePaths, path)
+	}
+}
+
+// WithClientCertificate sets a certificate presented to servers requesting mutual TLS
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(config *Config) {
+		config.ClientCertificates = append(config.ClientCertificates, cert)
+	}
+}
+
+// WithClientCertificateFiles loads a PEM certificate and key presented to servers requesting mutual TLS
+func WithClientCertificateFiles(certFile string, keyFile string) Option {
+	return func(config *Config) {
+		config.ClientCertificateFiles = append(config.ClientCertificateFiles, [2]string{certFile, keyFile})
+	}
+}
+
+// WithContentTypeCheck rejects downloaded modules whose Content-Type doesn't fit the loader
+func WithContentTypeCheck() Option {
+	return func(config *Config) {
+		config.CheckContentType = true
+	}
+}
+
+// WithVendorDir writes downloaded modules to dir, along with an import map pointing at them
+func WithVendorDir(dir string) Option {
+	return func(config *Config) {
+		config.VendorDir = dir
+	}
+}
+
+// WithHashedVendorFiles names vendored modules after their content hash
+func WithHashedVendorFiles() Option {
+	return func(config *Config) {
+		config.HashVendorFiles = true
+	}
+}
+
+// WithPublicPath sets the URL the output directory is served from
+func WithPublicPath(publicPath string) Option {
+	return func(config *Config) {
+		config.PublicPath = publicPath
+	}
+}
+
+// WithHooks sets the callbacks called around downloads and resolution misses
+func WithHooks(hooks Hooks) Option {
+	return func(config *Config) {
+		config.Hooks = hooks
+	}
+}
 
+// WithWarmup enables DNS prefetching and connection warm-up for the remote origins in the map
+func WithWarmup() Option {
+	return func(config *Config) {
+		config.Warmup = true
+	}
+}
+
+// WithMaxConcurrentFetches bounds the number of parallel downloads across all origins, which
+// defaults to a few per core
+func WithMaxConcurrentFetches(n int) Option {
+	return func(config *Config) {
+		config.MaxConcurrentFetches = n
+	}
+}
+
+// WithMaxFetchesPerHost overrides the number of parallel downloads allowed per origin
+func WithMaxFetchesPerHost(n int) Option {
+	return func(config *Config) {
+		config.MaxFetchesPerHost = n
+	}
+}
+
+func (p *plugin) setup(b api.PluginBuild) {
+	p.build = b
+	p.external = append(append([]string(nil), b.InitialOptions.External...), p.config.External...)
+	p.configureOutput(b.InitialOptions)
+	ctx, cancel := context.WithCancel(configContext(p.config))
+	p.ctx = ctx
+	b.OnDispose(cancel)
+
+	if p.config.VendorDir != "" && p.config.VendorCheckInterval > 0 && !p.config.Offline {
+		p.startVendorCheck()
+	}
+
+	b.OnStart(recoverOnStart(p.onStart))
+	b.OnEnd(recoverOnEnd(p.onEnd))
+
+	if p.config.GlobalShims && b.InitialOptions != nil {
+		p.injectGlobalShims(b.InitialOptions)
+		b.OnResolve(api.OnResolveOptions{
+			Filter: "^" + shimGlobalNamespace + ":",
+		}, recoverOnResolve(p.onResolveGlobalShim))
 		b.OnLoad(api.OnLoadOptions{
 			Filter:    ".*",
-			Namespace: namespace,
-		}, func(args api.OnLoadArgs) (api.OnLoadResult, error) {
-			loader := api.LoaderJS
-			ext := path.Ext(args.Path)
-			switch ext {
-			case ".ts":
-				loader = api.LoaderTS
-				break
-			case ".tsx":
-				loader = api.LoaderTSX
-			case ".jsx":
-				loader = api.LoaderJSX
+			Namespace: shimGlobalNamespace,
+		}, recoverOnLoad(p.onLoadGlobalShim))
+	}
+
+	onResolve := p.onResolve
+	if p.config.ImportMapPath != "" {
+		onResolve = p.watchImportMap(onResolve)
+	}
+	if p.config.OnStats != nil {
+		onResolve = p.countResolutions(onResolve)
+	}
+	if p.config.Debug {
+		onResolve = p.traceResolutions(onResolve)
+	}
+	if len(p.config.IncludedSpecifiers) > 0 || len(p.config.ExcludedSpecifiers) > 0 {
+		onResolve = p.filterSpecifiers(onResolve)
+	}
+
+	// the imports of the modules of the plugin's namespaces are resolved whatever the filter
+	if filter := p.resolveFilter(); filter != ".*" {
+		for _, ns := range []string{namespace, schemeNamespace, shimGlobalNamespace} {
+			b.OnResolve(api.OnResolveOptions{
+				Filter:    ".*",
+				Namespace: ns,
+			}, recoverOnResolve(onResolve))
+		}
+	}
+	b.OnResolve(api.OnResolveOptions{
+		Filter: p.resolveFilter(),
+	}, recoverOnResolve(onResolve))
+
+	b.OnLoad(api.OnLoadOptions{
+		Filter:    ".*",
+		Namespace: namespace,
+	}, recoverOnLoad(p.onLoad))
+
+	b.OnLoad(api.OnLoadOptions{
+		Filter:    ".*",
+		Namespace: shimNamespace,
+	}, recoverOnLoad(p.onLoadShim))
+
+	b.OnLoad(api.OnLoadOptions{
+		Filter:    ".*",
+		Namespace: schemeNamespace,
+	}, recoverOnLoad(p.onLoadScheme))
+}
+
+func (p *plugin) onLoad(args api.OnLoadArgs) (api.OnLoadResult, error) {
+	if isDataURL(args.Path) {
+		return p.onLoadDataURL(args)
+	}
+	if !strings.Contains(args.Path, "http") {
+		loader, ok := p.extensionLoader(args.Path)
+		if !ok {
+			loader = api.LoaderJS
+		}
+		cleanedPath := filePath(args.Path)
+		if filepath.IsLocal(cleanedPath) || filepath.IsAbs(cleanedPath) {
+			fileContents, err := os.ReadFile(cleanedPath)
+			if err != nil {
+				return api.OnLoadResult{}, err
+			}
+
+			warnings, err := p.verifyIntegrity(args.Path, fileContents)
+			if err != nil {
+				return api.OnLoadResult{}, err
 			}
-			if !strings.Contains(args.Path, "http") {
-				cleanedPath := strings.Replace(args.Path, "file://", "", 1)
-				if filepath.IsLocal(cleanedPath) || filepath.IsAbs(cleanedPath) {
-					fileContents, err := os.ReadFile(cleanedPath)
-					if err != nil {
-						return api.OnLoadResult{}, err
-					}
-
-					fileContentsStr := string(fileContents)
-
-					return api.OnLoadResult{
-						Contents: &fileContentsStr,
-						Loader:   loader,
-					}, nil
-				} else {
-					return api.OnLoadResult{}, errors.New("invalid path: " + args.Path)
-				}
-			} else {
-				// download from url
-				resp, err := http.Get(args.Path)
-
-				if err != nil {
-					return api.OnLoadResult{}, err
-				}
-
-				defer func(Body io.ReadCloser) {
-					_ = Body.Close()
-				}(resp.Body)
-
-				var buf bytes.Buffer
-
-				_, err = io.Copy(&buf, resp.Body)
-				if err != nil {
-					return api.OnLoadResult{}, err
-				}
-
-				contents := buf.String()
-
-				return api.OnLoadResult{
-					Contents: &contents,
-					Loader:   loader,
-				}, nil
+
+			fileContentsStr := string(fileContents)
+
+			return api.OnLoadResult{
+				Contents:   &fileContentsStr,
+				Loader:     loader,
+				Warnings:   warnings,
+				PluginData: loadedProvenance(args, ""),
+				WatchFiles: []string{cleanedPath},
+				// the imports left to esbuild, see UnresolvedPassthrough, are looked up from the file
+				ResolveDir: filepath.Dir(cleanedPath),
+			}, nil
+		} else {
+			return api.OnLoadResult{}, errors.New("invalid path: " + args.Path)
+		}
+	} else {
+		// download from url
+		result, err := p.fetch(p.ctx, args.Path)
+		if err != nil {
+			return api.OnLoadResult{}, err
+		}
+
+		// the vendored copies are transformed, so their body can't be checked against the
+		// integrity and lockfile hashes of the downloads, which were checked when vendoring
+		var warnings []api.Message
+		if !result.vendored {
+			warnings, err = p.verifyIntegrity(args.Path, []byte(result.body))
+			if err != nil {
+				return api.OnLoadResult{}, err
+			}
+		}
+
+		if p.config.LockfilePath != "" && !result.vendored {
+			if err = p.checkLockfile(args.Path, []byte(result.body)); err != nil {
+				return api.OnLoadResult{}, err
+			}
+		}
+
+		loader := p.remoteLoader(args.Path, result)
+		if p.config.CheckContentType {
+			if err = checkContentType(args.Path, result, loader); err != nil {
+				return api.OnLoadResult{}, err
+			}
+		}
+
+		if !result.vendored {
+			result.contents = p.interopContents(result, loader)
+		}
+
+		if p.config.VendorDir != "" {
+			if err = p.vendor(args.Path, result.contents); err != nil {
+				return api.OnLoadResult{}, err
 			}
-		})
+		}
+
+		return api.OnLoadResult{
+			Contents:   &result.contents,
+			Loader:     loader,
+			Warnings:   warnings,
+			PluginData: loadedProvenance(args, result.finalUrl),
+		}, nil
 	}
 }
 
-func onResolve(importMap importmap.IImportMap) func(args api.OnResolveArgs) (api.OnResolveResult, error) {
-	return func(args api.OnResolveArgs) (api.OnResolveResult, error) {
-		parsedImporterUrl, err := url.Parse(args.Importer)
+func (p *plugin) onResolve(args api.OnResolveArgs) (api.OnResolveResult, error) {
+	if _, ok := args.PluginData.(entryPointResolve); ok {
+		return api.OnResolveResult{}, nil
+	}
+
+	importMap := p.currentMap()
+	var entryPoint string
+	if p.hasEntryPointMaps() {
+		entryPoint = p.entryPoint(args)
+		importMap = p.mapFor(entryPoint)
+	}
+
+	// relative paths of files outside the plugin's namespace are left to esbuild
+	kind, _ := importmap.ParseSpecifier(args.Path, nil)
+	if kind == importmap.SpecifierRelative && !strings.HasPrefix(args.Path, "/") &&
+		args.Namespace != namespace && args.Namespace != schemeNamespace &&
+		!(p.config.PackageImportsPath != "" && strings.HasPrefix(args.Path, "#")) {
+		return api.OnResolveResult{}, nil
+	}
+
+	parsedImporterUrl, err := importerUrl(args)
+	if err != nil {
+		return api.OnResolveResult{}, err
+	}
+
+	resolution, err := p.resolutions.resolve(importMap, entryPoint, args.Path, parsedImporterUrl)
+	resolvedPath := p.rootFile(resolution.URL)
+	var unresolvedErr *importmap.UnresolvedError
+	if errors.As(err, &unresolvedErr) {
+		if hook := p.config.Hooks.OnResolveMiss; hook != nil {
+			hook(args.Path, args.Importer)
+		}
+		if p.config.OnStats != nil {
+			p.stats.unresolved()
+		}
+		if p.config.DeriveSubpaths {
+			if result, ok := p.resolveDerivedSubpath(importMap, args.Path, parsedImporterUrl); ok {
+				return p.withResolutionWarnings(result, importMap, args.Path, parsedImporterUrl, resolution), nil
+			}
+		}
+		if result, ok := p.resolveShim(args.Path); ok {
+			return p.withResolutionWarnings(result, importMap, args.Path, parsedImporterUrl, resolution), nil
+		}
+		result, err := p.onUnresolved(args, err)
 		if err != nil {
-			return api.OnResolveResult{}, err
+			return result, err
+		}
+		return p.withResolutionWarnings(result, importMap, args.Path, parsedImporterUrl, resolution), nil
+	}
+	if err != nil {
+		return api.OnResolveResult{}, err
+	}
+	if resolvedPath, err = p.resolveRegistry(resolvedPath); err != nil {
+		return api.OnResolveResult{}, err
+	}
+	resolvedPath = p.realFileUrl(resolvedPath)
+
+	if resolution.Key != "" && matchesExternal(p.external, args.Path) {
+		if p.tracksExternals() {
+			p.externals.record(resolution)
 		}
+		if p.reportsUnused() {
+			p.usage.record(resolution)
+		}
+		return api.OnResolveResult{
+			Path:     args.Path,
+			External: true,
+		}, nil
+	}
 
-		resolvedPath, err := importMap.ResolveWithParent(args.Path, parsedImporterUrl)
+	if result, ok, err := p.resolveScheme(resolvedPath, args.Importer); ok {
 		if err != nil {
 			return api.OnResolveResult{}, err
 		}
-		// this should call our custom importmap object
+		if p.reportsUnused() {
+			p.usage.record(resolution)
+		}
+		return p.withResolutionWarnings(result, importMap, args.Path, parsedImporterUrl, resolution), nil
+	}
+
+	if isRemoteAsset(args.Kind, resolvedPath) {
+		if p.reportsUnused() {
+			p.usage.record(resolution)
+		}
+		return api.OnResolveResult{
+			Path:     resolvedPath,
+			External: true,
+		}, nil
+	}
+
+	if publicUrl, ok := p.emittedOutput(resolvedPath); ok {
+		return api.OnResolveResult{
+			Path:     publicUrl,
+			External: true,
+		}, nil
+	}
+
+	if entryPoint != "" {
+		p.entryPoints.set(resolvedPath, entryPoint)
+	}
+	if p.reportsUnused() {
+		p.usage.record(resolution)
+	}
+
+	// this should call our custom importmap object
+	return p.withResolutionWarnings(api.OnResolveResult{
+		Path:      resolvedPath,
+		Namespace: "importmap-url",
+		PluginData: &Provenance{
+			Specifier: args.Path,
+			Importer:  parsedImporterUrl.String(),
+			Scope:     resolution.Scope,
+			Key:       resolution.Key,
+			Parent:    provenanceOf(args.PluginData),
+		},
+	}, importMap, args.Path, parsedImporterUrl, resolution), nil
+}
+
+// onUnresolved handles specifiers without a mapping according to Config.OnUnresolved.
+func (p *plugin) onUnresolved(args api.OnResolveArgs, err error) (api.OnResolveResult, error) {
+	switch p.config.OnUnresolved {
+	case UnresolvedWarn:
 		return api.OnResolveResult{
-			Path:      resolvedPath,
-			Namespace: "importmap-url",
+			Warnings: []api.Message{{Text: err.Error()}},
 		}, nil
+	case UnresolvedPassthrough:
+		return api.OnResolveResult{}, nil
+	case UnresolvedExternal:
+		return api.OnResolveResult{
+			Path:     args.Path,
+			External: true,
+		}, nil
+	default:
+		return api.OnResolveResult{}, err
+	}
+}
+
+// WithMapFormat lays out the import maps written by WithResidualMap, WithHTMLInjection,
+// WithExternalsMap and WithVendorDir, e.g. with the indentation of the project's formatter, or
+// compact to inline the map into HTML.
+func WithMapFormat(opts ...importmap.FormatOption) Option {
+	return func(config *Config) {
+		config.MapFormat = append(config.MapFormat, opts...)
+	}
+}
+
+// WithEnvironment applies the overrides the import maps have for environment, e.g. "development"
+// to map packages to their unminified builds, see importmap.Data.Environments.
+func WithEnvironment(environment string) Option {
+	return func(config *Config) {
+		config.Environment = environment
+	}
+}
+
+// WithConditions applies the overrides the import maps have for the runtime the bundle targets,
+// e.g. "deno" then "browser", the first conditions taking precedence. See importmap.Data.Conditions.
+func WithConditions(conditions ...string) Option {
+	return func(config *Config) {
+		config.Conditions = conditions
 	}
 }
@@ -1,9 +1,15 @@
 package esbuild_plugin_importmap
 
 import (
+	"context"
+	"errors"
 	"github.com/evanw/esbuild/pkg/api"
 	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -132,3 +138,341 @@ func TestPluginWithLocalModules(t *testing.T) {
 
 	t.Logf("Result contents:\n%s", result.OutputFiles[0].Contents)
 }
+
+func buildWithPlugin(t *testing.T, staticTestContent string, plugin api.Plugin) api.BuildResult {
+	t.Helper()
+
+	return api.Build(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		Write:       false,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, staticTestContent),
+			plugin,
+		},
+	})
+}
+
+func TestPluginOnUnresolved(t *testing.T) {
+	const content = "import {missing} from 'missing-package'; console.log(missing);"
+	data := importmap.Data{Imports: importmap.Imports{"@/": "./"}}
+
+	plugin, err := NewPlugin(WithMap(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := buildWithPlugin(t, content, plugin); len(result.Errors) == 0 {
+		t.Error("expected the build to fail by default")
+	}
+
+	plugin, err = NewPlugin(WithMap(data), WithOnUnresolved(UnresolvedExternal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, content, plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), `from "missing-package"`) {
+		t.Errorf("expected the import to be left untouched, got:\n%s", result.OutputFiles[0].Contents)
+	}
+
+	plugin, err = NewPlugin(WithMap(data), WithOnUnresolved(UnresolvedWarn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result = buildWithPlugin(t, content, plugin); len(result.Warnings) == 0 {
+		t.Error("expected a warning for the unresolved specifier")
+	}
+}
+
+func TestPluginPassthroughMixedSetup(t *testing.T) {
+	// only app comes from the map, dep is installed in node_modules and imported by both the
+	// entry point and the mapped file
+	dir := t.TempDir()
+	files := map[string]string{
+		"lib/app.js":                    "import {dep} from 'dep'; export const app = 'mapped ' + dep;",
+		"node_modules/dep/package.json": `{"name": "dep", "main": "index.js"}`,
+		"node_modules/dep/index.js":     "export const dep = 'installed';",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"app": importmap.FileURL(dir).String() + "/lib/app.js"}}),
+		WithOnUnresolved(UnresolvedPassthrough),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := api.Build(api.BuildOptions{
+		Bundle:   true,
+		Format:   api.FormatESModule,
+		LogLevel: api.LogLevelSilent,
+		Stdin: &api.StdinOptions{
+			Contents:   "import {app} from 'app'; import {dep} from 'dep'; console.log(app, dep);",
+			ResolveDir: dir,
+		},
+		Plugins: []api.Plugin{plugin},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "mapped ") || strings.Count(output, `"installed"`) != 1 {
+		t.Errorf("expected dep to be resolved by esbuild once for both importers, got:\n%s", output)
+	}
+}
+
+func TestPluginHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		_, _ = w.Write([]byte("export const pkg = '" + strings.TrimPrefix(r.URL.Path, "/") + "';"))
+	}))
+	defer server.Close()
+
+	data := importmap.Data{Imports: importmap.Imports{
+		"pkg":     server.URL + "/pkg.js",
+		"blocked": server.URL + "/blocked.js",
+	}}
+
+	var misses []string
+	plugin, err := NewPlugin(
+		WithMap(data),
+		WithOnUnresolved(UnresolvedExternal),
+		WithHooks(Hooks{
+			OnBeforeFetch: func(ctx context.Context, rawUrl string) (string, error) {
+				if strings.HasSuffix(rawUrl, "/blocked.js") {
+					return "", errors.New("blocked by policy")
+				}
+				return strings.Replace(rawUrl, "/pkg.js", "/rewritten.js", 1), nil
+			},
+			OnAfterFetch: func(rawUrl string, contents string) (string, error) {
+				return contents + "\nconsole.log('fetched " + rawUrl + "');", nil
+			},
+			OnResolveMiss: func(specifier string, importer string) {
+				misses = append(misses, specifier)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; import 'missing'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `"rewritten.js"`) || !strings.Contains(output, "/rewritten.js\")") {
+		t.Errorf("expected the fetch to be rewritten, got:\n%s", output)
+	}
+	if len(misses) != 1 || misses[0] != "missing" {
+		t.Errorf("expected a resolution miss for missing, got %v", misses)
+	}
+
+	result = buildWithPlugin(t, "import {pkg} from 'blocked'; console.log(pkg);", plugin)
+	if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Text, "blocked by policy") {
+		t.Errorf("expected the fetch to be vetoed, got %v", result.Errors)
+	}
+}
+
+func TestPluginWithPackageJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "package.json")
+	contents := `{"name": "app", "imports-map": {"imports": {"pkg": "https://mirror.invalid/pkg.js"}}}`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(
+		WithImportMapPath(path),
+		WithPackageJSONKey("imports-map"),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/pkg.js": "export const pkg = 'manifest';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	if _, err = NewPlugin(WithImportMapPath(path)); err == nil {
+		t.Error("expected an error without a map under the default key")
+	}
+}
+
+func TestPluginWithDenoConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deno.jsonc")
+	contents := `{
+		// mirrors the CDN used by the Deno code
+		"imports": {"pkg": "https://mirror.invalid/pkg.js"},
+	}`
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(
+		WithImportMapPath(path),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/pkg.js": "export const pkg = 'deno';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "deno") {
+		t.Errorf("expected the module mapped by the Deno config, got:\n%s", output)
+	}
+}
+
+func TestPluginWithTSConfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"tsconfig.json": `{"compilerOptions": {"paths": {"@/*": ["./src/*"]}}}`,
+		"src/util.ts":   "export const util: string = 'aliased';",
+	}
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plugin, err := NewPlugin(WithImportMapPath(filepath.Join(dir, "tsconfig.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {util} from '@/util.ts'; console.log(util);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "aliased") {
+		t.Errorf("expected the module of the path alias, got:\n%s", output)
+	}
+}
+
+func TestPluginWithImportMapHTML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.html")
+	doc := `<!DOCTYPE html><html><head>` +
+		`<script type="importmap">{"imports": {"pkg": "https://mirror.invalid/pkg.js"}}</script>` +
+		`<script type="module" src="./index.js"></script></head></html>`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(
+		WithImportMapHTML(path),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/pkg.js": "export const pkg = 'html';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	if _, err = NewPlugin(WithImportMapHTML(path), WithImportMapPath(path)); err == nil {
+		t.Error("expected an error for several map sources")
+	}
+}
+
+func TestPluginWithImportMapHTMLEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.html")
+	doc := `<script type="importmap">{
+		"imports": {"pkg": "https://cdn.invalid/pkg.development.js"},
+		"environments": {"production": {"imports": {"pkg": "https://cdn.invalid/pkg.production.js"}}}
+	}</script>`
+	if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(
+		WithImportMapHTML(path),
+		WithEnvironment("production"),
+		WithFetcher(fixtureFetcher{"https://cdn.invalid/pkg.production.js": "export const pkg = 'production';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "production") {
+		t.Errorf("expected the production build of pkg, got:\n%s", output)
+	}
+}
+
+func TestPluginWithMaps(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"a": "https://mirror.invalid/a@1.js",
+			"b": "https://mirror.invalid/b@1.js",
+		}}),
+		WithMaps(
+			importmap.Data{Imports: importmap.Imports{"a": "https://mirror.invalid/a@2.js"}},
+			importmap.Data{Imports: importmap.Imports{"a": "https://mirror.invalid/a@3.js"}},
+		),
+		WithFetcher(fixtureFetcher{
+			"https://mirror.invalid/a@3.js": "export const a = 'a3';",
+			"https://mirror.invalid/b@1.js": "export const b = 'b1';",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {a} from 'a'; import {b} from 'b'; console.log(a, b);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "a3") || !strings.Contains(output, "b1") {
+		t.Errorf("expected the last map to win, got:\n%s", output)
+	}
+}
+
+func TestPluginWithImportMap(t *testing.T) {
+	m, err := importmap.New(importmap.WithMap(importmap.Data{Imports: importmap.Imports{
+		"pkg": "https://mirror.invalid/pkg.js",
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plugin, err := NewPlugin(
+		WithImportMap(m),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/pkg.js": "export const pkg = 'built';"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, "built") {
+		t.Errorf("expected the module of the given map, got:\n%s", output)
+	}
+}
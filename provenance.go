@@ -0,0 +1,42 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// Provenance is the PluginData of the modules the plugin resolves and loads, telling how they were
+// reached. esbuild passes it to the resolution of their imports, so cooperating plugins resolving
+// or loading the paths of the plugin's namespace can read it with a type assertion to
+// *Provenance.
+type Provenance struct {
+	// Specifier is the import the module was resolved from, as written
+	Specifier string
+	// Importer is the URL of the module importing it, which scopes are matched against
+	Importer string
+	// Scope is the key of the scope of the entry used, empty for the top-level imports
+	Scope string
+	// Key is the key of the entry used, empty when the specifier is a URL without a mapping
+	Key string
+	// BaseURL is the URL a downloaded module was served from after redirects, which its relative
+	// imports and scopes are resolved against. It is empty until the module is loaded.
+	BaseURL string
+	// Parent is the provenance of the importer, nil for the modules imported by local files
+	Parent *Provenance
+}
+
+// provenanceOf returns the provenance in the plugin data of a resolution or load, nil without one.
+func provenanceOf(pluginData any) *Provenance {
+	provenance, _ := pluginData.(*Provenance)
+	return provenance
+}
+
+// loadedProvenance returns the provenance of a module loaded from baseUrl, keeping the one of its
+// resolution.
+func loadedProvenance(args api.OnLoadArgs, baseUrl string) *Provenance {
+	provenance := &Provenance{}
+	if resolved := provenanceOf(args.PluginData); resolved != nil {
+		*provenance = *resolved
+	}
+	provenance.BaseURL = baseUrl
+	return provenance
+}
@@ -0,0 +1,60 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"testing"
+)
+
+func TestPluginProvenance(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"lib": "https://mirror.invalid/lib/index.js"}}),
+		WithFetcher(fixtureFetcher{
+			"https://mirror.invalid/lib/index.js": "export * from './dep.js';",
+			"https://mirror.invalid/lib/dep.js":   "export * from './leaf.js';",
+			"https://mirror.invalid/lib/leaf.js":  "export const leaf = 1;",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a cooperating plugin reads the provenance of the importers of the plugin's modules
+	importers := make(map[string]*Provenance)
+	spy := api.Plugin{
+		Name: "spy",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: ".*", Namespace: namespace}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				importers[args.Path], _ = args.PluginData.(*Provenance)
+				return api.OnResolveResult{}, nil
+			})
+		},
+	}
+
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins:     []api.Plugin{spy, plugin, getFileTreePlugin(t, "import {leaf} from 'lib'; console.log(leaf);")},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	lib := importers["./dep.js"]
+	if lib == nil || lib.Specifier != "lib" || lib.Key != "lib" || lib.Scope != "" || lib.Parent != nil {
+		t.Fatalf("unexpected provenance of lib %+v", lib)
+	}
+	if lib.BaseURL != "https://mirror.invalid/lib/index.js" {
+		t.Errorf("expected the URL lib was loaded from, got %s", lib.BaseURL)
+	}
+
+	dep := importers["./leaf.js"]
+	if dep == nil || dep.Specifier != "./dep.js" || dep.Key != "" || dep.Importer != "https://mirror.invalid/lib/index.js" {
+		t.Fatalf("unexpected provenance of dep %+v", dep)
+	}
+	if dep.BaseURL != "https://mirror.invalid/lib/dep.js" || dep.Parent == nil || dep.Parent.Specifier != "lib" {
+		t.Errorf("expected the provenance of lib to be the parent of dep's, got %+v", dep)
+	}
+}
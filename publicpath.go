@@ -0,0 +1,66 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// configureOutput captures the public path and output directory of the build the plugin is set up for.
+// The public path of the plugin takes precedence over the one of the build options.
+func (p *plugin) configureOutput(options *api.BuildOptions) {
+	p.publicPath = p.config.PublicPath
+	p.outdir = ""
+	if options == nil {
+		return
+	}
+
+	if p.publicPath == "" {
+		p.publicPath = options.PublicPath
+	}
+
+	outdir := options.Outdir
+	if outdir == "" && options.Outfile != "" {
+		outdir = filepath.Dir(options.Outfile)
+	}
+	if outdir != "" {
+		if abs, err := filepath.Abs(outdir); err == nil {
+			p.outdir = abs
+		}
+	}
+}
+
+// publicUrl joins the public path and a slash separated path relative to the output directory.
+// Without a public path the result is relative to the referencing file, using "./".
+func (p *plugin) publicUrl(name string) string {
+	if p.publicPath == "" {
+		return "./" + name
+	}
+	return strings.TrimSuffix(p.publicPath, "/") + "/" + strings.TrimPrefix(name, "/")
+}
+
+// emittedOutput returns the public URL of a resolved file URL pointing inside the output directory.
+// Such files are emitted by the build itself (e.g. chunks of a previous build or copied assets), so
+// they are referenced through the public path instead of being bundled again.
+func (p *plugin) emittedOutput(resolved string) (string, bool) {
+	if p.outdir == "" || p.publicPath == "" {
+		return "", false
+	}
+
+	u, err := url.Parse(resolved)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+
+	path, err := importmap.FilePath(u)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(p.outdir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return p.publicUrl(filepath.ToSlash(rel)), true
+}
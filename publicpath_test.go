@@ -0,0 +1,50 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginKeepsEmittedOutputsExternal(t *testing.T) {
+	outdir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outdir, "shared.js"), []byte("export const shared = 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(WithMap(importmap.Data{
+		Imports: importmap.Imports{
+			"@shared": "file://" + filepath.ToSlash(outdir) + "/shared.js",
+		},
+	}), WithPublicPath("https://static.example/assets/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		Splitting:   true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		Outdir:      outdir,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, "import('@shared').then(m => console.log(m.shared));"),
+			plugin,
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	var output string
+	for _, file := range result.OutputFiles {
+		output += string(file.Contents)
+	}
+	if !strings.Contains(output, `import("https://static.example/assets/shared.js")`) {
+		t.Errorf("expected the emitted file to be imported through the public path, got:\n%s", output)
+	}
+}
@@ -0,0 +1,76 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"runtime/debug"
+)
+
+// panicMessage converts a recovered panic into an esbuild error message carrying the stack trace.
+func panicMessage(r interface{}) api.Message {
+	return api.Message{
+		Text:  fmt.Sprintf("panic: %v", r),
+		Notes: []api.Note{{Text: string(debug.Stack())}},
+	}
+}
+
+// recoverOnResolve makes panics of an OnResolve callback fail the build instead of the process.
+func recoverOnResolve(callback func(api.OnResolveArgs) (api.OnResolveResult, error)) func(api.OnResolveArgs) (api.OnResolveResult, error) {
+	return func(args api.OnResolveArgs) (result api.OnResolveResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = api.OnResolveResult{Errors: []api.Message{panicMessage(r)}}, nil
+			}
+		}()
+		return callback(args)
+	}
+}
+
+// recoverOnLoad makes panics of an OnLoad callback fail the build instead of the process.
+func recoverOnLoad(callback func(api.OnLoadArgs) (api.OnLoadResult, error)) func(api.OnLoadArgs) (api.OnLoadResult, error) {
+	return func(args api.OnLoadArgs) (result api.OnLoadResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = api.OnLoadResult{Errors: []api.Message{panicMessage(r)}}, nil
+			}
+		}()
+		return callback(args)
+	}
+}
+
+// recoverOnStart makes panics of an OnStart callback fail the build instead of the process.
+func recoverOnStart(callback func() (api.OnStartResult, error)) func() (api.OnStartResult, error) {
+	return func() (result api.OnStartResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = api.OnStartResult{Errors: []api.Message{panicMessage(r)}}, nil
+			}
+		}()
+		return callback()
+	}
+}
+
+// recoverOnEnd makes panics of an OnEnd callback fail the build instead of the process.
+func recoverOnEnd(callback func(*api.BuildResult) (api.OnEndResult, error)) func(*api.BuildResult) (api.OnEndResult, error) {
+	return func(buildResult *api.BuildResult) (result api.OnEndResult, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				result, err = api.OnEndResult{Errors: []api.Message{panicMessage(r)}}, nil
+			}
+		}()
+		return callback(buildResult)
+	}
+}
+
+// applyOptions applies opts to config, converting panics into an error.
+func applyOptions(config *Config, opts []Option) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid plugin option: %v", r)
+		}
+	}()
+	for _, opt := range opts {
+		opt(config)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"testing"
+)
+
+func TestRecoverOnResolve(t *testing.T) {
+	callback := recoverOnResolve(func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+		panic("boom")
+	})
+
+	result, err := callback(api.OnResolveArgs{Path: "react"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Text != "panic: boom" {
+		t.Errorf("expected the panic to be reported as an error, got %v", result.Errors)
+	}
+	if len(result.Errors[0].Notes) != 1 {
+		t.Error("expected a note with the stack trace")
+	}
+}
+
+func TestRecoverOnEnd(t *testing.T) {
+	callback := recoverOnEnd(func(result *api.BuildResult) (api.OnEndResult, error) {
+		panic("boom")
+	})
+
+	result, err := callback(&api.BuildResult{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Text != "panic: boom" {
+		t.Errorf("expected the panic to be reported as an error, got %v", result.Errors)
+	}
+}
+
+func TestNewPluginDoesNotPanic(t *testing.T) {
+	if _, err := NewPlugin(WithImportMapPath("./does-not-exist.json")); err == nil {
+		t.Error("expected an error for a missing import map file")
+	}
+
+	panicking := func(config *Config) {
+		panic("invalid option")
+	}
+	if _, err := NewPlugin(panicking); err == nil {
+		t.Error("expected an error for a panicking option")
+	}
+}
@@ -0,0 +1,30 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// defaultMaxRedirects is how many redirects a download follows unless Config.MaxRedirects says otherwise
+const defaultMaxRedirects = 10
+
+// WithMaxRedirects sets how many redirects a download follows, 10 by default.
+func WithMaxRedirects(n int) Option {
+	return func(config *Config) {
+		config.MaxRedirects = n
+	}
+}
+
+// checkRedirect stops following redirects after the configured number of hops.
+func checkRedirect(config *Config) func(req *http.Request, via []*http.Request) error {
+	maxRedirects := config.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects downloading %s", maxRedirects, via[0].URL)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPluginResolvesAgainstRedirectedUrl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		switch r.URL.Path {
+		case "/pkg@1":
+			http.Redirect(w, r, "/v1/pkg@1.2.3/es2022/index.js", http.StatusFound)
+		case "/v1/pkg@1.2.3/es2022/index.js":
+			_, _ = w.Write([]byte("export {dep as pkg} from './dep.js';"))
+		case "/v1/pkg@1.2.3/es2022/dep.js":
+			_, _ = w.Write([]byte("export const dep = 'redirected';"))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	data := importmap.Data{Imports: importmap.Imports{
+		"pkg":  server.URL + "/pkg@1",
+		"loop": server.URL + "/loop",
+	}}
+	plugin, err := NewPlugin(WithMap(data), WithMaxRedirects(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(entryPoint string) api.BuildResult {
+		return api.Build(api.BuildOptions{
+			Bundle:      true,
+			Format:      api.FormatESModule,
+			LogLevel:    api.LogLevelSilent,
+			EntryPoints: []string{entryPoint},
+			Plugins:     []api.Plugin{plugin},
+		})
+	}
+
+	result := build("pkg")
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), `"redirected"`) {
+		t.Errorf("expected the relative import to be resolved against the final URL, got:\n%s", result.OutputFiles[0].Contents)
+	}
+
+	result = build("loop")
+	if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Text, "stopped after 3 redirects") {
+		t.Errorf("expected the redirects to be limited, got %v", result.Errors)
+	}
+}
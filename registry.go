@@ -0,0 +1,56 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap/generate"
+	"strings"
+)
+
+// jsrUrls are the URLs of the jsr packages on the CDNs serving them, "{}" standing for the
+// package, its version and subpath
+var jsrUrls = map[generate.Provider]string{
+	generate.ProviderESMSH: "https://esm.sh/jsr/{}",
+}
+
+// resolveRegistry translates npm: and jsr: specifiers like "npm:react@18" or "jsr:@std/path" to
+// the URL of the package on the CDN of Config.RegistryProvider. Other URLs are returned as is, as
+// are the schemes served by a handler of RegisterScheme.
+func (p *plugin) resolveRegistry(resolved string) (string, error) {
+	registry, spec, ok := strings.Cut(resolved, ":")
+	if !ok || (registry != "npm" && registry != "jsr") {
+		return resolved, nil
+	}
+	if _, ok = p.config.Schemes[registry]; ok {
+		return resolved, nil
+	}
+
+	provider := p.config.RegistryProvider
+	if provider == "" {
+		provider = generate.ProviderESMSH
+	}
+	spec = strings.TrimPrefix(spec, "/")
+	if spec == "" {
+		return "", fmt.Errorf("%s: no package name", resolved)
+	}
+	if registry == "jsr" {
+		template, ok := jsrUrls[provider]
+		if !ok {
+			return "", fmt.Errorf("%s: jsr packages aren't available on %s, use WithRegistryProvider(generate.ProviderESMSH)", resolved, provider)
+		}
+		return strings.Replace(template, "{}", spec, 1), nil
+	}
+	ref, ok := importmap.ParsePackageRef(spec)
+	if !ok {
+		return "", fmt.Errorf("%s: invalid package name", resolved)
+	}
+	return provider.PackageURL(ref)
+}
+
+// WithRegistryProvider sets the CDN npm: and jsr: specifiers and targets are downloaded from,
+// generate.ProviderESMSH by default. Only esm.sh serves jsr packages.
+func WithRegistryProvider(provider generate.Provider) Option {
+	return func(config *Config) {
+		config.RegistryProvider = provider
+	}
+}
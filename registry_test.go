@@ -0,0 +1,61 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap/generate"
+	"strings"
+	"testing"
+)
+
+func TestResolveRegistry(t *testing.T) {
+	cases := []struct {
+		provider generate.Provider
+		resolved string
+		expected string
+	}{
+		{"", "npm:react@18", "https://esm.sh/react@18"},
+		{"", "npm:/preact@10/hooks", "https://esm.sh/preact@10/hooks"},
+		{"", "jsr:@std/path@1", "https://esm.sh/jsr/@std/path@1"},
+		{generate.ProviderJSDelivr, "npm:preact@10/hooks", "https://cdn.jsdelivr.net/npm/preact@10/hooks/+esm"},
+		{generate.ProviderUnpkg, "npm:react@18", "https://unpkg.com/react@18?module"},
+		{generate.ProviderJSPM, "npm:react@18.2.0", "https://ga.jspm.io/npm:react@18.2.0"},
+		{"", "https://esm.sh/react@18", "https://esm.sh/react@18"},
+	}
+	for _, c := range cases {
+		p := newTestPlugin(t, WithRegistryProvider(c.provider))
+		resolved, err := p.resolveRegistry(c.resolved)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved != c.expected {
+			t.Errorf("expected %s for %s on %q, got %s", c.expected, c.resolved, c.provider, resolved)
+		}
+	}
+
+	p := newTestPlugin(t, WithRegistryProvider(generate.ProviderJSDelivr))
+	if _, err := p.resolveRegistry("jsr:@std/path"); err == nil {
+		t.Error("expected an error for jsr packages on a CDN without them")
+	}
+}
+
+func TestPluginWithRegistrySpecifiers(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"react": "npm:react@18"}}),
+		WithFetcher(fixtureFetcher{
+			"https://esm.sh/react@18":        "export const react = 'npm react';",
+			"https://esm.sh/jsr/@std/path@1": "export const join = 'jsr path';",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {react} from 'react'; import {join} from 'jsr:@std/path@1'; console.log(react, join);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, "npm react") || !strings.Contains(output, "jsr path") {
+		t.Errorf("expected the registry specifiers to be downloaded from esm.sh, got:\n%s", output)
+	}
+}
@@ -0,0 +1,84 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// loadImportMapPath loads the import map at Config.ImportMapPath, from its PackageJSONKey for
+// package.json files, from the imports and scopes of Deno config files and from the paths of
+// tsconfig files.
+func loadImportMapPath(config *Config) (importmap.IImportMap, error) {
+	opts, err := mapOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Base(config.ImportMapPath) {
+	case "package.json":
+		return importmap.LoadFromPackageJSON(config.ImportMapPath, config.PackageJSONKey, opts...)
+	case "deno.json", "deno.jsonc":
+		return importmap.LoadFromDenoConfig(config.ImportMapPath, opts...)
+	}
+	if base := filepath.Base(config.ImportMapPath); strings.HasPrefix(base, "tsconfig") && filepath.Ext(base) == ".json" {
+		return importmap.FromTSConfig(config.ImportMapPath, opts...)
+	}
+	return importmap.LoadFromFile(config.ImportMapPath, opts...)
+}
+
+// modTime returns the modification time of path, zero when it can't be read.
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// reloadImportMap loads the import map file again when it changed since it was loaded, so
+// incremental and watch builds pick up edits. A map which fails to load is reported and the
+// previous one kept.
+func (p *plugin) reloadImportMap() api.OnStartResult {
+	loaded := modTime(p.config.ImportMapPath)
+	p.mapsMu.RLock()
+	unchanged := loaded.Equal(p.mapModTime)
+	p.mapsMu.RUnlock()
+	if unchanged {
+		return api.OnStartResult{}
+	}
+
+	importMap, err := loadImportMapPath(p.config)
+	if err == nil {
+		importMap, err = addPackageImports(p.config, importMap)
+	}
+	if err == nil {
+		if err = p.replaceImportMap(importMap); err == nil {
+			p.mapsMu.Lock()
+			p.mapModTime = loaded
+			p.mapsMu.Unlock()
+			return api.OnStartResult{}
+		}
+	}
+	return api.OnStartResult{
+		Errors: []api.Message{{Text: "failed to reload the import map: " + err.Error()}},
+	}
+}
+
+// watchImportMap makes esbuild's watch mode rebuild when the import map file changes, by adding
+// it to the watched files of every import the plugin resolves.
+func (p *plugin) watchImportMap(callback func(api.OnResolveArgs) (api.OnResolveResult, error)) func(api.OnResolveArgs) (api.OnResolveResult, error) {
+	mapPath, err := filepath.Abs(p.config.ImportMapPath)
+	if err != nil {
+		mapPath = p.config.ImportMapPath
+	}
+	return func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+		result, err := callback(args)
+		if err == nil && result.Path != "" {
+			result.WatchFiles = append(result.WatchFiles, mapPath)
+		}
+		return result, err
+	}
+}
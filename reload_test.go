@@ -0,0 +1,72 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPluginReloadsImportMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "importmap.json")
+	writeMap := func(target string, modified time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(`{"imports": {"pkg": "`+target+`"}}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMap("https://mirror.invalid/pkg@1.js", time.Now().Add(-time.Hour))
+
+	plugin, err := NewPlugin(
+		WithImportMapPath(path),
+		WithFetcher(fixtureFetcher{
+			"https://mirror.invalid/pkg@1.js": "export const pkg = 'v1';",
+			"https://mirror.invalid/pkg@2.js": "export const pkg = 'v2';",
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, ctxErr := api.Context(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, "import {pkg} from 'pkg'; console.log(pkg);"),
+			plugin,
+		},
+	})
+	if ctxErr != nil {
+		t.Fatal(ctxErr)
+	}
+	defer ctx.Dispose()
+
+	rebuild := func(expected string) {
+		t.Helper()
+		result := ctx.Rebuild()
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		if output := string(result.OutputFiles[0].Contents); !strings.Contains(output, expected) {
+			t.Errorf("expected %s in the output:\n%s", expected, output)
+		}
+	}
+
+	rebuild(`"v1"`)
+	writeMap("https://mirror.invalid/pkg@2.js", time.Now())
+	rebuild(`"v2"`)
+
+	if err = os.WriteFile(path, []byte(`{"imports": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if result := ctx.Rebuild(); len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Text, "failed to reload the import map") {
+		t.Errorf("expected the invalid map to be reported, got %v", result.Errors)
+	}
+}
@@ -0,0 +1,125 @@
+package esbuild_plugin_importmap
+
+import (
+	"errors"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResidualMapFile is the name of the import map written to the output directory by WithResidualMap
+const ResidualMapFile = "importmap.json"
+
+// outputUrl rebases an absolute URL of the residual map to dir. Files inside the output directory
+// are referenced through the public path when set, other files by a path relative to dir, and
+// remote URLs are kept as is.
+func (p *plugin) outputUrl(rawUrl string, dir string) string {
+	if public, ok := p.emittedOutput(rawUrl); ok {
+		return public
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Scheme != "file" {
+		return rawUrl
+	}
+	path, err := importmap.FilePath(u)
+	if err != nil {
+		return rawUrl
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return rawUrl
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasSuffix(u.Path, "/") {
+		rel += "/"
+	}
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + strings.TrimPrefix(rel, "./")
+	}
+	return rel
+}
+
+// residualMap returns the import map the bundle still needs at runtime, i.e. the entries of the
+// specifiers left external, with their targets rebased to dir, and the integrity of the outputs
+// of the build with WithOutputIntegrity.
+func (p *plugin) residualMap(result *api.BuildResult, dir string) (importmap.Data, error) {
+	residual, err := importmap.New(
+		importmap.WithMap(p.externalsMap()),
+		importmap.WithMapUrl(p.currentMap().GetMapUrl()),
+	)
+	if err != nil {
+		return importmap.Data{}, err
+	}
+
+	canonical := residual.CanonicalForm()
+	data := importmap.Data{
+		Imports:   p.outputSpecifierMap(canonical.Imports, dir),
+		Scopes:    make(importmap.Scopes, len(canonical.Scopes)),
+		Integrity: make(importmap.Integrity, len(canonical.Integrity)),
+	}
+	for scopeKey, scope := range canonical.Scopes {
+		data.Scopes[p.outputUrl(scopeKey, dir)] = p.outputSpecifierMap(scope, dir)
+	}
+	for target, integrity := range canonical.Integrity {
+		data.Integrity[p.outputUrl(target, dir)] = integrity
+	}
+
+	outputs, err := p.outputIntegrity(result, dir)
+	if err != nil {
+		return importmap.Data{}, err
+	}
+	for target, integrity := range outputs {
+		data.Integrity[target] = integrity
+	}
+	return data, nil
+}
+
+func (p *plugin) outputSpecifierMap(specifierMap map[string]string, dir string) map[string]string {
+	result := make(map[string]string, len(specifierMap))
+	for key, target := range specifierMap {
+		result[p.outputUrl(key, dir)] = p.outputUrl(target, dir)
+	}
+	return result
+}
+
+// emitResidualMap writes the residual map next to the bundle, or adds it to the output files
+// of the result when the build doesn't write to disk. Failed builds emit nothing.
+func (p *plugin) emitResidualMap(result *api.BuildResult) error {
+	if len(result.Errors) > 0 {
+		return nil
+	}
+	if p.outdir == "" {
+		return errors.New("the residual import map requires the Outdir or Outfile build option")
+	}
+
+	data, err := p.residualMap(result, p.outdir)
+	if err != nil {
+		return err
+	}
+	contents, err := importmap.Format(data, p.config.MapFormat...)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(p.outdir, ResidualMapFile)
+	if p.build.InitialOptions == nil || !p.build.InitialOptions.Write {
+		result.OutputFiles = append(result.OutputFiles, api.OutputFile{Path: path, Contents: contents})
+		return nil
+	}
+	if err = os.MkdirAll(p.outdir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, contents, 0o644)
+}
+
+// WithResidualMap emits an import map named ResidualMapFile alongside the bundle after each build,
+// holding only the mapped specifiers left external, which the browser still resolves at runtime.
+// Its local targets are rebased to the output directory, or to the public path for the files
+// inside it. Builds which don't write to disk get the map in the output files of their result.
+func WithResidualMap() Option {
+	return func(config *Config) {
+		config.ResidualMap = true
+	}
+}
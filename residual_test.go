@@ -0,0 +1,136 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newResidualMapPlugin(t *testing.T, dir string, opts ...Option) api.Plugin {
+	mapPath := filepath.Join(dir, "importmap.json")
+	err := os.WriteFile(mapPath, []byte(`{
+		"imports": {
+			"react": "https://esm.invalid/react@18.2.0",
+			"local": "file://`+filepath.ToSlash(dir)+`/lib/local.js",
+			"bundled": "https://esm.invalid/bundled.js"
+		},
+		"integrity": {"https://esm.invalid/react@18.2.0": "`+reactIntegrity+`"}
+	}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := NewPlugin(append([]Option{
+		WithImportMapPath(mapPath),
+		WithFetcher(fixtureFetcher{"https://esm.invalid/bundled.js": "export const bundled = 1;"}),
+		WithExternal([]string{"react", "local"}),
+		WithResidualMap(),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plugin
+}
+
+func buildResidualMap(t *testing.T, plugin api.Plugin, outdir string, write bool) api.BuildResult {
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Outdir:      outdir,
+		Write:       write,
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, "import React from 'react'; import local from 'local'; import {bundled} from 'bundled'; console.log(React, local, bundled);"),
+			plugin,
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	return result
+}
+
+func TestPluginResidualMap(t *testing.T) {
+	dir := t.TempDir()
+	outdir := filepath.Join(dir, "dist")
+	result := buildResidualMap(t, newResidualMapPlugin(t, dir), outdir, false)
+
+	var contents []byte
+	for _, file := range result.OutputFiles {
+		if file.Path == filepath.Join(outdir, ResidualMapFile) {
+			contents = file.Contents
+		}
+	}
+	if contents == nil {
+		t.Fatal("expected the residual map in the output files")
+	}
+	data := importmap.Data{}
+	if err := json.Unmarshal(contents, &data); err != nil {
+		t.Fatal(err)
+	}
+	expected := importmap.Imports{
+		"react": "https://esm.invalid/react@18.2.0",
+		"local": "../lib/local.js",
+	}
+	if len(data.Imports) != len(expected) {
+		t.Fatalf("expected only the external specifiers in the map, got %s", contents)
+	}
+	for key, target := range expected {
+		if data.Imports[key] != target {
+			t.Errorf("expected %s to map to %s, got %s", key, target, data.Imports[key])
+		}
+	}
+	if data.Integrity["https://esm.invalid/react@18.2.0"] != reactIntegrity {
+		t.Errorf("expected the integrity of the external specifier, got %s", contents)
+	}
+}
+
+func TestPluginResidualMapWritten(t *testing.T) {
+	dir := t.TempDir()
+	outdir := filepath.Join(dir, "dist")
+	plugin := newResidualMapPlugin(t, dir, WithPublicPath("https://cdn.invalid/app/"))
+	buildResidualMap(t, plugin, outdir, true)
+
+	contents, err := os.ReadFile(filepath.Join(outdir, ResidualMapFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := importmap.Data{}
+	if err = json.Unmarshal(contents, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Imports["react"] != "https://esm.invalid/react@18.2.0" {
+		t.Errorf("expected the remote target to stay absolute, got %s", contents)
+	}
+	if _, ok := data.Imports["bundled"]; ok {
+		t.Errorf("expected the bundled specifier to be left out, got %s", contents)
+	}
+}
+
+func TestPluginResidualMapWithoutOutdir(t *testing.T) {
+	plugin := newResidualMapPlugin(t, t.TempDir())
+	result := buildWithPlugin(t, "import React from 'react'; console.log(React);", plugin)
+	if len(result.Errors) == 0 {
+		t.Error("expected an error without an output directory")
+	}
+}
+
+func TestPluginResidualMapFormat(t *testing.T) {
+	dir := t.TempDir()
+	outdir := filepath.Join(dir, "dist")
+	plugin := newResidualMapPlugin(t, dir, WithMapFormat(importmap.WithIndent("\t"), importmap.WithTrailingNewline()))
+	buildResidualMap(t, plugin, outdir, true)
+
+	contents, err := os.ReadFile(filepath.Join(outdir, ResidualMapFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(contents), "{\n\t\"imports\": {\n\t\t") || !strings.HasSuffix(string(contents), "}\n") {
+		t.Errorf("expected the map indented with tabs and ending with a newline, got:\n%s", contents)
+	}
+}
@@ -0,0 +1,98 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultFetchTimeout bounds each download unless Config.FetchTimeout says otherwise
+	defaultFetchTimeout = time.Minute
+
+	// defaultRetryBaseDelay is the delay before the first retry, doubled for each following one
+	defaultRetryBaseDelay = 250 * time.Millisecond
+
+	// maxRetryDelay caps the exponential backoff
+	maxRetryDelay = 10 * time.Second
+)
+
+// WithFetchTimeout bounds the time each download may take, including reading the body.
+// Zero means one minute and a negative timeout disables it.
+func WithFetchTimeout(timeout time.Duration) Option {
+	return func(config *Config) {
+		config.FetchTimeout = timeout
+	}
+}
+
+// WithRetries retries failed downloads up to retries times, waiting baseDelay before the first
+// retry and twice as long before each following one, with jitter. Only network errors, timeouts
+// and retryable HTTP statuses are retried. A zero baseDelay means 250ms.
+func WithRetries(retries int, baseDelay time.Duration) Option {
+	return func(config *Config) {
+		config.FetchRetries = retries
+		config.RetryBaseDelay = baseDelay
+	}
+}
+
+// WithContext aborts in-flight downloads and retry back-offs once ctx is done. esbuild doesn't
+// tell plugins about cancelled builds, so cancel ctx along with the build context's Cancel to
+// stop a slow fetch. Disposing the build context aborts them too.
+func WithContext(ctx context.Context) Option {
+	return func(config *Config) {
+		config.Context = ctx
+	}
+}
+
+// configContext returns Config.Context, or the background context when it isn't set.
+func configContext(config *Config) context.Context {
+	if config.Context != nil {
+		return config.Context
+	}
+	return context.Background()
+}
+
+// fetchTimeout returns the context of a single download attempt.
+func (p *plugin) fetchTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := p.config.FetchTimeout
+	if timeout == 0 {
+		timeout = defaultFetchTimeout
+	}
+	if timeout < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// retryDelay returns how long to wait before sending the request failed with err once more,
+// attempt being the number of attempts so far, or false when it must not be retried.
+//
+// A 429 or 503 response asking to retry soon is retried once even without configured retries.
+func (p *plugin) retryDelay(err error, attempt int) (time.Duration, bool) {
+	var httpErr *HTTPError
+	isHTTPErr := errors.As(err, &httpErr)
+	if isHTTPErr && httpErr.RetryAfter > 0 && httpErr.RetryAfter <= maxRetryAfter &&
+		(httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode == http.StatusServiceUnavailable) &&
+		(attempt == 1 || attempt <= p.config.FetchRetries) {
+		return httpErr.RetryAfter, true
+	}
+
+	if attempt > p.config.FetchRetries || (isHTTPErr && !httpErr.Retryable()) || errors.Is(err, context.Canceled) {
+		return 0, false
+	}
+
+	delay := p.config.RetryBaseDelay
+	if delay <= 0 {
+		delay = defaultRetryBaseDelay
+	}
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	// jitter over the upper half of the delay spreads the retries of parallel downloads
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)), true
+}
@@ -0,0 +1,143 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"errors"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchRetries(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.js" {
+			requests.Add(1)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	if _, err := newTestPlugin(t).fetch(context.Background(), server.URL+"/mod.js"); err == nil {
+		t.Fatal("expected the download to fail without retries")
+	}
+
+	requests.Store(0)
+	p := newTestPlugin(t, WithRetries(2, time.Millisecond))
+	result, err := p.fetch(context.Background(), server.URL+"/mod.js")
+	if err != nil || result.contents != "export default 1;" {
+		t.Fatalf("unexpected result %v, %v", result, err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+
+	requests.Store(0)
+	if _, err = p.fetch(context.Background(), server.URL+"/missing.js"); err == nil || requests.Load() != 1 {
+		t.Errorf("expected a 404 not to be retried, got %d requests", requests.Load())
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	p := newTestPlugin(t, WithFetchTimeout(50*time.Millisecond), WithRetries(1, time.Millisecond))
+	start := time.Now()
+	if _, err := p.fetch(context.Background(), server.URL+"/hung.js"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the download to time out quickly, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.fetch(ctx, server.URL+"/hung.js"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to be honored, got %v", err)
+	}
+}
+
+func TestPluginCancelledBuild(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	fetchCtx, cancelFetches := context.WithCancel(context.Background())
+	defer cancelFetches()
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"slow": server.URL + "/slow.js"}}),
+		WithContext(fetchCtx),
+		WithRetries(3, time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, ctxErr := api.Context(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{
+			getFileTreePlugin(t, "import slow from 'slow'; console.log(slow);"),
+			plugin,
+		},
+	})
+	if ctxErr != nil {
+		t.Fatal(ctxErr)
+	}
+	defer ctx.Dispose()
+
+	done := make(chan api.BuildResult)
+	go func() {
+		done <- ctx.Rebuild()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	cancelFetches()
+	ctx.Cancel()
+
+	select {
+	case result := <-done:
+		if len(result.Errors) == 0 {
+			t.Error("expected the cancelled build to fail")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("expected the slow download to be aborted, took %s", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the cancelled build to stop the slow download")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := newTestPlugin(t, WithRetries(5, 100*time.Millisecond))
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 5: 1600 * time.Millisecond} {
+		delay, ok := p.retryDelay(errors.New("connection reset"), attempt)
+		if !ok || delay < max/2 || delay > max {
+			t.Errorf("attempt %d: unexpected delay %s", attempt, delay)
+		}
+	}
+	if _, ok := p.retryDelay(errors.New("connection reset"), 6); ok {
+		t.Error("expected no retry after the configured retries")
+	}
+}
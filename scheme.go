@@ -0,0 +1,86 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"net/url"
+	"strings"
+)
+
+// schemeNamespace is the esbuild namespace of the modules served by scheme handlers
+const schemeNamespace = "importmap-scheme"
+
+// SchemeHandler serves the modules of a custom URL scheme, like "app:" or "widgets:"
+type SchemeHandler struct {
+	// Resolve returns the path of the module at rawUrl, which is passed to Load.
+	// The URL itself is used when Resolve is nil.
+	Resolve func(rawUrl string, importer string) (string, error)
+	// Load returns the contents of the module at path and the loader to use for them
+	Load func(path string) (string, api.Loader, error)
+}
+
+// schemeData is the plugin data of the modules served by scheme handlers
+type schemeData struct {
+	scheme string
+}
+
+// RegisterScheme serves the URLs of scheme with handler. Specifiers are resolved with the import
+// map first, so mappings can point at the scheme and URLs of the scheme can be remapped.
+// The scheme is given without the trailing colon.
+func RegisterScheme(scheme string, handler SchemeHandler) Option {
+	return func(config *Config) {
+		if handler.Load == nil {
+			panic(fmt.Sprintf("the handler of the %s scheme has no Load function", scheme))
+		}
+		if config.Schemes == nil {
+			config.Schemes = make(map[string]SchemeHandler)
+		}
+		config.Schemes[strings.ToLower(scheme)] = handler
+	}
+}
+
+// resolveScheme resolves a URL of a registered scheme, if resolved is one.
+func (p *plugin) resolveScheme(resolved string, importer string) (api.OnResolveResult, bool, error) {
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return api.OnResolveResult{}, false, nil
+	}
+	handler, ok := p.config.Schemes[strings.ToLower(u.Scheme)]
+	if !ok {
+		return api.OnResolveResult{}, false, nil
+	}
+
+	// URLs without authority are passed as written, e.g. "app:/a.js" rather than "app:///a.js"
+	if u.Host == "" && u.User == nil {
+		u.OmitHost = true
+		resolved = u.String()
+	}
+
+	path := resolved
+	if handler.Resolve != nil {
+		if path, err = handler.Resolve(resolved, importer); err != nil {
+			return api.OnResolveResult{}, true, err
+		}
+	}
+	return api.OnResolveResult{
+		Path:       path,
+		Namespace:  schemeNamespace,
+		PluginData: schemeData{scheme: strings.ToLower(u.Scheme)},
+	}, true, nil
+}
+
+func (p *plugin) onLoadScheme(args api.OnLoadArgs) (api.OnLoadResult, error) {
+	data, ok := args.PluginData.(schemeData)
+	if !ok {
+		return api.OnLoadResult{}, fmt.Errorf("no scheme handler for %s", args.Path)
+	}
+
+	contents, loader, err := p.config.Schemes[data.scheme].Load(args.Path)
+	if err != nil {
+		return api.OnLoadResult{}, err
+	}
+	return api.OnLoadResult{
+		Contents: &contents,
+		Loader:   loader,
+	}, nil
+}
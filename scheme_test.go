@@ -0,0 +1,58 @@
+package esbuild_plugin_importmap
+
+import (
+	"errors"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginRegisterScheme(t *testing.T) {
+	widgets := map[string]string{
+		"widgets:/button.js": "import {theme} from './theme.js'; export const button = 'button-' + theme;",
+		"widgets:/theme.js":  "export const theme = 'dark';",
+	}
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"@widgets/": "widgets:/"}}),
+		RegisterScheme("widgets", SchemeHandler{
+			Load: func(path string) (string, api.Loader, error) {
+				contents, ok := widgets[path]
+				if !ok {
+					return "", api.LoaderNone, errors.New("no widget at " + path)
+				}
+				return contents, api.LoaderJS, nil
+			},
+		}),
+		RegisterScheme("app", SchemeHandler{
+			Resolve: func(rawUrl string, importer string) (string, error) {
+				return strings.TrimPrefix(rawUrl, "app:"), nil
+			},
+			Load: func(path string) (string, api.Loader, error) {
+				return "export default " + `"` + path + `-config"`, api.LoaderJS, nil
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the plugin comes first as the file tree plugin claims every relative import
+	result := api.Build(api.BuildOptions{
+		Bundle:      true,
+		Format:      api.FormatESModule,
+		LogLevel:    api.LogLevelSilent,
+		EntryPoints: []string{"./index.js"},
+		Plugins: []api.Plugin{
+			plugin,
+			getFileTreePlugin(t, "import {button} from '@widgets/button.js'; import config from 'app:settings'; console.log(button, config);"),
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `"dark"`) || !strings.Contains(output, `"settings-config"`) {
+		t.Errorf("expected the scheme modules to be bundled, got:\n%s", output)
+	}
+}
@@ -0,0 +1,146 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"sort"
+	"strings"
+)
+
+const (
+	// shimNamespace is the esbuild namespace of the shim modules
+	shimNamespace = "importmap-shim"
+
+	// shimGlobalNamespace is the esbuild namespace of the modules injected for the node globals
+	shimGlobalNamespace = "importmap-shim-global"
+)
+
+const processShim = `const env = {};
+export { env };
+export const browser = true;
+export const argv = [];
+export const version = "";
+export const versions = {};
+export const platform = "browser";
+export function cwd() { return "/"; }
+export function nextTick(fn, ...args) { queueMicrotask(() => fn(...args)); }
+export default { env, browser, argv, version, versions, platform, cwd, nextTick };
+`
+
+const bufferShim = `export class Buffer extends Uint8Array {
+  static from(value) {
+    if (typeof value === "string") return new Buffer(new TextEncoder().encode(value));
+    return new Buffer(value);
+  }
+  static alloc(size) { return new Buffer(size); }
+  static isBuffer(value) { return value instanceof Buffer; }
+  toString() { return new TextDecoder().decode(this); }
+}
+export default { Buffer };
+`
+
+// BuiltinShims are lightweight browser replacements for node modules commonly imported by CDN builds
+// of CommonJS packages, available by name to WithShims.
+var BuiltinShims = map[string]string{
+	"process":      processShim,
+	"node:process": processShim,
+	"buffer":       bufferShim,
+	"node:buffer":  bufferShim,
+}
+
+// globalShims are the modules injected for the node globals referenced by CDN builds of CommonJS
+// packages, by global and the specifier they import it from.
+var globalShims = map[string]struct{ specifier, source string }{
+	"process": {"process", `export { default as process } from "process";`},
+	"Buffer":  {"buffer", `export { Buffer } from "buffer";`},
+}
+
+// WithShims enables built-in shims for the given specifiers, or all of BuiltinShims when none are given.
+// Shims are only used for specifiers the import map has no mapping for. NewPlugin fails for the
+// specifiers without a built-in shim.
+func WithShims(specifiers ...string) Option {
+	return func(config *Config) {
+		if len(specifiers) == 0 {
+			for specifier := range BuiltinShims {
+				specifiers = append(specifiers, specifier)
+			}
+			sort.Strings(specifiers)
+		}
+		for _, specifier := range specifiers {
+			source, ok := BuiltinShims[specifier]
+			if !ok {
+				config.UnknownShims = append(config.UnknownShims, specifier)
+				continue
+			}
+			WithShim(specifier, source)(config)
+		}
+	}
+}
+
+// WithShim serves source as the module for specifier when the import map has no mapping for it
+func WithShim(specifier string, source string) Option {
+	return func(config *Config) {
+		if config.Shims == nil {
+			config.Shims = make(map[string]string)
+		}
+		config.Shims[specifier] = source
+	}
+}
+
+// WithGlobalShims injects the process and Buffer globals CDN builds of CommonJS packages reference,
+// importing them from the "process" and "buffer" modules. Only the globals whose module the
+// import map maps or a shim provides when the build is set up are injected.
+func WithGlobalShims() Option {
+	return func(config *Config) {
+		config.GlobalShims = true
+	}
+}
+
+// injectGlobalShims adds the modules of the globals provided by the import map or the shims to
+// the files esbuild injects.
+func (p *plugin) injectGlobalShims(options *api.BuildOptions) {
+	globals := make([]string, 0, len(globalShims))
+	for global, shim := range globalShims {
+		_, shimmed := p.config.Shims[shim.specifier]
+		if _, err := p.currentMap().Resolve(shim.specifier); err == nil || shimmed {
+			globals = append(globals, global)
+		}
+	}
+	sort.Strings(globals)
+	for _, global := range globals {
+		options.Inject = append(options.Inject, shimGlobalNamespace+":"+global)
+	}
+}
+
+func (p *plugin) onResolveGlobalShim(args api.OnResolveArgs) (api.OnResolveResult, error) {
+	return api.OnResolveResult{
+		Path:      strings.TrimPrefix(args.Path, shimGlobalNamespace+":"),
+		Namespace: shimGlobalNamespace,
+	}, nil
+}
+
+func (p *plugin) onLoadGlobalShim(args api.OnLoadArgs) (api.OnLoadResult, error) {
+	source := globalShims[args.Path].source
+	return api.OnLoadResult{
+		Contents: &source,
+		Loader:   api.LoaderJS,
+	}, nil
+}
+
+// resolveShim returns the shim module for specifier, if one is configured.
+func (p *plugin) resolveShim(specifier string) (api.OnResolveResult, bool) {
+	if _, ok := p.config.Shims[specifier]; !ok {
+		return api.OnResolveResult{}, false
+	}
+	return api.OnResolveResult{
+		Path:      specifier,
+		Namespace: shimNamespace,
+	}, true
+}
+
+func (p *plugin) onLoadShim(args api.OnLoadArgs) (api.OnLoadResult, error) {
+	source := p.config.Shims[args.Path]
+	return api.OnLoadResult{
+		Contents: &source,
+		Loader:   api.LoaderJS,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginShims(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"@/": "./"}}),
+		WithShims("process"),
+		WithShim("buffer", "export const Buffer = 'custom-buffer';"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import process from 'process'; import {Buffer} from 'buffer'; console.log(process.platform, Buffer);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `platform = "browser"`) || !strings.Contains(output, "custom-buffer") {
+		t.Errorf("expected the shims to be bundled, got:\n%s", output)
+	}
+
+	if _, err = NewPlugin(WithMap(importmap.Data{}), WithShims("fs")); err == nil || err.Error() != "no built-in shim for fs" {
+		t.Errorf("expected an error for an unknown built-in shim, got %v", err)
+	}
+}
+
+func TestPluginGlobalShims(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"process": "https://cdn.invalid/process.js"}}),
+		WithFetcher(fixtureFetcher{"https://cdn.invalid/process.js": "export default { env: { API: 'mapped' } };"}),
+		WithShim("buffer", "export const Buffer = 'custom-buffer';"),
+		WithGlobalShims(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const content = "console.log(process.env.API, Buffer);"
+	result := buildWithPlugin(t, content, plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	output := string(result.OutputFiles[0].Contents)
+	if !strings.Contains(output, `"mapped"`) || !strings.Contains(output, "custom-buffer") {
+		t.Errorf("expected the globals to be imported from the map and the shims, got:\n%s", output)
+	}
+
+	plugin, err = NewPlugin(WithMap(importmap.Data{}), WithGlobalShims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = buildWithPlugin(t, content, plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if output = string(result.OutputFiles[0].Contents); !strings.Contains(output, "process.env.API, Buffer") {
+		t.Errorf("expected the globals without a mapping or shim to be left alone, got:\n%s", output)
+	}
+}
@@ -0,0 +1,72 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"sync"
+)
+
+// fetchCall is a download in flight or done during the current build
+type fetchCall struct {
+	done   chan struct{}
+	result *fetchResult
+	err    error
+}
+
+// fetchGroup coalesces the downloads of a URL, so each one is downloaded once per build however
+// many modules import it. Failed downloads are forgotten once done, so later imports try again.
+type fetchGroup struct {
+	mu    sync.Mutex
+	calls map[string]*fetchCall
+}
+
+// do returns the result of the call for key, running fn unless another caller did or is doing so.
+// Callers get copies of the result, which they may modify, marked fromCache unless they ran fn.
+// A panic of fn is returned as an error to the caller and the ones waiting for it.
+func (g *fetchGroup) do(key string, fn func() (*fetchResult, error)) (*fetchResult, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*fetchCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		call = &fetchCall{done: make(chan struct{})}
+		g.calls[key] = call
+	}
+	g.mu.Unlock()
+
+	if ok {
+		<-call.done
+	} else {
+		g.run(key, call, fn)
+	}
+
+	if call.err != nil {
+		return nil, call.err
+	}
+	result := *call.result
+	result.fromCache = result.fromCache || ok
+	return &result, nil
+}
+
+// run runs fn for call, releasing the callers waiting for it however it ends.
+func (g *fetchGroup) run(key string, call *fetchCall, fn func() (*fetchResult, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.result, call.err = nil, fmt.Errorf("downloading %s panicked: %v", key, r)
+		}
+		if call.err != nil {
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+		}
+		close(call.done)
+	}()
+	call.result, call.err = fn()
+}
+
+// reset forgets the downloads of the previous build.
+func (g *fetchGroup) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls = nil
+}
@@ -0,0 +1,96 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCoalescesDownloads(t *testing.T) {
+	var downloads atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		<-release
+		_, _ = w.Write([]byte("export default 1;"))
+	}))
+	defer server.Close()
+
+	p := newTestPlugin(t, WithHooks(Hooks{
+		OnAfterFetch: func(rawUrl string, contents string) (string, error) {
+			return contents + "\n// seen", nil
+		},
+	}))
+
+	var wg sync.WaitGroup
+	results := make([]*fetchResult, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := p.fetch(context.Background(), server.URL+"/mod.js")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = result
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	if _, err := p.fetch(context.Background(), server.URL+"/mod.js"); err != nil {
+		t.Fatal(err)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("expected a single download, got %d", n)
+	}
+	for _, result := range results {
+		if result != nil && result.contents != "export default 1;\n// seen" {
+			t.Errorf("expected the hook to run once per fetch, got %q", result.contents)
+		}
+	}
+
+	p.downloads.reset()
+	if _, err := p.fetch(context.Background(), server.URL+"/mod.js"); err != nil || downloads.Load() != 2 {
+		t.Errorf("expected a new build to download again, got %d downloads, %v", downloads.Load(), err)
+	}
+}
+
+func TestFetchGroupPanic(t *testing.T) {
+	var g fetchGroup
+	started, release := make(chan struct{}), make(chan struct{})
+	errs := make(chan error, 2)
+	go func() {
+		_, err := g.do("https://esm.sh/a.js", func() (*fetchResult, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err := g.do("https://esm.sh/a.js", func() (*fetchResult, error) {
+			return &fetchResult{}, nil
+		})
+		errs <- err
+	}()
+	// the waiter has to join the call before it's released
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	for n := 0; n < 2; n++ {
+		if err := <-errs; err == nil {
+			t.Error("expected the panic to be returned as an error to the caller and the waiter")
+		}
+	}
+
+	// the failed call is forgotten
+	if _, err := g.do("https://esm.sh/a.js", func() (*fetchResult, error) { return &fetchResult{}, nil }); err != nil {
+		t.Errorf("expected the download to be tried again, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stats are the metrics of a build, for tracking the performance of builds in CI
+type Stats struct {
+	// Resolved is the number of imports resolved by the plugin, Unresolved the number of
+	// specifiers the import map had no mapping for
+	Resolved   int
+	Unresolved int
+	// Fetches is the number of remote modules requested, CacheHits the ones served without a
+	// download
+	Fetches   int
+	CacheHits int
+	// BytesDownloaded is the size of the downloaded modules, after decompression
+	BytesDownloaded int64
+	// FetchLatencyP50, FetchLatencyP90 and FetchLatencyP99 are percentiles of the durations of the
+	// downloads
+	FetchLatencyP50 time.Duration
+	FetchLatencyP90 time.Duration
+	FetchLatencyP99 time.Duration
+	// ResolutionCacheSize is the number of memoized resolutions at the end of the build,
+	// ResolutionCacheHits the imports resolved from them and ResolutionCacheInvalidations the
+	// times they were dropped because the import map changed
+	ResolutionCacheSize          int
+	ResolutionCacheHits          int
+	ResolutionCacheInvalidations int
+}
+
+// CacheHitRatio returns the share of the fetches served without a download.
+func (s Stats) CacheHitRatio() float64 {
+	if s.Fetches == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.Fetches)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d resolved, %d unresolved, %d fetches (%.0f%% cached), %d bytes downloaded, latency p50 %s p90 %s p99 %s, %d memoized resolutions (%d hits, %d invalidations)",
+		s.Resolved, s.Unresolved, s.Fetches, 100*s.CacheHitRatio(), s.BytesDownloaded,
+		s.FetchLatencyP50, s.FetchLatencyP90, s.FetchLatencyP99,
+		s.ResolutionCacheSize, s.ResolutionCacheHits, s.ResolutionCacheInvalidations)
+}
+
+// WithStatsReport calls onStats with the metrics of each build when it ends.
+func WithStatsReport(onStats func(Stats)) Option {
+	return func(config *Config) {
+		config.OnStats = onStats
+	}
+}
+
+// statsCollector collects the metrics of the current build
+type statsCollector struct {
+	mu        sync.Mutex
+	stats     Stats
+	latencies []time.Duration
+}
+
+func (c *statsCollector) resolved() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Resolved++
+}
+
+func (c *statsCollector) unresolved() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Unresolved++
+}
+
+// fetched records a fetch, latency being the duration of the download if there was one.
+func (c *statsCollector) fetched(result *fetchResult, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Fetches++
+	if result.fromCache {
+		c.stats.CacheHits++
+		return
+	}
+	c.stats.BytesDownloaded += int64(len(result.body))
+	c.latencies = append(c.latencies, latency)
+}
+
+// take returns the metrics collected since the last call and starts over.
+func (c *statsCollector) take() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	sort.Slice(c.latencies, func(i, j int) bool {
+		return c.latencies[i] < c.latencies[j]
+	})
+	stats.FetchLatencyP50 = percentile(c.latencies, 50)
+	stats.FetchLatencyP90 = percentile(c.latencies, 90)
+	stats.FetchLatencyP99 = percentile(c.latencies, 99)
+
+	c.stats = Stats{}
+	c.latencies = nil
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// countResolutions wraps a resolve callback to count the imports it resolves.
+func (p *plugin) countResolutions(callback func(api.OnResolveArgs) (api.OnResolveResult, error)) func(api.OnResolveArgs) (api.OnResolveResult, error) {
+	return func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+		result, err := callback(args)
+		if err == nil && result.Path != "" {
+			p.stats.resolved()
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,59 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"testing"
+	"time"
+)
+
+func TestPluginStatsReport(t *testing.T) {
+	var reports []Stats
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"a": "https://mirror.invalid/a.js",
+			"b": "https://mirror.invalid/b.js",
+		}}),
+		WithFetcher(fixtureFetcher{
+			"https://mirror.invalid/a.js": "export const a = 1;",
+			"https://mirror.invalid/b.js": "export const b = 22;",
+		}),
+		WithOnUnresolved(UnresolvedExternal),
+		WithStatsReport(func(stats Stats) {
+			reports = append(reports, stats)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {a} from 'a'; import {b} from 'b'; import 'missing'; console.log(a, b);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected a report, got %v", reports)
+	}
+	stats := reports[0]
+	if stats.Resolved != 3 || stats.Unresolved != 1 || stats.Fetches != 2 || stats.CacheHits != 0 || stats.BytesDownloaded != 39 {
+		t.Errorf("unexpected stats %s", stats)
+	}
+	if stats.ResolutionCacheSize != 3 || stats.ResolutionCacheInvalidations != 0 {
+		t.Errorf("expected the resolutions of the build to be memoized, got %s", stats)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	if p := percentile(latencies, 50); p != 50*time.Millisecond {
+		t.Errorf("unexpected p50 %s", p)
+	}
+	if p := percentile(latencies, 99); p != 99*time.Millisecond {
+		t.Errorf("unexpected p99 %s", p)
+	}
+	if p := percentile(latencies[:1], 90); p != time.Millisecond {
+		t.Errorf("unexpected p90 of a single value %s", p)
+	}
+}
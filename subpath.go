@@ -0,0 +1,83 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"strings"
+)
+
+// splitPackageSpecifier splits a bare specifier like "lodash/chunk" or "@scope/pkg/sub" into the
+// package name and the subpath.
+func splitPackageSpecifier(specifier string) (name string, subpath string, ok bool) {
+	segments := strings.SplitN(specifier, "/", 3)
+	if strings.HasPrefix(specifier, "@") {
+		if len(segments) < 3 || segments[1] == "" || segments[2] == "" {
+			return "", "", false
+		}
+		return segments[0] + "/" + segments[1], segments[2], true
+	}
+	if len(segments) < 2 || segments[0] == "" {
+		return "", "", false
+	}
+	return segments[0], strings.Join(segments[1:], "/"), true
+}
+
+// deriveSubpath derives the URL of a package subpath from the mapping of the package root, e.g.
+// "https://esm.sh/lodash@4/chunk" for "lodash/chunk" when "lodash" maps to "https://esm.sh/lodash@4".
+// The root must map to a URL containing the package name followed by a version.
+func deriveSubpath(m importmap.IImportMap, specifier string, parent *url.URL) (string, bool) {
+	name, subpath, ok := splitPackageSpecifier(specifier)
+	if !ok {
+		return "", false
+	}
+
+	resolved, err := m.ResolveWithParent(name, parent)
+	if err != nil {
+		return "", false
+	}
+	u, err := url.Parse(resolved)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+
+	start := strings.Index(u.Path, "/"+name+"@")
+	if start < 0 {
+		return "", false
+	}
+	end := start + len(name) + 2
+	if slash := strings.Index(u.Path[end:], "/"); slash >= 0 {
+		end += slash
+	} else {
+		end = len(u.Path)
+	}
+
+	u.Path = u.Path[:end] + "/" + subpath
+	u.RawPath = ""
+	return u.String(), true
+}
+
+// WithSubpathDerivation resolves subpaths of packages mapped by their root only, like "lodash/chunk"
+// for "lodash", by deriving the URL from the one of the package root. Each derived URL is reported
+// with a warning, so it can be added to the map.
+func WithSubpathDerivation() Option {
+	return func(config *Config) {
+		config.DeriveSubpaths = true
+	}
+}
+
+// resolveDerivedSubpath resolves specifier with a derived subpath URL, if one can be derived.
+func (p *plugin) resolveDerivedSubpath(m importmap.IImportMap, specifier string, parent *url.URL) (api.OnResolveResult, bool) {
+	derived, ok := deriveSubpath(m, specifier, parent)
+	if !ok {
+		return api.OnResolveResult{}, false
+	}
+	return api.OnResolveResult{
+		Path:      derived,
+		Namespace: namespace,
+		Warnings: []api.Message{{
+			Text: fmt.Sprintf("%s has no mapping, derived %s from the mapping of its package", specifier, derived),
+		}},
+	}, true
+}
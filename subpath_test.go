@@ -0,0 +1,54 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDeriveSubpath(t *testing.T) {
+	m, err := importmap.New(importmap.WithMap(importmap.Data{Imports: importmap.Imports{
+		"lodash":      "https://esm.sh/lodash@4",
+		"@scope/pkg":  "https://cdn.jsdelivr.net/npm/@scope/pkg@1.2.3/dist/index.js",
+		"query":       "https://esm.sh/query@2?bundle",
+		"unversioned": "https://example.com/unversioned.js",
+	}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"lodash/chunk":        "https://esm.sh/lodash@4/chunk",
+		"@scope/pkg/utils.js": "https://cdn.jsdelivr.net/npm/@scope/pkg@1.2.3/utils.js",
+		"query/sub":           "https://esm.sh/query@2/sub?bundle",
+		"unversioned/sub":     "",
+		"missing/sub":         "",
+		"lodash":              "",
+	}
+	for specifier, expected := range cases {
+		derived, ok := deriveSubpath(m, specifier, &url.URL{})
+		if ok != (expected != "") || derived != expected {
+			t.Errorf("deriveSubpath(%q) = %q, %v, expected %q", specifier, derived, ok, expected)
+		}
+	}
+}
+
+func TestPluginSubpathDerivation(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"lodash": "https://esm.invalid/lodash@4"}}),
+		WithFetcher(fixtureFetcher{"https://esm.invalid/lodash@4/chunk": "export default function chunk() {}"}),
+		WithSubpathDerivation(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import chunk from 'lodash/chunk'; console.log(chunk);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0].Text, "https://esm.invalid/lodash@4/chunk") {
+		t.Errorf("expected the derived URL to be reported, got %v", result.Warnings)
+	}
+}
@@ -0,0 +1,39 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"path/filepath"
+)
+
+// WithResolveSymlinks loads the local files the map points at from their real path, like esbuild
+// does for the files it resolves itself, so a file linked from several places, like the packages
+// of pnpm layouts or monorepo links, is bundled and watched once. The scopes of the map apply to
+// the imports of such files according to their real path. Builds with PreserveSymlinks keep the
+// linked paths, as esbuild does.
+func WithResolveSymlinks() Option {
+	return func(config *Config) {
+		config.ResolveSymlinks = true
+	}
+}
+
+// realFileUrl returns the file URL of the real path of the file resolved points at, or resolved
+// itself when it isn't a file URL, symlinks are preserved or the file can't be found.
+func (p *plugin) realFileUrl(resolved string) string {
+	if !p.config.ResolveSymlinks || (p.build.InitialOptions != nil && p.build.InitialOptions.PreserveSymlinks) {
+		return resolved
+	}
+	u, err := url.Parse(resolved)
+	if err != nil || u.Scheme != "file" {
+		return resolved
+	}
+	path, err := importmap.FilePath(u)
+	if err != nil {
+		return resolved
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil || realPath == path {
+		return resolved
+	}
+	return withQuery(importmap.FileURL(realPath), u).String()
+}
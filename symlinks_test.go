@@ -0,0 +1,62 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPluginWithResolveSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	pkgDir := filepath.Join(dir, "store", "pkg")
+	if err := os.MkdirAll(pkgDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pkgDir, "index.js"), []byte("export const pkg = {name: 'linked'};"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the package is linked from two places, like pnpm links it into the node_modules of packages
+	for _, link := range []string{"a", "b"} {
+		if err := os.Symlink(pkgDir, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symlinks aren't supported: %v", err)
+		}
+	}
+
+	root := importmap.FileURL(dir).String()
+	data := importmap.Data{Imports: importmap.Imports{
+		"a": root + "/a/index.js",
+		"b": root + "/b/index.js",
+	}}
+	build := func(preserveSymlinks bool, opts ...Option) string {
+		t.Helper()
+		plugin, err := NewPlugin(append([]Option{WithMap(data)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := api.Build(api.BuildOptions{
+			Bundle:           true,
+			Format:           api.FormatESModule,
+			LogLevel:         api.LogLevelSilent,
+			PreserveSymlinks: preserveSymlinks,
+			Stdin:            &api.StdinOptions{Contents: "import {pkg as a} from 'a'; import {pkg as b} from 'b'; console.log(a === b);"},
+			Plugins:          []api.Plugin{plugin},
+		})
+		if len(result.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", result.Errors)
+		}
+		return string(result.OutputFiles[0].Contents)
+	}
+
+	if output := build(false); strings.Count(output, `"linked"`) != 2 {
+		t.Errorf("expected the links to be bundled apart by default, got:\n%s", output)
+	}
+	if output := build(false, WithResolveSymlinks()); strings.Count(output, `"linked"`) != 1 {
+		t.Errorf("expected the links to be bundled once from their real path, got:\n%s", output)
+	}
+	if output := build(true, WithResolveSymlinks()); strings.Count(output, `"linked"`) != 2 {
+		t.Errorf("expected PreserveSymlinks to keep the linked paths, got:\n%s", output)
+	}
+}
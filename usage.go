@@ -0,0 +1,123 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"sort"
+	"sync"
+)
+
+// UnusedMapping is an entry of the import map which no import of a build resolved with
+type UnusedMapping struct {
+	// Scope is the key of the scope holding the entry, empty for the top-level imports
+	Scope  string
+	Key    string
+	Target string
+}
+
+func (m UnusedMapping) String() string {
+	if m.Scope == "" {
+		return fmt.Sprintf("%q -> %q", m.Key, m.Target)
+	}
+	return fmt.Sprintf("%q -> %q in scope %q", m.Key, m.Target, m.Scope)
+}
+
+// mappingKey identifies an entry of the import map
+type mappingKey struct {
+	scope string
+	key   string
+}
+
+// usageTracker records the entries of the import map used during a build
+type usageTracker struct {
+	mu   sync.Mutex
+	used map[mappingKey]bool
+}
+
+func (u *usageTracker) record(resolution importmap.Resolution) {
+	if resolution.Key == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.used[mappingKey{resolution.Scope, resolution.Key}] = true
+}
+
+func (u *usageTracker) reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.used = make(map[mappingKey]bool)
+}
+
+// unused returns the entries of m which weren't recorded, sorted by scope and key.
+func (u *usageTracker) unused(m importmap.IImportMap) []UnusedMapping {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var result []UnusedMapping
+	for key, target := range m.GetImports() {
+		if !u.used[mappingKey{"", key}] {
+			result = append(result, UnusedMapping{Key: key, Target: target})
+		}
+	}
+	for scopeKey, scope := range m.GetScopes() {
+		for key, target := range scope {
+			if !u.used[mappingKey{scopeKey, key}] {
+				result = append(result, UnusedMapping{Scope: scopeKey, Key: key, Target: target})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Scope != result[j].Scope {
+			return result[i].Scope < result[j].Scope
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// WithUnusedMappingReport calls report after each successful build with the entries of the
+// import map no import resolved with, so long-lived maps can be pruned
+func WithUnusedMappingReport(report func([]UnusedMapping)) Option {
+	return func(config *Config) {
+		config.OnUnusedMappings = report
+	}
+}
+
+// WithUnusedMappingWarnings reports the entries of the import map no import resolved with as
+// warnings of successful builds
+func WithUnusedMappingWarnings() Option {
+	return func(config *Config) {
+		config.WarnUnusedMappings = true
+	}
+}
+
+// reportsUnused reports whether the usage of the import map has to be tracked.
+func (p *plugin) reportsUnused() bool {
+	return p.config.OnUnusedMappings != nil || p.config.WarnUnusedMappings
+}
+
+// unusedMappingWarnings reports the unused entries according to the configuration.
+func (p *plugin) unusedMappingWarnings(result *api.BuildResult) []api.Message {
+	if !p.reportsUnused() || len(result.Errors) > 0 {
+		return nil
+	}
+
+	unused := p.usage.unused(p.currentMap())
+	if p.config.OnUnusedMappings != nil {
+		p.config.OnUnusedMappings(unused)
+	}
+	if !p.config.WarnUnusedMappings {
+		return nil
+	}
+
+	warnings := make([]api.Message, 0, len(unused))
+	for _, mapping := range unused {
+		warnings = append(warnings, api.Message{
+			Text: fmt.Sprintf("import map entry %s is never used", mapping),
+		})
+	}
+	return warnings
+}
@@ -0,0 +1,46 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"strings"
+	"testing"
+)
+
+func TestPluginUnusedMappings(t *testing.T) {
+	var unused []UnusedMapping
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{
+			Imports: importmap.Imports{
+				"used":   "https://mirror.invalid/used.js",
+				"unused": "https://mirror.invalid/unused.js",
+			},
+			Scopes: importmap.Scopes{
+				"https://mirror.invalid/": {"dep": "https://mirror.invalid/dep.js"},
+			},
+		}),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/used.js": "export const used = 1;"}),
+		WithUnusedMappingReport(func(mappings []UnusedMapping) {
+			unused = mappings
+		}),
+		WithUnusedMappingWarnings(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {used} from 'used'; console.log(used);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	expected := []UnusedMapping{
+		{Key: "unused", Target: "https://mirror.invalid/unused.js"},
+		{Scope: "https://mirror.invalid/", Key: "dep", Target: "https://mirror.invalid/dep.js"},
+	}
+	if len(unused) != len(expected) || unused[0] != expected[0] || unused[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, unused)
+	}
+	if len(result.Warnings) != 2 || !strings.Contains(result.Warnings[0].Text, `"unused"`) {
+		t.Errorf("expected warnings for the unused entries, got %v", result.Warnings)
+	}
+}
@@ -0,0 +1,155 @@
+package esbuild_plugin_importmap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// VendorMapFile is the name of the import map written to the vendor directory
+	VendorMapFile = "importmap.json"
+
+	// vendorHashLength is the number of hex characters of the content hash used in vendored file names
+	vendorHashLength = 12
+)
+
+// unsafeFileNameChars are replaced when turning URLs into file names
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._@-]`)
+
+// vendorStore records the remote modules written to the vendor directory during a build
+type vendorStore struct {
+	mu    sync.Mutex
+	files map[string]string
+}
+
+// vendorFileName returns the path of a remote module inside the vendor directory, using forward slashes.
+// Without hashed, the path follows the URL, with a hash of its query when it has one.
+func vendorFileName(rawUrl string, contents string, hashed bool) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		u = &url.URL{Path: rawUrl}
+	}
+
+	ext := path.Ext(u.Path)
+	if ext == "" || strings.Contains(ext, "@") {
+		ext = ".js"
+	}
+
+	if hashed {
+		base := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+		if base == "" || base == "/" || base == "." {
+			base = "index"
+		}
+		sum := sha256.Sum256([]byte(contents))
+		return unsafeFileNameChars.ReplaceAllString(base, "_") + "-" + hex.EncodeToString(sum[:])[:vendorHashLength] + ext
+	}
+
+	segments := []string{unsafeFileNameChars.ReplaceAllString(u.Host, "_")}
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			continue
+		}
+		segments = append(segments, unsafeFileNameChars.ReplaceAllString(segment, "_"))
+	}
+	name := strings.TrimSuffix(strings.Join(segments, "/"), ext)
+	if u.RawQuery != "" {
+		// URLs differing by their query, e.g. "?dev", are different modules
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		name += "-" + hex.EncodeToString(sum[:])[:vendorHashLength]
+	}
+	return name + ext
+}
+
+// vendor writes a downloaded module to the vendor directory.
+func (p *plugin) vendor(rawUrl string, contents string) error {
+	name := vendorFileName(rawUrl, contents, p.config.HashVendorFiles)
+	target := filepath.Join(p.config.VendorDir, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, []byte(contents), 0o644); err != nil {
+		return err
+	}
+
+	p.vendored.mu.Lock()
+	p.vendored.files[rawUrl] = name
+	p.vendored.mu.Unlock()
+	return nil
+}
+
+// vendorMap returns an import map pointing the original URLs, and the specifiers mapped to them,
+// at the vendored files. Targets are relative to the vendor directory, or below the public path
+// when one is configured.
+func (p *plugin) vendorMap() importmap.Data {
+	p.vendored.mu.Lock()
+	defer p.vendored.mu.Unlock()
+
+	data := importmap.Data{
+		Imports: make(importmap.Imports),
+		Scopes:  make(importmap.Scopes),
+	}
+	for rawUrl, name := range p.vendored.files {
+		data.Imports[rawUrl] = p.vendorUrl(name)
+	}
+
+	importMap := p.currentMap()
+	rewrite := func(specifierMap map[string]string, dst map[string]string) {
+		for specifier, target := range specifierMap {
+			resolved, err := importMap.Resolve(target)
+			if err != nil {
+				continue
+			}
+			if name, ok := p.vendored.files[resolved]; ok {
+				dst[specifier] = p.vendorUrl(name)
+			}
+		}
+	}
+
+	rewrite(importMap.GetImports(), data.Imports)
+	for scopeKey, scope := range importMap.GetScopes() {
+		rewritten := make(importmap.Scope)
+		rewrite(scope, rewritten)
+		if len(rewritten) > 0 {
+			data.Scopes[scopeKey] = rewritten
+		}
+	}
+
+	return data
+}
+
+// vendorUrl returns the URL of a vendored file. When the vendor directory is inside the output
+// directory, the URL is built from the public path.
+func (p *plugin) vendorUrl(name string) string {
+	if p.publicPath == "" {
+		return "./" + name
+	}
+	if p.outdir != "" {
+		if vendorDir, err := filepath.Abs(p.config.VendorDir); err == nil {
+			if rel, relErr := filepath.Rel(p.outdir, vendorDir); relErr == nil && !strings.HasPrefix(rel, "..") {
+				name = path.Join(filepath.ToSlash(rel), name)
+			}
+		}
+	}
+	return p.publicUrl(name)
+}
+
+// writeVendorMap writes the import map of the vendored modules to the vendor directory.
+func (p *plugin) writeVendorMap() error {
+	contents, err := importmap.Format(p.vendorMap(), p.config.MapFormat...)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(p.config.VendorDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(p.config.VendorDir, VendorMapFile), contents, 0o644)
+}
@@ -0,0 +1,73 @@
+package esbuild_plugin_importmap
+
+import (
+	"encoding/json"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestVendorFileName(t *testing.T) {
+	if name := vendorFileName("https://esm.sh/react@18.2.0/index.js", "", false); name != "esm.sh/react@18.2.0/index.js" {
+		t.Errorf("unexpected name %s", name)
+	}
+	if name := vendorFileName("https://esm.sh/react@18", "", false); name != "esm.sh/react@18.js" {
+		t.Errorf("unexpected name %s", name)
+	}
+	if name := vendorFileName("https://esm.sh/react@18?dev", "", false); !regexp.MustCompile(`^esm\.sh/react@18-[0-9a-f]{12}\.js$`).MatchString(name) {
+		t.Errorf("expected the query to be part of the name, got %s", name)
+	}
+	if vendorFileName("https://esm.sh/react@18?dev", "", false) == vendorFileName("https://esm.sh/react@18?target=es2020", "", false) {
+		t.Error("expected different queries to give different names")
+	}
+	if name := vendorFileName("https://esm.sh/react@18", "export {}", true); !regexp.MustCompile(`^react@18-[0-9a-f]{12}\.js$`).MatchString(name) {
+		t.Errorf("unexpected name %s", name)
+	}
+}
+
+func TestPluginVendorsHashedFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		_, _ = w.Write([]byte("export const pkg = 'remote';"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{"pkg": server.URL + "/pkg@1.0.0/index.js"}}),
+		WithVendorDir(dir),
+		WithHashedVendorFiles(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {pkg} from 'pkg'; console.log(pkg);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(dir, VendorMapFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := importmap.Data{}
+	if err = json.Unmarshal(contents, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	target := data.Imports["pkg"]
+	if !regexp.MustCompile(`^\./index-[0-9a-f]{12}\.js$`).MatchString(target) {
+		t.Fatalf("unexpected vendored target %q in %s", target, contents)
+	}
+	if data.Imports[server.URL+"/pkg@1.0.0/index.js"] != target {
+		t.Errorf("expected the original URL to be mapped too, got %s", contents)
+	}
+	if _, err = os.Stat(filepath.Join(dir, target)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,77 @@
+package esbuild_plugin_importmap
+
+import (
+	"context"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// warmupTimeout bounds how long OnStart waits for connections to be established.
+const warmupTimeout = 3 * time.Second
+
+// remoteOrigins returns the distinct http(s) origins appearing as targets in the import map.
+func remoteOrigins(m importmap.IImportMap) []string {
+	seen := make(map[string]struct{})
+	add := func(target string) {
+		u, err := url.Parse(target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		seen[u.Scheme+"://"+u.Host] = struct{}{}
+	}
+
+	for _, target := range m.GetImports() {
+		add(target)
+	}
+	for _, scope := range m.GetScopes() {
+		for _, target := range scope {
+			add(target)
+		}
+	}
+
+	origins := make([]string, 0, len(seen))
+	for origin := range seen {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// warmup resolves DNS for every origin and opens a connection to it, so that the
+// connection is already in the client's idle pool by the time modules are fetched.
+func (p *plugin) warmup(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, origin := range remoteOrigins(p.currentMap()) {
+		wg.Add(1)
+		go func(origin string) {
+			defer wg.Done()
+
+			u, err := url.Parse(origin)
+			if err != nil {
+				return
+			}
+			if _, err = net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+				return
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, origin+"/", nil)
+			if err != nil {
+				return
+			}
+			resp, err := p.client.Do(req)
+			if err != nil {
+				return
+			}
+			_ = resp.Body.Close()
+		}(origin)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,28 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"reflect"
+	"testing"
+)
+
+func TestRemoteOrigins(t *testing.T) {
+	m, err := importmap.New(importmap.WithMap(importmap.Data{
+		Imports: importmap.Imports{
+			"a": "https://esm.sh/a@1",
+			"b": "https://esm.sh/b@1",
+			"c": "./local.js",
+		},
+		Scopes: importmap.Scopes{
+			"/x/": {"d": "https://cdn.jsdelivr.net/npm/d"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"https://cdn.jsdelivr.net", "https://esm.sh"}
+	if origins := remoteOrigins(m); !reflect.DeepEqual(origins, expected) {
+		t.Errorf("expected %v, got %v", expected, origins)
+	}
+}
@@ -0,0 +1,123 @@
+package esbuild_plugin_importmap
+
+import (
+	"fmt"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// WithResolutionWarnings reports recoverable resolution problems as esbuild warnings located at
+// the import, like ignored map entries matching the specifier, shims used in place of a mapping,
+// or entries which would have been used but for their scope or key, see fallbackWarnings.
+func WithResolutionWarnings() Option {
+	return func(config *Config) {
+		config.ResolutionWarnings = true
+	}
+}
+
+// ignoredEntryWarnings returns a warning for each ignored entry of m which would have matched
+// specifier. Messages of resolve callbacks without a location get the location of the import.
+func ignoredEntryWarnings(m importmap.IImportMap, specifier string) []api.Message {
+	var warnings []api.Message
+	for _, diagnostic := range m.Diagnostics() {
+		matches := diagnostic.Key == specifier ||
+			(strings.HasSuffix(diagnostic.Key, "/") && strings.HasPrefix(specifier, diagnostic.Key))
+		if matches {
+			warnings = append(warnings, api.Message{Text: diagnostic.String()})
+		}
+	}
+	return warnings
+}
+
+// fallbackWarnings explains why the resolution of specifier from parentUrl fell back from entries
+// which look meant for it: a URL used as is although a key is its prefix but for the trailing
+// "/", or the top-level imports, or no entry, used although a scope with an entry for specifier
+// contains the parent but for the trailing "/" or the origin of its URL.
+func fallbackWarnings(m importmap.IImportMap, specifier string, parentUrl *url.URL, resolution importmap.Resolution) []api.Message {
+	if resolution.Key != "" && resolution.Scope != "" {
+		return nil
+	}
+	kind, specifierUrl := importmap.ParseSpecifier(specifier, parentUrl)
+	if kind != importmap.SpecifierBare {
+		specifier = specifierUrl.String()
+	}
+	canonical := m.CanonicalForm()
+
+	var warnings []api.Message
+	if resolution.Key == "" && resolution.URL != "" {
+		for _, key := range sortedKeys(canonical.Imports) {
+			if !strings.HasSuffix(key, "/") && strings.HasPrefix(resolution.URL, key+"/") {
+				warnings = append(warnings, api.Message{
+					Text: fmt.Sprintf("%s has no mapping and is used as is, the import map key %q only matches URLs starting with it if it ends with \"/\"", resolution.URL, key),
+				})
+			}
+		}
+	}
+
+	if resolution.Scope != "" || parentUrl == nil {
+		return warnings
+	}
+	parent := parentUrl.String()
+	for _, scopeKey := range sortedKeys(canonical.Scopes) {
+		if !hasEntryFor(canonical.Scopes[scopeKey], specifier) {
+			continue
+		}
+		scopeUrl, err := url.Parse(scopeKey)
+		if err != nil {
+			continue
+		}
+		switch {
+		case !strings.HasSuffix(scopeKey, "/") && strings.HasPrefix(parent, scopeKey+"/"):
+			warnings = append(warnings, api.Message{
+				Text: fmt.Sprintf("the scope %q mapping %q doesn't contain %s, scopes matching directories must end with \"/\"", scopeKey, specifier, parent),
+			})
+		case strings.HasSuffix(scopeUrl.Path, "/") && strings.HasPrefix(parentUrl.Path, scopeUrl.Path) &&
+			(scopeUrl.Scheme != parentUrl.Scheme || scopeUrl.Host != parentUrl.Host):
+			warnings = append(warnings, api.Message{
+				Text: fmt.Sprintf("the scope %q mapping %q matches the path of %s but not its origin", scopeKey, specifier, parent),
+			})
+		}
+	}
+	return warnings
+}
+
+// hasEntryFor tells whether specifierMap has a key equal to specifier or a prefix of it ending
+// with "/".
+func hasEntryFor(specifierMap map[string]string, specifier string) bool {
+	for key := range specifierMap {
+		if key == specifier || (strings.HasSuffix(key, "/") && strings.HasPrefix(specifier, key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in lexicographic order, so warnings come in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// withResolutionWarnings adds the warnings about the resolution of specifier from parentUrl to
+// result.
+func (p *plugin) withResolutionWarnings(result api.OnResolveResult, m importmap.IImportMap, specifier string, parentUrl *url.URL, resolution importmap.Resolution) api.OnResolveResult {
+	if !p.config.ResolutionWarnings {
+		return result
+	}
+
+	result.Warnings = append(result.Warnings, ignoredEntryWarnings(m, specifier)...)
+	result.Warnings = append(result.Warnings, fallbackWarnings(m, specifier, parentUrl, resolution)...)
+	if result.Namespace == shimNamespace {
+		result.Warnings = append(result.Warnings, api.Message{
+			Text: fmt.Sprintf("%s has no mapping, using a shim", specifier),
+		})
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package esbuild_plugin_importmap
+
+import (
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/pushrbx/esbuild-plugin-importmap/importmap"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPluginResolutionWarnings(t *testing.T) {
+	plugin, err := NewPlugin(
+		WithMap(importmap.Data{Imports: importmap.Imports{
+			"lib/":  "https://mirror.invalid/lib.js",
+			"lib/a": "https://mirror.invalid/a.js",
+		}}),
+		WithFetcher(fixtureFetcher{"https://mirror.invalid/a.js": "export const a = 1;"}),
+		WithShims("process"),
+		WithResolutionWarnings(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := buildWithPlugin(t, "import {a} from 'lib/a';\nimport process from 'process';\nconsole.log(a, process);", plugin)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if len(result.Warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", result.Warnings)
+	}
+
+	ignored, shim := result.Warnings[0], result.Warnings[1]
+	if !strings.Contains(ignored.Text, `ignored import map entry "lib/"`) {
+		t.Errorf("expected a warning for the ignored entry, got %q", ignored.Text)
+	}
+	if ignored.Location == nil || ignored.Location.Line != 1 || !strings.Contains(ignored.Location.LineText, "lib/a") {
+		t.Errorf("expected the warning to be located at the import, got %+v", ignored.Location)
+	}
+	if !strings.Contains(shim.Text, "using a shim") || shim.Location == nil || shim.Location.Line != 2 {
+		t.Errorf("expected a located warning for the shim, got %+v", shim)
+	}
+}
+
+func TestPluginFallbackWarnings(t *testing.T) {
+	p := newTestPlugin(t, WithResolutionWarnings())
+	m, err := importmap.New(
+		importmap.WithMap(importmap.Data{
+			Imports: importmap.Imports{
+				"dep":                     "https://cdn.invalid/dep.js",
+				"https://cdn.invalid/lib": "https://mirror.invalid/lib/",
+			},
+			Scopes: importmap.Scopes{
+				"file:///app/legacy": {"dep": "https://cdn.invalid/dep-legacy.js"},
+				"/vendor/":           {"dep": "https://cdn.invalid/dep-vendor.js"},
+			},
+		}),
+		importmap.WithMapUrl(&url.URL{Scheme: "file", Path: "/app/importmap.json"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.replaceImportMap(m)
+
+	warnings := func(path string, importer string) []string {
+		t.Helper()
+		result, err := p.onResolve(api.OnResolveArgs{Path: path, Importer: importer, Namespace: "file", Kind: api.ResolveJSImportStatement})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var texts []string
+		for _, warning := range result.Warnings {
+			texts = append(texts, warning.Text)
+		}
+		return texts
+	}
+
+	texts := warnings("https://cdn.invalid/lib/a.js", "/app/a.js")
+	if len(texts) != 1 || !strings.Contains(texts[0], `the import map key "https://cdn.invalid/lib" only matches URLs starting with it if it ends with "/"`) {
+		t.Errorf("expected a warning for the key without trailing slash, got %q", texts)
+	}
+	texts = warnings("dep", "/app/legacy/a.js")
+	if len(texts) != 1 || !strings.Contains(texts[0], `the scope "file:///app/legacy" mapping "dep" doesn't contain file:///app/legacy/a.js, scopes matching directories must end with "/"`) {
+		t.Errorf("expected a warning for the scope without trailing slash, got %q", texts)
+	}
+	texts = warnings("dep", "/vendor/a.js")
+	if len(texts) != 1 || !strings.Contains(texts[0], `the scope "/vendor/" mapping "dep" matches the path of file:///vendor/a.js but not its origin`) {
+		t.Errorf("expected a warning for the root-relative scope, got %q", texts)
+	}
+	if texts = warnings("dep", "/app/a.js"); len(texts) != 0 {
+		t.Errorf("expected no warnings for a plain resolution, got %q", texts)
+	}
+}